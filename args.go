@@ -1,24 +1,67 @@
 package main
 
 import (
+	"math/rand"
 	"os"
 	"strconv"
+	"time"
 )
 
 type Args struct {
-	Interval      int
-	Retry         int
-	RetryInterval int
+	Interval          int
+	IntervalJitter    int
+	Retry             int
+	RetryInterval     int
+	NotifyOrder       string
+	LeaderElection    bool
+	LeaderLockService string
+	LeaderLease       int
+	RunOnce           bool
+	EnablePprof       bool
+	MaxNotifications  int
+	CycleWebhookUrl   string
+	CycleWebhookAll   bool
+	IdleNotifyUrl     string
+	IdleNotifyPeriod  int
+	DaemonDownUrl     string
+	DaemonDownAfter   int
 }
 
 func GetArgs() *Args {
 	return &Args{
-		Interval:      getValue(5, "DF_INTERVAL"),
-		Retry:         getValue(1, "DF_RETRY"),
-		RetryInterval: getValue(0, "DF_RETRY_INTERVAL"),
+		Interval:          getValue(5, "DF_INTERVAL"),
+		IntervalJitter:    getValue(0, "DF_INTERVAL_JITTER"),
+		Retry:             getValue(1, "DF_RETRY"),
+		RetryInterval:     getValue(0, "DF_RETRY_INTERVAL"),
+		NotifyOrder:       getStringValue("create_first", "DF_NOTIFY_ORDER"),
+		LeaderElection:    getBoolValue(false, "DF_LEADER_ELECTION"),
+		LeaderLockService: getStringValue("swarm-listener", "DF_LEADER_LOCK_SERVICE"),
+		LeaderLease:       getValue(30, "DF_LEADER_LEASE"),
+		RunOnce:           getBoolValue(false, "DF_RUN_ONCE"),
+		EnablePprof:       getBoolValue(false, "DF_ENABLE_PPROF"),
+		MaxNotifications:  getValue(0, "DF_MAX_NOTIFICATIONS_PER_CYCLE"),
+		CycleWebhookUrl:   os.Getenv("DF_CYCLE_WEBHOOK_URL"),
+		CycleWebhookAll:   getBoolValue(false, "DF_CYCLE_WEBHOOK_ALWAYS"),
+		IdleNotifyUrl:     os.Getenv("DF_IDLE_NOTIFY_URL"),
+		IdleNotifyPeriod:  getValue(60, "DF_IDLE_NOTIFY_INTERVAL"),
+		DaemonDownUrl:     os.Getenv("DF_NOTIF_DAEMON_DOWN_URL"),
+		DaemonDownAfter:   getValue(3, "DF_DAEMON_DOWN_THRESHOLD"),
 	}
 }
 
+func (a *Args) GetEffectiveInterval() time.Duration {
+	interval := time.Second * time.Duration(a.Interval)
+	if a.IntervalJitter <= 0 {
+		return interval
+	}
+	jitter := time.Second * time.Duration(a.IntervalJitter)
+	interval += time.Duration(rand.Int63n(int64(2*jitter)+1)) - jitter
+	if interval < 0 {
+		return 0
+	}
+	return interval
+}
+
 func getValue(defValue int, varName string) int {
 	value := defValue
 	if len(os.Getenv(varName)) > 0 {
@@ -26,3 +69,19 @@ func getValue(defValue int, varName string) int {
 	}
 	return value
 }
+
+func getStringValue(defValue string, varName string) string {
+	value := defValue
+	if len(os.Getenv(varName)) > 0 {
+		value = os.Getenv(varName)
+	}
+	return value
+}
+
+func getBoolValue(defValue bool, varName string) bool {
+	value := defValue
+	if len(os.Getenv(varName)) > 0 {
+		value, _ = strconv.ParseBool(os.Getenv(varName))
+	}
+	return value
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"testing"
+	"time"
 )
 
 type ArgsTestSuite struct {
@@ -27,6 +28,10 @@ func (s *ArgsTestSuite) Test_GetArgs_ReturnsDefaultValues() {
 	s.Equal(5, args.Interval)
 	s.Equal(1, args.Retry)
 	s.Equal(0, args.RetryInterval)
+	s.Equal("create_first", args.NotifyOrder)
+	s.False(args.LeaderElection)
+	s.Equal("swarm-listener", args.LeaderLockService)
+	s.Equal(30, args.LeaderLease)
 }
 
 func (s *ArgsTestSuite) Test_GetArgs_ReturnsIntervalFromEnv() {
@@ -61,3 +66,161 @@ func (s *ArgsTestSuite) Test_GetArgs_ReturnsRetryIntervalFromEnv() {
 
 	s.Equal(expected, args.RetryInterval)
 }
+
+func (s *ArgsTestSuite) Test_GetArgs_ReturnsNotifyOrderFromEnv() {
+	orderOrig := os.Getenv("DF_NOTIFY_ORDER")
+	defer func() { os.Setenv("DF_NOTIFY_ORDER", orderOrig) }()
+	os.Setenv("DF_NOTIFY_ORDER", "remove_first")
+
+	args := GetArgs()
+
+	s.Equal("remove_first", args.NotifyOrder)
+}
+
+func (s *ArgsTestSuite) Test_GetArgs_ReturnsLeaderElectionFromEnv() {
+	electionOrig := os.Getenv("DF_LEADER_ELECTION")
+	serviceOrig := os.Getenv("DF_LEADER_LOCK_SERVICE")
+	leaseOrig := os.Getenv("DF_LEADER_LEASE")
+	defer func() {
+		os.Setenv("DF_LEADER_ELECTION", electionOrig)
+		os.Setenv("DF_LEADER_LOCK_SERVICE", serviceOrig)
+		os.Setenv("DF_LEADER_LEASE", leaseOrig)
+	}()
+	os.Setenv("DF_LEADER_ELECTION", "true")
+	os.Setenv("DF_LEADER_LOCK_SERVICE", "my-listener")
+	os.Setenv("DF_LEADER_LEASE", "60")
+
+	args := GetArgs()
+
+	s.True(args.LeaderElection)
+	s.Equal("my-listener", args.LeaderLockService)
+	s.Equal(60, args.LeaderLease)
+}
+
+func (s *ArgsTestSuite) Test_GetArgs_ReturnsIntervalJitterFromEnv() {
+	jitterOrig := os.Getenv("DF_INTERVAL_JITTER")
+	defer func() { os.Setenv("DF_INTERVAL_JITTER", jitterOrig) }()
+	os.Setenv("DF_INTERVAL_JITTER", "2")
+
+	args := GetArgs()
+
+	s.Equal(2, args.IntervalJitter)
+}
+
+func (s *ArgsTestSuite) Test_GetArgs_ReturnsRunOnceFromEnv() {
+	runOnceOrig := os.Getenv("DF_RUN_ONCE")
+	defer func() { os.Setenv("DF_RUN_ONCE", runOnceOrig) }()
+	os.Setenv("DF_RUN_ONCE", "true")
+
+	args := GetArgs()
+
+	s.True(args.RunOnce)
+}
+
+func (s *ArgsTestSuite) Test_GetArgs_ReturnsEnablePprofFromEnv() {
+	pprofOrig := os.Getenv("DF_ENABLE_PPROF")
+	defer func() { os.Setenv("DF_ENABLE_PPROF", pprofOrig) }()
+	os.Setenv("DF_ENABLE_PPROF", "true")
+
+	args := GetArgs()
+
+	s.True(args.EnablePprof)
+}
+
+func (s *ArgsTestSuite) Test_GetArgs_ReturnsMaxNotificationsFromEnv() {
+	maxOrig := os.Getenv("DF_MAX_NOTIFICATIONS_PER_CYCLE")
+	defer func() { os.Setenv("DF_MAX_NOTIFICATIONS_PER_CYCLE", maxOrig) }()
+	os.Setenv("DF_MAX_NOTIFICATIONS_PER_CYCLE", "20")
+
+	args := GetArgs()
+
+	s.Equal(20, args.MaxNotifications)
+}
+
+func (s *ArgsTestSuite) Test_GetArgs_ReturnsCycleWebhookFromEnv() {
+	urlOrig := os.Getenv("DF_CYCLE_WEBHOOK_URL")
+	allOrig := os.Getenv("DF_CYCLE_WEBHOOK_ALWAYS")
+	defer func() {
+		os.Setenv("DF_CYCLE_WEBHOOK_URL", urlOrig)
+		os.Setenv("DF_CYCLE_WEBHOOK_ALWAYS", allOrig)
+	}()
+	os.Setenv("DF_CYCLE_WEBHOOK_URL", "http://dashboard/cycles")
+	os.Setenv("DF_CYCLE_WEBHOOK_ALWAYS", "true")
+
+	args := GetArgs()
+
+	s.Equal("http://dashboard/cycles", args.CycleWebhookUrl)
+	s.True(args.CycleWebhookAll)
+}
+
+func (s *ArgsTestSuite) Test_GetArgs_ReturnsIdleNotifyFromEnv() {
+	urlOrig := os.Getenv("DF_IDLE_NOTIFY_URL")
+	intervalOrig := os.Getenv("DF_IDLE_NOTIFY_INTERVAL")
+	defer func() {
+		os.Setenv("DF_IDLE_NOTIFY_URL", urlOrig)
+		os.Setenv("DF_IDLE_NOTIFY_INTERVAL", intervalOrig)
+	}()
+	os.Setenv("DF_IDLE_NOTIFY_URL", "http://monitor/alive")
+	os.Setenv("DF_IDLE_NOTIFY_INTERVAL", "300")
+
+	args := GetArgs()
+
+	s.Equal("http://monitor/alive", args.IdleNotifyUrl)
+	s.Equal(300, args.IdleNotifyPeriod)
+}
+
+func (s *ArgsTestSuite) Test_GetArgs_ReturnsDaemonDownFromEnv() {
+	urlOrig := os.Getenv("DF_NOTIF_DAEMON_DOWN_URL")
+	thresholdOrig := os.Getenv("DF_DAEMON_DOWN_THRESHOLD")
+	defer func() {
+		os.Setenv("DF_NOTIF_DAEMON_DOWN_URL", urlOrig)
+		os.Setenv("DF_DAEMON_DOWN_THRESHOLD", thresholdOrig)
+	}()
+	os.Setenv("DF_NOTIF_DAEMON_DOWN_URL", "http://monitor/docker")
+	os.Setenv("DF_DAEMON_DOWN_THRESHOLD", "5")
+
+	args := GetArgs()
+
+	s.Equal("http://monitor/docker", args.DaemonDownUrl)
+	s.Equal(5, args.DaemonDownAfter)
+}
+
+func (s *ArgsTestSuite) Test_GetArgs_ReturnsDefaultDaemonDownThreshold() {
+	thresholdOrig := os.Getenv("DF_DAEMON_DOWN_THRESHOLD")
+	defer func() { os.Setenv("DF_DAEMON_DOWN_THRESHOLD", thresholdOrig) }()
+	os.Unsetenv("DF_DAEMON_DOWN_THRESHOLD")
+
+	args := GetArgs()
+
+	s.Equal(3, args.DaemonDownAfter)
+}
+
+// GetEffectiveInterval
+
+func (s *ArgsTestSuite) Test_GetEffectiveInterval_ReturnsInterval_WhenJitterIsNotSet() {
+	args := Args{Interval: 5}
+
+	s.Equal(5*time.Second, args.GetEffectiveInterval())
+}
+
+func (s *ArgsTestSuite) Test_GetEffectiveInterval_VariesWithinJitter() {
+	args := Args{Interval: 5, IntervalJitter: 2}
+	intervals := map[time.Duration]bool{}
+
+	for i := 0; i < 100; i++ {
+		actual := args.GetEffectiveInterval()
+		s.True(actual >= 3*time.Second, "%s is shorter than the interval minus the jitter", actual)
+		s.True(actual <= 7*time.Second, "%s is longer than the interval plus the jitter", actual)
+		intervals[actual] = true
+	}
+
+	s.True(len(intervals) > 1)
+}
+
+func (s *ArgsTestSuite) Test_GetEffectiveInterval_DoesNotReturnNegativeIntervals() {
+	args := Args{Interval: 1, IntervalJitter: 5}
+
+	for i := 0; i < 100; i++ {
+		s.True(args.GetEffectiveInterval() >= 0)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"time"
+)
+
+var errRetryBudgetSpent = errors.New("The retry budget of the iteration is spent")
+
+func (m *Service) StartCycle(retries, interval int) error {
+	m.startCycleTimeout()
+	m.budgetMu.Lock()
+	m.retryBudgetLeft = m.CycleRetryBudget
+	deferred := m.deferred
+	m.deferred = []notification{}
+	m.budgetMu.Unlock()
+	if len(deferred) == 0 {
+		return nil
+	}
+	logPrintf("Sending %d notifications deferred from the previous iteration", len(deferred))
+	errs := m.sendNotifications(deferred, retries, interval)
+	m.settleDeferred(deferred, errs)
+	if len(errs) > 0 {
+		return fmt.Errorf("At least one request produced errors. Please consult logs for more details.")
+	}
+	return nil
+}
+
+func (m *Service) settleDeferred(deferred []notification, errs map[string]error) {
+	// Resent notifications get the same bookkeeping as the ones that went out in their own iteration
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	for _, eventType := range []string{"create", "update"} {
+		notifications := []notification{}
+		for _, n := range deferred {
+			if n.event == eventType {
+				notifications = append(notifications, n)
+			}
+		}
+		if len(notifications) == 0 {
+			continue
+		}
+		names := getNotifiedServiceNames(notifications)
+		services := []swarm.Service{}
+		for _, name := range names {
+			if s, ok := m.ServicesCache[name]; ok {
+				services = append(services, s)
+			}
+		}
+		m.settleNotified(eventType, services, names, errs)
+	}
+}
+
+func (m *Service) takeRetryBudget(delay time.Duration) bool {
+	if m.CycleRetryBudget <= 0 {
+		return true
+	}
+	m.budgetMu.Lock()
+	defer m.budgetMu.Unlock()
+	if delay > m.retryBudgetLeft {
+		m.retryBudgetLeft = 0
+		return false
+	}
+	m.retryBudgetLeft -= delay
+	return true
+}
+
+func (m *Service) deferNotification(n notification) {
+	// Failed removals are detected again in the next iteration since the service is still tracked
+	if n.event == "remove" {
+		return
+	}
+	m.budgetMu.Lock()
+	defer m.budgetMu.Unlock()
+	m.deferred = append(m.deferred, n)
+}
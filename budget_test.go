@@ -0,0 +1,174 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+type BudgetTestSuite struct {
+	suite.Suite
+	mu     sync.Mutex
+	sleeps []time.Duration
+}
+
+func TestBudgetUnitTestSuite(t *testing.T) {
+	s := new(BudgetTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+func (s *BudgetTestSuite) SetupTest() {
+	s.sleeps = []time.Duration{}
+	retrySleep = func(d time.Duration) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.sleeps = append(s.sleeps, d)
+	}
+}
+
+func (s *BudgetTestSuite) TearDownTest() {
+	retrySleep = time.Sleep
+}
+
+// sendNotifications
+
+func (s *BudgetTestSuite) Test_SendNotifications_CapsTotalRetryTimeOfTheCycle() {
+	httpSrv := s.newReceiver(http.StatusInternalServerError)
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.CycleRetryBudget = 5 * time.Second
+	service.StartCycle(1, 0)
+	notifications := []notification{
+		{"go-demo", "create", httpSrv.URL + "?serviceName=go-demo", ""},
+		{"other", "create", httpSrv.URL + "?serviceName=other", ""},
+	}
+
+	errs := service.sendNotifications(notifications, 10, 2)
+
+	s.Equal(2, len(errs))
+	s.True(s.getTotalSleep() <= 5*time.Second)
+	s.Equal([]time.Duration{2 * time.Second, 2 * time.Second}, s.sleeps)
+	s.Equal(errRetryBudgetSpent, errs["other"])
+}
+
+func (s *BudgetTestSuite) Test_SendNotifications_RetriesWithoutLimit_WhenBudgetIsNotSet() {
+	httpSrv := s.newReceiver(http.StatusInternalServerError)
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	service.sendNotifications([]notification{{"go-demo", "create", httpSrv.URL, ""}}, 10, 2)
+
+	s.Equal(9, len(s.sleeps))
+}
+
+// StartCycle
+
+func (s *BudgetTestSuite) Test_StartCycle_SendsNotificationsDeferredInThePreviousCycle() {
+	status := http.StatusInternalServerError
+	requests := 0
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(status)
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.CycleRetryBudget = time.Second
+	service.StartCycle(1, 0)
+	service.sendNotifications([]notification{{"go-demo", "create", httpSrv.URL, ""}}, 3, 2)
+	status = http.StatusOK
+	requests = 0
+
+	err := service.StartCycle(3, 2)
+
+	s.NoError(err)
+	s.Equal(1, requests)
+	s.Empty(service.deferred)
+}
+
+func (s *BudgetTestSuite) Test_StartCycle_AppliesSuccessBookkeeping_WhenDeferredNotificationIsSent() {
+	status := http.StatusInternalServerError
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	service.CycleRetryBudget = time.Second
+	srv := swarm.Service{}
+	srv.Spec.Name = "go-demo"
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	service.ServicesCache["go-demo"] = srv
+	service.PreviousServices["go-demo"] = srv
+	service.StartCycle(1, 0)
+	service.NotifyServicesUpdate([]swarm.Service{srv}, 3, 2)
+	status = http.StatusOK
+
+	err := service.StartCycle(3, 2)
+
+	s.NoError(err)
+	s.Equal([]NotificationCount{{ServiceName: "go-demo", Update: 1}}, service.NotificationCounts.GetAll())
+	_, ok := service.LastSpecs.Get("go-demo")
+	s.True(ok)
+	s.NotContains(service.PreviousServices, "go-demo")
+}
+
+func (s *BudgetTestSuite) Test_StartCycle_ResetsRetryBudget() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.CycleRetryBudget = 5 * time.Second
+	service.StartCycle(1, 0)
+	service.takeRetryBudget(5 * time.Second)
+
+	service.StartCycle(1, 0)
+
+	s.True(service.takeRetryBudget(5 * time.Second))
+}
+
+func (s *BudgetTestSuite) Test_SendNotifications_DoesNotDeferRemoveNotifications() {
+	httpSrv := s.newReceiver(http.StatusInternalServerError)
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.CycleRetryBudget = time.Second
+	service.StartCycle(1, 0)
+
+	errs := service.sendNotifications([]notification{{"go-demo", "remove", httpSrv.URL, ""}}, 3, 2)
+
+	s.Equal(errRetryBudgetSpent, errs["go-demo"])
+	s.Empty(service.deferred)
+}
+
+// NewServiceFromEnv
+
+func (s *BudgetTestSuite) Test_NewServiceFromEnv_SetsCycleRetryBudget() {
+	budgetOrig := os.Getenv("DF_CYCLE_RETRY_BUDGET")
+	defer func() { os.Setenv("DF_CYCLE_RETRY_BUDGET", budgetOrig) }()
+	os.Setenv("DF_CYCLE_RETRY_BUDGET", "30")
+
+	service := NewServiceFromEnv()
+
+	s.Equal(30*time.Second, service.CycleRetryBudget)
+}
+
+// Util
+
+func (s *BudgetTestSuite) newReceiver(status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+}
+
+func (s *BudgetTestSuite) getTotalSleep() time.Duration {
+	total := time.Duration(0)
+	for _, d := range s.sleeps {
+		total += d
+	}
+	return total
+}
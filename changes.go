@@ -0,0 +1,169 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var defaultUpdateWatchFields = []string{"forceUpdate", "restartPolicy", "env", "labels", "placement", "secrets", "configs"}
+
+var watchableFields = map[string]func(s swarm.Service, prefix string) interface{}{
+	"forceUpdate":   func(s swarm.Service, prefix string) interface{} { return s.Spec.TaskTemplate.ForceUpdate },
+	"restartPolicy": func(s swarm.Service, prefix string) interface{} { return getRestartPolicy(s) },
+	"env":           func(s swarm.Service, prefix string) interface{} { return getEnvHash(s) },
+	"image":         func(s swarm.Service, prefix string) interface{} { return getImage(s) },
+	"labels":        func(s swarm.Service, prefix string) interface{} { return getLabels(s, prefix) },
+	"replicas":      func(s swarm.Service, prefix string) interface{} { return getReplicas(s) },
+	"placement":     func(s swarm.Service, prefix string) interface{} { return getSpreadDescriptors(s) },
+	"secrets":       func(s swarm.Service, prefix string) interface{} { return getSecretIds(s) },
+	"configs":       func(s swarm.Service, prefix string) interface{} { return getConfigIds(s) },
+}
+
+func getChangedFields(old, new swarm.Service, fields []string, prefix string) []string {
+	changed := []string{}
+	for _, f := range fields {
+		value, ok := watchableFields[f]
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(value(old, prefix), value(new, prefix)) {
+			changed = append(changed, f)
+		}
+	}
+	return changed
+}
+
+func getSpecDigest(s swarm.Service, fields []string, prefix string) string {
+	values := map[string]interface{}{}
+	for _, f := range fields {
+		if value, ok := watchableFields[f]; ok {
+			values[f] = value(s, prefix)
+		}
+	}
+	data, _ := json.Marshal(values)
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+func getLabels(s swarm.Service, prefix string) map[string]string {
+	labels := map[string]string{}
+	for k, v := range getServiceLabels(s) {
+		// The status and the leader lease are written by the listener itself and must not trigger updates
+		if k != prefix+notifyStatusKey && k != prefix+leaderKey && k != prefix+instancesKey {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+func getSpreadDescriptors(s swarm.Service) []string {
+	descriptors := []string{}
+	for _, p := range getPlacementPreferences(s) {
+		if p.Spread != nil {
+			descriptors = append(descriptors, p.Spread.SpreadDescriptor)
+		}
+	}
+	return descriptors
+}
+
+func getSecretIds(s swarm.Service) []string {
+	ids := []string{}
+	for _, secret := range getSecrets(s) {
+		if secret != nil {
+			ids = append(ids, secret.SecretID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func getConfigIds(s swarm.Service) []string {
+	ids := []string{}
+	for _, config := range getConfigs(s) {
+		if config != nil {
+			ids = append(ids, config.ConfigID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func getEnvHash(s swarm.Service) string {
+	env := append([]string{}, getEnv(s)...)
+	sort.Strings(env)
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(env, "\n"))))
+}
+
+func getChangedEnvNames(old, new swarm.Service) []string {
+	oldEnv := getEnvMap(old)
+	newEnv := getEnvMap(new)
+	names := []string{}
+	for k, v := range newEnv {
+		if oldValue, ok := oldEnv[k]; !ok || oldValue != v {
+			names = append(names, k)
+		}
+	}
+	for k := range oldEnv {
+		if _, ok := newEnv[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+type LabelChanges struct {
+	Added   map[string]string `json:"added,omitempty"`
+	Removed []string          `json:"removed,omitempty"`
+	Changed map[string]string `json:"changed,omitempty"`
+}
+
+func getLabelChanges(old, new swarm.Service, prefix string) LabelChanges {
+	oldLabels := getLabels(old, prefix)
+	newLabels := getLabels(new, prefix)
+	changes := LabelChanges{Added: map[string]string{}, Removed: []string{}, Changed: map[string]string{}}
+	for k, v := range newLabels {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if oldValue, ok := oldLabels[k]; !ok {
+			changes.Added[k] = v
+		} else if oldValue != v {
+			changes.Changed[k] = v
+		}
+	}
+	for k := range oldLabels {
+		if _, ok := newLabels[k]; !ok && strings.HasPrefix(k, prefix) {
+			changes.Removed = append(changes.Removed, k)
+		}
+	}
+	sort.Strings(changes.Removed)
+	return changes
+}
+
+func getLabelChangesParam(old, new swarm.Service, prefix string) string {
+	changes := getLabelChanges(old, new, prefix)
+	if len(changes.Added) == 0 && len(changes.Removed) == 0 && len(changes.Changed) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(changes)
+	return url.QueryEscape(string(data))
+}
+
+func getEnvMap(s swarm.Service) map[string]string {
+	env := map[string]string{}
+	for _, e := range getEnv(s) {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) == 2 {
+			env[kv[0]] = kv[1]
+		} else {
+			env[kv[0]] = ""
+		}
+	}
+	return env
+}
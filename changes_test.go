@@ -0,0 +1,142 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type ChangesTestSuite struct {
+	suite.Suite
+}
+
+func TestChangesUnitTestSuite(t *testing.T) {
+	s := new(ChangesTestSuite)
+	suite.Run(t, s)
+}
+
+// getChangedFields
+
+func (s *ChangesTestSuite) Test_GetChangedFields_ReturnsEmptySlice_WhenNothingChanged() {
+	old := s.getServiceWithEnv("DB=go-demo-db", "PORT=8080")
+	new := s.getServiceWithEnv("PORT=8080", "DB=go-demo-db")
+
+	s.Equal([]string{}, getChangedFields(old, new, defaultUpdateWatchFields, "com.df."))
+}
+
+func (s *ChangesTestSuite) Test_GetChangedFields_ReturnsEnv_WhenEnvChanged() {
+	old := s.getServiceWithEnv("DB=go-demo-db")
+	new := s.getServiceWithEnv("DB=other-db")
+
+	s.Equal([]string{"env"}, getChangedFields(old, new, defaultUpdateWatchFields, "com.df."))
+}
+
+func (s *ChangesTestSuite) Test_GetChangedFields_ReturnsForceUpdate_WhenForceUpdateChanged() {
+	old := s.getServiceWithEnv()
+	new := s.getServiceWithEnv()
+	new.Spec.TaskTemplate.ForceUpdate = 1
+
+	s.Equal([]string{"forceUpdate"}, getChangedFields(old, new, defaultUpdateWatchFields, "com.df."))
+}
+
+func (s *ChangesTestSuite) Test_GetChangedFields_ReturnsOnlyWatchedFields() {
+	old := s.getServiceWithEnv("A=1")
+	old.Spec.Labels = map[string]string{"com.df.port": "8080"}
+	new := s.getServiceWithEnv("A=2")
+	new.Spec.Labels = map[string]string{"com.df.port": "8081"}
+	new.Spec.TaskTemplate.ContainerSpec.Image = "go-demo:2.0"
+
+	s.Equal([]string{"image", "labels"}, getChangedFields(old, new, []string{"image", "labels", "replicas"}, "com.df."))
+	s.Equal([]string{"env"}, getChangedFields(old, new, []string{"env"}, "com.df."))
+}
+
+func (s *ChangesTestSuite) Test_GetChangedFields_ReturnsReplicas_WhenReplicasChanged() {
+	oldReplicas := uint64(1)
+	newReplicas := uint64(3)
+	old := s.getServiceWithEnv()
+	old.Spec.Mode.Replicated = &swarm.ReplicatedService{Replicas: &oldReplicas}
+	new := s.getServiceWithEnv()
+	new.Spec.Mode.Replicated = &swarm.ReplicatedService{Replicas: &newReplicas}
+
+	s.Equal([]string{"replicas"}, getChangedFields(old, new, []string{"image", "replicas"}, "com.df."))
+}
+
+func (s *ChangesTestSuite) Test_GetChangedFields_ReturnsPlacement_WhenSpreadPreferencesChanged() {
+	old := s.getServiceWithEnv()
+	new := s.getServiceWithEnv()
+	new.Spec.TaskTemplate.Placement = &swarm.Placement{
+		Preferences: []swarm.PlacementPreference{{Spread: &swarm.SpreadOver{SpreadDescriptor: "node.labels.az"}}},
+	}
+
+	s.Equal([]string{"placement"}, getChangedFields(old, new, defaultUpdateWatchFields, "com.df."))
+}
+
+func (s *ChangesTestSuite) Test_GetChangedFields_ReturnsSecretsAndConfigs_WhenReferencesAreRotated() {
+	old := s.getServiceWithEnv()
+	old.Spec.TaskTemplate.ContainerSpec.Secrets = []*swarm.SecretReference{{SecretID: "cert-v1", SecretName: "cert-v1"}}
+	old.Spec.TaskTemplate.ContainerSpec.Configs = []*swarm.ConfigReference{{ConfigID: "conf-v1", ConfigName: "conf-v1"}}
+	new := s.getServiceWithEnv()
+	new.Spec.TaskTemplate.ContainerSpec.Secrets = []*swarm.SecretReference{{SecretID: "cert-v2", SecretName: "cert-v2"}}
+	new.Spec.TaskTemplate.ContainerSpec.Configs = []*swarm.ConfigReference{{ConfigID: "conf-v2", ConfigName: "conf-v2"}}
+
+	s.Equal([]string{"secrets", "configs"}, getChangedFields(old, new, defaultUpdateWatchFields, "com.df."))
+}
+
+func (s *ChangesTestSuite) Test_GetChangedFields_IgnoresUnknownFields() {
+	s.Equal([]string{}, getChangedFields(s.getServiceWithEnv("A=1"), s.getServiceWithEnv("A=2"), []string{"unknown"}, "com.df."))
+}
+
+// getSpecDigest
+
+func (s *ChangesTestSuite) Test_GetSpecDigest_IgnoresEnvOrder() {
+	s.Equal(getSpecDigest(s.getServiceWithEnv("A=1", "B=2"), defaultUpdateWatchFields, "com.df."), getSpecDigest(s.getServiceWithEnv("B=2", "A=1"), defaultUpdateWatchFields, "com.df."))
+}
+
+func (s *ChangesTestSuite) Test_GetSpecDigest_Changes_WhenEnvChanges() {
+	s.NotEqual(getSpecDigest(s.getServiceWithEnv("A=1"), defaultUpdateWatchFields, "com.df."), getSpecDigest(s.getServiceWithEnv("A=2"), defaultUpdateWatchFields, "com.df."))
+}
+
+// getChangedEnvNames
+
+func (s *ChangesTestSuite) Test_GetChangedEnvNames_ReturnsNamesOfAddedChangedAndRemovedVariables() {
+	old := s.getServiceWithEnv("DB=go-demo-db", "PORT=8080", "TOKEN=secret")
+	new := s.getServiceWithEnv("DB=other-db", "PORT=8080", "DEBUG=true")
+
+	s.Equal([]string{"DB", "DEBUG", "TOKEN"}, getChangedEnvNames(old, new))
+}
+
+// getLabelChanges
+
+func (s *ChangesTestSuite) Test_GetLabelChanges_ReturnsAddedRemovedAndChangedLabels() {
+	old := swarm.Service{}
+	old.Spec.Labels = map[string]string{"com.df.notify": "true", "com.df.port": "8080", "com.df.distribute": "true", "com.df.notifyStatus": "ok"}
+	new := swarm.Service{}
+	new.Spec.Labels = map[string]string{"com.df.notify": "true", "com.df.port": "9090", "com.df.servicePath": "/demo", "other": "label"}
+
+	actual := getLabelChanges(old, new, "com.df.")
+
+	s.Equal(LabelChanges{
+		Added:   map[string]string{"com.df.servicePath": "/demo"},
+		Removed: []string{"com.df.distribute"},
+		Changed: map[string]string{"com.df.port": "9090"},
+	}, actual)
+}
+
+func (s *ChangesTestSuite) Test_GetLabelChangesParam_ReturnsEmptyString_WhenLabelsDidNotChange() {
+	old := swarm.Service{}
+	old.Spec.Labels = map[string]string{"com.df.notify": "true"}
+
+	s.Empty(getLabelChangesParam(old, old, "com.df."))
+}
+
+// Util
+
+func (s *ChangesTestSuite) getServiceWithEnv(env ...string) swarm.Service {
+	return swarm.Service{
+		Spec: swarm.ServiceSpec{
+			TaskTemplate: swarm.TaskSpec{
+				ContainerSpec: &swarm.ContainerSpec{Env: env},
+			},
+		},
+	}
+}
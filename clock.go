@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"time"
+)
+
+var futureCreatedAtTolerance = time.Minute
+
+func (m *Service) checkLastCreatedAt(services []swarm.Service) {
+	newest := time.Time{}
+	for _, s := range services {
+		if s.Meta.CreatedAt.After(newest) {
+			newest = s.Meta.CreatedAt
+		}
+	}
+	// A last creation time newer than all the services is expected after removals, but not in the future
+	if !serviceLastCreatedAt.After(newest) || !serviceLastCreatedAt.After(time.Now().Add(futureCreatedAtTolerance)) {
+		return
+	}
+	logPrintf(
+		"WARNING: The last service creation time %s is in the future. Services will be reconciled with the tracked ones.",
+		serviceLastCreatedAt.Format(time.RFC3339),
+	)
+	// Services created after the newest one are found by the timestamp and the missed ones by membership
+	serviceLastCreatedAt = newest
+	m.lastCreatedAtReset = true
+}
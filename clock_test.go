@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ClockTestSuite struct {
+	suite.Suite
+	logs []string
+}
+
+func TestClockUnitTestSuite(t *testing.T) {
+	s := new(ClockTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {
+		s.logs = append(s.logs, fmt.Sprintf(format, v...))
+	}
+
+	suite.Run(t, s)
+}
+
+func (s *ClockTestSuite) SetupTest() {
+	s.logs = []string{}
+	serviceLastCreatedAt = time.Time{}
+}
+
+func (s *ClockTestSuite) TearDownTest() {
+	serviceLastCreatedAt = time.Time{}
+}
+
+// GetNewServices
+
+func (s *ClockTestSuite) Test_GetNewServices_RecoversFromFutureLastCreatedAt() {
+	createdAt := time.Now().Add(-time.Minute)
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.Services["go-demo"] = true
+	serviceLastCreatedAt = time.Now().Add(time.Hour)
+	services := []swarm.Service{
+		s.getService("go-demo", createdAt.Add(-time.Hour)),
+		s.getService("go-api", createdAt),
+	}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(1, len(actual))
+	s.Equal("go-api", actual[0].Spec.Name)
+	s.Contains(service.Services, "go-api")
+	s.True(createdAt.Equal(serviceLastCreatedAt))
+	s.Equal(1, len(s.logs))
+	s.True(strings.HasPrefix(s.logs[0], "WARNING: The last service creation time"))
+}
+
+func (s *ClockTestSuite) Test_GetNewServices_DoesNotTreatFutureLastCreatedAtAsDaemonChange() {
+	createdAt := time.Now().Add(-time.Minute)
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifCreateFailureUrl = "http://failures"
+	service.CreateFailureWindow = time.Hour
+	service.Services["go-demo"] = true
+	serviceLastCreatedAt = time.Now().Add(time.Hour)
+	services := []swarm.Service{
+		s.getService("go-demo", createdAt.Add(-time.Hour)),
+		s.getService("go-api", createdAt),
+	}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(1, len(actual))
+	s.Equal("go-api", actual[0].Spec.Name)
+	s.False(service.daemonChanged)
+	s.False(service.lastCreatedAtReset)
+	s.Contains(service.ServicesCache, "go-api")
+	s.Contains(service.SpecDigests, "go-api")
+	s.Contains(service.CreatedServices, "go-api")
+	s.True(createdAt.Equal(serviceLastCreatedAt))
+	s.Equal(1, len(s.logs))
+}
+
+func (s *ClockTestSuite) Test_GetNewServices_RecoversServicesOlderThanTrackedOnes_WhenLastCreatedAtIsInFuture() {
+	createdAt := time.Now().Add(-time.Minute)
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.Services["go-demo"] = true
+	serviceLastCreatedAt = time.Now().Add(time.Hour)
+	services := []swarm.Service{
+		s.getService("go-demo", createdAt),
+		s.getService("go-api", createdAt.Add(-time.Hour)),
+	}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(1, len(actual))
+	s.Equal("go-api", actual[0].Spec.Name)
+	s.True(createdAt.Equal(serviceLastCreatedAt))
+	s.Equal(1, len(s.logs))
+}
+
+func (s *ClockTestSuite) Test_GetNewServices_ReturnsOnlyNewerServices_WhenPassAfterFutureLastCreatedAt() {
+	createdAt := time.Now().Add(-time.Minute)
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.Services["go-demo"] = true
+	serviceLastCreatedAt = time.Now().Add(time.Hour)
+	service.GetNewServices([]swarm.Service{s.getService("go-demo", createdAt.Add(-time.Hour))})
+	services := []swarm.Service{
+		s.getService("go-demo", createdAt.Add(-time.Hour)),
+		s.getService("go-api", createdAt),
+	}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(1, len(actual))
+	s.Equal("go-api", actual[0].Spec.Name)
+	s.True(createdAt.Equal(serviceLastCreatedAt))
+}
+
+func (s *ClockTestSuite) Test_GetNewServices_KeepsLastCreatedAt_WhenNewestServiceWasRemoved() {
+	lastCreatedAt := time.Now().Add(-time.Minute)
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.Services["go-demo"] = true
+	serviceLastCreatedAt = lastCreatedAt
+
+	actual, _ := service.GetNewServices([]swarm.Service{s.getService("go-demo", lastCreatedAt.Add(-time.Hour))})
+
+	s.Empty(actual)
+	s.True(lastCreatedAt.Equal(serviceLastCreatedAt))
+	s.Empty(s.logs)
+}
+
+// Util
+
+func (s *ClockTestSuite) getService(name string, createdAt time.Time) swarm.Service {
+	srv := swarm.Service{}
+	srv.Spec.Name = name
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	srv.Meta.CreatedAt = createdAt
+	return srv
+}
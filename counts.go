@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+type NotificationCount struct {
+	ServiceName string `json:"serviceName"`
+	Create      int    `json:"create"`
+	Update      int    `json:"update"`
+	Remove      int    `json:"remove"`
+}
+
+type NotificationCounts struct {
+	mu      sync.RWMutex
+	max     int
+	touches uint64
+	items   map[string]*NotificationCount
+	touched map[string]uint64
+}
+
+func (m *NotificationCounts) Add(serviceName, event string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.items[serviceName]
+	if !ok {
+		if m.max <= 0 {
+			return
+		}
+		if len(m.items) >= m.max {
+			m.evict()
+		}
+		c = &NotificationCount{ServiceName: serviceName}
+		m.items[serviceName] = c
+	}
+	switch event {
+	case "create":
+		c.Create++
+	case "update":
+		c.Update++
+	case "remove":
+		c.Remove++
+	}
+	m.touches++
+	m.touched[serviceName] = m.touches
+}
+
+// The counters of the service notified least recently make room for the new one
+func (m *NotificationCounts) evict() {
+	oldest := ""
+	for name, touched := range m.touched {
+		if len(oldest) == 0 || touched < m.touched[oldest] {
+			oldest = name
+		}
+	}
+	delete(m.items, oldest)
+	delete(m.touched, oldest)
+}
+
+func (m *NotificationCounts) GetAll() []NotificationCount {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	counts := []NotificationCount{}
+	for _, c := range m.items {
+		counts = append(counts, *c)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].ServiceName < counts[j].ServiceName
+	})
+	return counts
+}
+
+func NewNotificationCounts(max int) *NotificationCounts {
+	return &NotificationCounts{
+		max:     max,
+		items:   make(map[string]*NotificationCount),
+		touched: make(map[string]uint64),
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+)
+
+type CountsTestSuite struct {
+	suite.Suite
+}
+
+func TestCountsUnitTestSuite(t *testing.T) {
+	s := new(CountsTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// Add
+
+func (s *CountsTestSuite) Test_Add_CountsNotificationsPerServiceAndEvent() {
+	counts := NewNotificationCounts(10)
+
+	counts.Add("go-demo", "create")
+	counts.Add("go-demo", "update")
+	counts.Add("go-demo", "update")
+	counts.Add("go-demo", "remove")
+	counts.Add("other", "create")
+
+	s.Equal([]NotificationCount{
+		{ServiceName: "go-demo", Create: 1, Update: 2, Remove: 1},
+		{ServiceName: "other", Create: 1},
+	}, counts.GetAll())
+}
+
+func (s *CountsTestSuite) Test_Add_EvictsLeastRecentlyNotifiedService_WhenMaxIsReached() {
+	counts := NewNotificationCounts(2)
+
+	counts.Add("s1", "create")
+	counts.Add("s2", "create")
+	counts.Add("s1", "update")
+	counts.Add("s3", "create")
+
+	s.Equal([]NotificationCount{
+		{ServiceName: "s1", Create: 1, Update: 1},
+		{ServiceName: "s3", Create: 1},
+	}, counts.GetAll())
+}
+
+func (s *CountsTestSuite) Test_Add_DoesNotCount_WhenMaxIsZero() {
+	counts := NewNotificationCounts(0)
+
+	counts.Add("go-demo", "create")
+
+	s.Empty(counts.GetAll())
+}
+
+// NotifyServicesRemove
+
+func (s *CountsTestSuite) Test_NotifyServicesRemove_DoesNotCountFailedNotifications() {
+	service := NewService("unix:///var/run/docker.sock", "", "http://127.0.0.1:1")
+	service.Services["go-demo"] = true
+
+	service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	s.Empty(service.GetNotificationCounts())
+}
+
+// NewServiceFromEnv
+
+func (s *CountsTestSuite) Test_NewServiceFromEnv_SetsNotificationCountsMax() {
+	maxOrig := os.Getenv("DF_NOTIFICATION_COUNTS_MAX")
+	defer func() { os.Setenv("DF_NOTIFICATION_COUNTS_MAX", maxOrig) }()
+	os.Setenv("DF_NOTIFICATION_COUNTS_MAX", "5")
+
+	service := NewServiceFromEnv()
+
+	s.Equal(5, service.NotificationCounts.max)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var idleNotifiedAt time.Time
+var daemonFailures int
+var daemonDown bool
+
+type CycleSummary struct {
+	Created                int   `json:"created"`
+	Updated                int   `json:"updated"`
+	Removed                int   `json:"removed"`
+	Failures               int   `json:"failures"`
+	DurationMs             int64 `json:"durationMs"`
+	NotificationDurationMs int64 `json:"notificationDurationMs"`
+}
+
+func sendCycleSummary(args *Args, summary CycleSummary) {
+	if len(args.CycleWebhookUrl) == 0 {
+		return
+	}
+	if summary.Created+summary.Updated+summary.Removed+summary.Failures == 0 && !args.CycleWebhookAll {
+		return
+	}
+	body, _ := json.Marshal(summary)
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(args.CycleWebhookUrl, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logPrintf("WARNING: Could not send the iteration summary to %s\n%s", args.CycleWebhookUrl, err.Error())
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		logPrintf("WARNING: Could not send the iteration summary to %s\nThe webhook returned status code %d", args.CycleWebhookUrl, resp.StatusCode)
+	}
+}
+
+func sendIdleNotification(args *Args, summary CycleSummary) {
+	if len(args.IdleNotifyUrl) == 0 {
+		return
+	}
+	// Changes are a sign of life on their own so the interval restarts with each of them
+	if summary.Created+summary.Updated+summary.Removed > 0 {
+		idleNotifiedAt = time.Now()
+		return
+	}
+	if time.Since(idleNotifiedAt) < time.Second*time.Duration(args.IdleNotifyPeriod) {
+		return
+	}
+	idleNotifiedAt = time.Now()
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(args.IdleNotifyUrl)
+	if err != nil {
+		logPrintf("WARNING: Could not send the idle notification to %s\n%s", args.IdleNotifyUrl, err.Error())
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		logPrintf("WARNING: Could not send the idle notification to %s\nThe receiver returned status code %d", args.IdleNotifyUrl, resp.StatusCode)
+	}
+}
+
+func reportDaemonStatus(args *Args, err error) {
+	if err != nil {
+		daemonFailures++
+		// Only the failure that crosses the threshold is reported so that an outage produces a single notification
+		if daemonDown || daemonFailures < args.DaemonDownAfter {
+			return
+		}
+		daemonDown = true
+		logPrintf("ERROR: Could not reach the Docker daemon %d times in a row\n%s", daemonFailures, err.Error())
+		sendDaemonNotification(args, fmt.Sprintf("status=down&failures=%d", daemonFailures))
+		return
+	}
+	if daemonDown {
+		logPrintf("The Docker daemon is reachable again after %d failed attempts", daemonFailures)
+		sendDaemonNotification(args, "status=up")
+	}
+	daemonFailures = 0
+	daemonDown = false
+}
+
+func sendDaemonNotification(args *Args, query string) {
+	if len(args.DaemonDownUrl) == 0 {
+		return
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	fullUrl := fmt.Sprintf("%s?%s", args.DaemonDownUrl, query)
+	resp, err := client.Get(fullUrl)
+	if err != nil {
+		logPrintf("WARNING: Could not send the Docker daemon status to %s\n%s", fullUrl, err.Error())
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		logPrintf("WARNING: Could not send the Docker daemon status to %s\nThe receiver returned status code %d", fullUrl, resp.StatusCode)
+	}
+}
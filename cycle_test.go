@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type CycleTestSuite struct {
+	suite.Suite
+}
+
+func TestCycleUnitTestSuite(t *testing.T) {
+	s := new(CycleTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+func (s *CycleTestSuite) SetupTest() {
+	idleNotifiedAt = time.Time{}
+	daemonFailures = 0
+	daemonDown = false
+}
+
+// sendCycleSummary
+
+func (s *CycleTestSuite) Test_SendCycleSummary_PostsSummaryAsJson() {
+	actualMethod := ""
+	actualContentType := ""
+	actual := CycleSummary{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualMethod = r.Method
+		actualContentType = r.Header.Get("Content-Type")
+		json.NewDecoder(r.Body).Decode(&actual)
+	}))
+	defer srv.Close()
+	expected := CycleSummary{Created: 1, Updated: 2, Removed: 3, Failures: 1, DurationMs: 10, NotificationDurationMs: 5}
+
+	sendCycleSummary(&Args{CycleWebhookUrl: srv.URL}, expected)
+
+	s.Equal("POST", actualMethod)
+	s.Equal("application/json", actualContentType)
+	s.Equal(expected, actual)
+}
+
+func (s *CycleTestSuite) Test_SendCycleSummary_SendsSummaryWithFailuresOnly() {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	sendCycleSummary(&Args{CycleWebhookUrl: srv.URL}, CycleSummary{Failures: 1})
+
+	s.True(called)
+}
+
+func (s *CycleTestSuite) Test_SendCycleSummary_DoesNotSend_WhenCycleHasNoChanges() {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	sendCycleSummary(&Args{CycleWebhookUrl: srv.URL}, CycleSummary{DurationMs: 10})
+
+	s.False(called)
+}
+
+func (s *CycleTestSuite) Test_SendCycleSummary_DoesNotPanic_WhenWebhookIsUnreachable() {
+	s.NotPanics(func() {
+		sendCycleSummary(&Args{CycleWebhookUrl: "http://127.0.0.1:1"}, CycleSummary{Created: 1})
+	})
+}
+
+// sendIdleNotification
+
+func (s *CycleTestSuite) Test_SendIdleNotification_PingsOnlyDuringQuietPeriods() {
+	pings := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pings++
+	}))
+	defer srv.Close()
+	args := &Args{IdleNotifyUrl: srv.URL, IdleNotifyPeriod: 60}
+
+	sendIdleNotification(args, CycleSummary{})
+	s.Equal(1, pings)
+
+	sendIdleNotification(args, CycleSummary{})
+	s.Equal(1, pings, "The ping should not repeat within the interval")
+
+	idleNotifiedAt = time.Now().Add(-2 * time.Minute)
+	sendIdleNotification(args, CycleSummary{Created: 1})
+	sendIdleNotification(args, CycleSummary{})
+	s.Equal(1, pings, "Changes should restart the interval")
+
+	idleNotifiedAt = time.Now().Add(-2 * time.Minute)
+	sendIdleNotification(args, CycleSummary{})
+	s.Equal(2, pings)
+}
+
+func (s *CycleTestSuite) Test_SendIdleNotification_DoesNotPing_WhenCycleHasChanges() {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	sendIdleNotification(&Args{IdleNotifyUrl: srv.URL, IdleNotifyPeriod: 60}, CycleSummary{Removed: 1})
+
+	s.False(called)
+}
+
+// reportDaemonStatus
+
+func (s *CycleTestSuite) Test_ReportDaemonStatus_NotifiesOnce_WhenFailuresExceedThresholdAndOnRecovery() {
+	actual := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual = append(actual, r.URL.RawQuery)
+	}))
+	defer srv.Close()
+	args := &Args{DaemonDownUrl: srv.URL, DaemonDownAfter: 3}
+
+	for i := 0; i < 5; i++ {
+		reportDaemonStatus(args, fmt.Errorf("This is an error"))
+	}
+	reportDaemonStatus(args, nil)
+	reportDaemonStatus(args, nil)
+
+	s.Equal([]string{"status=down&failures=3", "status=up"}, actual)
+}
+
+func (s *CycleTestSuite) Test_ReportDaemonStatus_DoesNotNotify_WhenFailuresAreBelowThreshold() {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+	args := &Args{DaemonDownUrl: srv.URL, DaemonDownAfter: 3}
+
+	reportDaemonStatus(args, fmt.Errorf("This is an error"))
+	reportDaemonStatus(args, fmt.Errorf("This is an error"))
+	reportDaemonStatus(args, nil)
+	reportDaemonStatus(args, fmt.Errorf("This is an error"))
+
+	s.False(called)
+	s.Equal(1, daemonFailures)
+}
+
+func (s *CycleTestSuite) Test_ReportDaemonStatus_LogsOutage_WhenUrlIsNotSet() {
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	msgs := []string{}
+	logPrintf = func(format string, v ...interface{}) {
+		msgs = append(msgs, fmt.Sprintf(format, v...))
+	}
+	args := &Args{DaemonDownAfter: 1}
+
+	reportDaemonStatus(args, fmt.Errorf("This is an error"))
+	reportDaemonStatus(args, nil)
+
+	s.Len(msgs, 2)
+	s.Contains(msgs[0], "Could not reach the Docker daemon 1 times in a row")
+	s.Contains(msgs[1], "reachable again")
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"encoding/base64"
+)
+
+func (m *Service) decodeLabel(serviceName, key, value string) string {
+	if !m.isDecodedLabel(key) {
+		return value
+	}
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		logPrintf("WARNING: The value of the label %s of the service %s is not valid base64 and was sent unchanged\n%s", key, serviceName, err.Error())
+		return value
+	}
+	return string(decoded)
+}
+
+func (m *Service) isDecodedLabel(key string) bool {
+	for _, k := range m.DecodeLabels {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type DecodeTestSuite struct {
+	suite.Suite
+	logs []string
+}
+
+func TestDecodeUnitTestSuite(t *testing.T) {
+	s := new(DecodeTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {
+		s.logs = append(s.logs, fmt.Sprintf(format, v...))
+	}
+
+	suite.Run(t, s)
+}
+
+func (s *DecodeTestSuite) SetupTest() {
+	s.logs = []string{}
+	serviceLastCreatedAt = time.Time{}
+}
+
+// NotifyServicesCreate
+
+func (s *DecodeTestSuite) Test_NotifyServicesCreate_DecodesBase64LabelValues() {
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	service.DecodeLabels = []string{"com.df.servicePath"}
+
+	// L2RlbW8= is /demo
+	service.NotifyServicesCreate([]swarm.Service{s.getService("go-demo", "L2RlbW8=")}, 1, 0)
+
+	s.Equal("serviceName=go-demo&servicePath=/demo", actualQuery)
+}
+
+func (s *DecodeTestSuite) Test_NotifyServicesCreate_SendsValueUnchanged_WhenBase64IsInvalid() {
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	service.DecodeLabels = []string{"com.df.servicePath"}
+
+	service.NotifyServicesCreate([]swarm.Service{s.getService("go-demo", "/demo")}, 1, 0)
+
+	s.Equal("serviceName=go-demo&servicePath=/demo", actualQuery)
+	s.Contains(strings.Join(s.logs, "\n"), "WARNING: The value of the label com.df.servicePath of the service go-demo is not valid base64")
+}
+
+func (s *DecodeTestSuite) Test_NotifyServicesCreate_DoesNotDecode_WhenLabelIsNotListed() {
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	service.DecodeLabels = []string{"com.df.port"}
+
+	service.NotifyServicesCreate([]swarm.Service{s.getService("go-demo", "L2RlbW8=")}, 1, 0)
+
+	s.Equal("serviceName=go-demo&servicePath=L2RlbW8=", actualQuery)
+}
+
+func (s *DecodeTestSuite) Test_NotifyServicesCreate_EscapesDecodedValues() {
+	actualQuery := url.Values{}
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.Query()
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	service.DecodeLabels = []string{"com.df.servicePath"}
+
+	// L2E/Yj0xJmM9MiN0b3A= is /a?b=1&c=2#top
+	service.NotifyServicesCreate([]swarm.Service{s.getService("go-demo", "L2E/Yj0xJmM9MiN0b3A=")}, 1, 0)
+
+	s.Equal(url.Values{"serviceName": {"go-demo"}, "servicePath": {"/a?b=1&c=2#top"}}, actualQuery)
+}
+
+// NewServiceFromEnv
+
+func (s *DecodeTestSuite) Test_NewServiceFromEnv_SetsDecodeLabels() {
+	decodeOrig := os.Getenv("DF_DECODE_LABELS")
+	defer func() { os.Setenv("DF_DECODE_LABELS", decodeOrig) }()
+	os.Setenv("DF_DECODE_LABELS", "com.df.servicePath,com.df.reqPathSearch")
+
+	service := NewServiceFromEnv()
+
+	s.Equal([]string{"com.df.servicePath", "com.df.reqPathSearch"}, service.DecodeLabels)
+}
+
+// Util
+
+func (s *DecodeTestSuite) getService(name, servicePath string) swarm.Service {
+	srv := swarm.Service{}
+	srv.Spec.Name = name
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true", "com.df.servicePath": servicePath}
+	return srv
+}
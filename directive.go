@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+type Directive struct {
+	Retry           *bool    `json:"retry,omitempty"`
+	ConfirmedRoutes []string `json:"confirmedRoutes,omitempty"`
+}
+
+func (m *Service) readDirective(serviceName string, resp *http.Response) Directive {
+	directive := Directive{}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return directive
+	}
+	body := readResponseBody(resp)
+	// The body is restored so that it can still be logged when the request fails
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	resp.Header.Del("Content-Encoding")
+	if len(bytes.TrimSpace(body)) == 0 {
+		return directive
+	}
+	if err := json.Unmarshal(body, &directive); err != nil {
+		logPrintf("WARNING: Could not parse the directive returned for the service %s\n%s", serviceName, err.Error())
+		return Directive{}
+	}
+	return directive
+}
+
+func (m *Directive) isNoRetry() bool {
+	return m.Retry != nil && !*m.Retry
+}
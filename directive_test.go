@@ -0,0 +1,104 @@
+package main
+
+import (
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type DirectiveTestSuite struct {
+	suite.Suite
+}
+
+func TestDirectiveUnitTestSuite(t *testing.T) {
+	s := new(DirectiveTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// sendNotification
+
+func (s *DirectiveTestSuite) Test_SendNotification_StopsRetrying_WhenReceiverReturnsNoRetryDirective() {
+	requests := 0
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"retry": false}`))
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL+"?serviceName=go-demo", 3, 0)
+
+	s.NoError(err)
+	s.Equal(1, requests)
+}
+
+func (s *DirectiveTestSuite) Test_SendNotification_Retries_WhenReceiverDoesNotReturnDirective() {
+	requests := 0
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL+"?serviceName=go-demo", 3, 0)
+
+	s.Error(err)
+	s.Equal(3, requests)
+}
+
+func (s *DirectiveTestSuite) Test_SendNotification_IgnoresDirectives_WhenStatusIsNotSuccessful() {
+	requests := 0
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"retry": false}`))
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL+"?serviceName=go-demo", 2, 0)
+
+	s.Error(err)
+	s.Contains(err.Error(), `{"retry": false}`)
+	s.Equal(2, requests)
+}
+
+func (s *DirectiveTestSuite) Test_SendNotification_RecordsConfirmedRoutes() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"confirmedRoutes": ["/demo", "/api"]}`))
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL+"?serviceName=go-demo", 1, 0)
+
+	s.NoError(err)
+	receipts := service.GetReceipts()
+	s.Equal(1, len(receipts))
+	s.Equal([]string{"/demo", "/api"}, receipts[0].ConfirmedRoutes)
+}
+
+func (s *DirectiveTestSuite) Test_SendNotification_IgnoresMalformedDirectives() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`not json`))
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL+"?serviceName=go-demo", 1, 0)
+
+	s.NoError(err)
+	s.Empty(service.GetReceipts())
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"math/rand"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var staggerSleep = time.Sleep
+
+type notification struct {
+	serviceName string
+	event       string
+	fullUrl     string
+	body        string
+}
+
+func (m *Service) sendNotifications(notifications []notification, retries, interval int) map[string]error {
+	endpoints := []string{}
+	groups := map[string][]notification{}
+	for _, n := range notifications {
+		endpoint := getEndpoint(n.fullUrl)
+		if _, ok := groups[endpoint]; !ok {
+			endpoints = append(endpoints, endpoint)
+		}
+		groups[endpoint] = append(groups[endpoint], n)
+	}
+	errs := map[string]error{}
+	skipped := []notification{}
+	mu := sync.Mutex{}
+	send := func(n notification) {
+		payload, err := m.transform(n)
+		if err == nil {
+			err = m.sendTransformed(n.serviceName, n.event, payload, retries, interval)
+		}
+		if err == errRetryBudgetSpent || err == errCycleTimeout {
+			m.deferNotification(n)
+		}
+		if err != nil {
+			mu.Lock()
+			errs[n.serviceName] = err
+			if err == errCycleTimeout {
+				skipped = append(skipped, n)
+			}
+			mu.Unlock()
+		}
+	}
+	wg := sync.WaitGroup{}
+	for _, endpoint := range endpoints {
+		wg.Add(1)
+		go func(items []notification) {
+			defer wg.Done()
+			workers := sync.WaitGroup{}
+			sem := make(chan struct{}, m.getEndpointConcurrency())
+			queues := groupByService(items)
+			for _, queue := range queues {
+				sem <- struct{}{}
+				workers.Add(1)
+				go func(queue []notification) {
+					defer func() {
+						<-sem
+						workers.Done()
+					}()
+					if len(queues) > 1 {
+						m.stagger()
+					}
+					// Notifications of the same service are sent one by one so that they arrive in the order they were produced
+					for _, n := range queue {
+						send(n)
+					}
+				}(queue)
+			}
+			workers.Wait()
+		}(groups[endpoint])
+	}
+	wg.Wait()
+	m.logCycleTimeout(skipped)
+	return errs
+}
+
+// The state lock is released while requests are in flight so that slow receivers do not block the other phases
+func (m *Service) sendUnlocked(notifications []notification, retries, interval int) map[string]error {
+	m.stateMu.Unlock()
+	defer m.stateMu.Lock()
+	return m.sendNotifications(notifications, retries, interval)
+}
+
+func (m *Service) sendNotificationUnlocked(serviceName, event, fullUrl string, retries, interval int) error {
+	m.stateMu.Unlock()
+	defer m.stateMu.Lock()
+	return m.sendNotification(serviceName, event, fullUrl, retries, interval)
+}
+
+func groupByService(notifications []notification) [][]notification {
+	queues := [][]notification{}
+	indexes := map[string]int{}
+	for _, n := range notifications {
+		i, ok := indexes[n.serviceName]
+		if !ok {
+			i = len(queues)
+			indexes[n.serviceName] = i
+			queues = append(queues, []notification{})
+		}
+		queues[i] = append(queues[i], n)
+	}
+	return queues
+}
+
+func getNotifiedServiceNames(notifications []notification) []string {
+	names := []string{}
+	found := map[string]bool{}
+	for _, n := range notifications {
+		if !found[n.serviceName] {
+			found[n.serviceName] = true
+			names = append(names, n.serviceName)
+		}
+	}
+	return names
+}
+
+func (m *Service) stagger() {
+	if m.NotifyStagger <= 0 {
+		return
+	}
+	staggerSleep(time.Duration(rand.Int63n(int64(m.NotifyStagger))))
+}
+
+func (m *Service) getEndpointConcurrency() int {
+	if m.EndpointConcurrency < 1 {
+		return 1
+	}
+	return m.EndpointConcurrency
+}
+
+func getEndpoint(fullUrl string) string {
+	u, err := url.Parse(fullUrl)
+	if err != nil || len(u.Host) == 0 {
+		return fullUrl
+	}
+	return u.Host
+}
+
+func getUrls(value string) []string {
+	urls := []string{}
+	for _, u := range strings.Split(value, ",") {
+		if u = strings.TrimSpace(u); len(u) > 0 {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
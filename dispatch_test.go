@@ -0,0 +1,235 @@
+package main
+
+import (
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+type DispatchTestSuite struct {
+	suite.Suite
+}
+
+func TestDispatchUnitTestSuite(t *testing.T) {
+	s := new(DispatchTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// sendNotifications
+
+func (s *DispatchTestSuite) Test_SendNotifications_DoesNotStarveFastEndpoint_WhenAnotherEndpointIsSlow() {
+	release := make(chan struct{})
+	slowSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slowSrv.Close()
+	defer close(release)
+	fastDone := make(chan struct{})
+	fastCount := 0
+	fastSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fastCount++
+		if fastCount == 3 {
+			close(fastDone)
+		}
+	}))
+	defer fastSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	notifications := []notification{}
+	for _, name := range []string{"a", "b", "c"} {
+		notifications = append(notifications, notification{name, "create", slowSrv.URL + "?serviceName=" + name, ""})
+		notifications = append(notifications, notification{name, "create", fastSrv.URL + "?serviceName=" + name, ""})
+	}
+
+	go service.sendNotifications(notifications, 1, 0)
+
+	select {
+	case <-fastDone:
+	case <-time.After(5 * time.Second):
+		s.Fail("The fast endpoint was starved by the slow one")
+	}
+}
+
+func (s *DispatchTestSuite) Test_SendNotifications_LimitsConcurrencyPerEndpoint() {
+	mu := sync.Mutex{}
+	active := 0
+	maxActive := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.EndpointConcurrency = 2
+	notifications := []notification{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		notifications = append(notifications, notification{name, "create", srv.URL + "?serviceName=" + name, ""})
+	}
+
+	errs := service.sendNotifications(notifications, 1, 0)
+
+	s.Empty(errs)
+	s.Equal(2, maxActive)
+}
+
+func (s *DispatchTestSuite) Test_SendNotifications_ReturnsErrorsByServiceName() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("serviceName") == "b" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	notifications := []notification{
+		{"a", "create", srv.URL + "?serviceName=a", ""},
+		{"b", "create", srv.URL + "?serviceName=b", ""},
+	}
+
+	errs := service.sendNotifications(notifications, 1, 0)
+
+	s.Equal(1, len(errs))
+	s.Contains(errs, "b")
+}
+
+func (s *DispatchTestSuite) Test_SendNotifications_PreservesOrderPerService() {
+	mu := sync.Mutex{}
+	active := 0
+	maxActive := 0
+	events := map[string][]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+		// Earlier events are slower so that they would be overtaken if they were sent concurrently
+		switch r.URL.Query().Get("event") {
+		case "create":
+			time.Sleep(30 * time.Millisecond)
+		case "update":
+			time.Sleep(15 * time.Millisecond)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		active--
+		name := r.URL.Query().Get("serviceName")
+		events[name] = append(events[name], r.URL.Query().Get("event"))
+	}))
+	defer srv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.EndpointConcurrency = 10
+	notifications := []notification{}
+	for _, event := range []string{"create", "update", "remove"} {
+		for _, name := range []string{"a", "b", "c"} {
+			notifications = append(notifications, notification{name, event, srv.URL + "?serviceName=" + name + "&event=" + event, ""})
+		}
+	}
+
+	errs := service.sendNotifications(notifications, 1, 0)
+
+	s.Empty(errs)
+	for _, name := range []string{"a", "b", "c"} {
+		s.Equal([]string{"create", "update", "remove"}, events[name])
+	}
+	s.Equal(3, maxActive)
+}
+
+func (s *DispatchTestSuite) Test_SendNotifications_StaggersServicesWithinWindow() {
+	staggerSleepOrig := staggerSleep
+	defer func() { staggerSleep = staggerSleepOrig }()
+	mu := sync.Mutex{}
+	delays := []time.Duration{}
+	staggerSleep = func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		delays = append(delays, d)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyStagger = 50 * time.Millisecond
+	notifications := []notification{}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		notifications = append(notifications, notification{name, "create", srv.URL + "?serviceName=" + name, ""})
+	}
+
+	service.sendNotifications(notifications, 1, 0)
+
+	s.Len(delays, 5)
+	for _, d := range delays {
+		s.True(d >= 0 && d < 50*time.Millisecond, d.String())
+	}
+}
+
+func (s *DispatchTestSuite) Test_SendNotifications_DoesNotStagger_WhenOnlyOneServiceIsNotified() {
+	staggerSleepOrig := staggerSleep
+	defer func() { staggerSleep = staggerSleepOrig }()
+	called := false
+	staggerSleep = func(d time.Duration) { called = true }
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyStagger = 50 * time.Millisecond
+
+	service.sendNotifications([]notification{{"a", "create", srv.URL + "?serviceName=a", ""}}, 1, 0)
+
+	s.False(called)
+}
+
+// groupByService
+
+func (s *DispatchTestSuite) Test_GroupByService_KeepsOrderOfServicesAndNotifications() {
+	actual := groupByService([]notification{
+		{"b", "create", "http://proxy?serviceName=b", ""},
+		{"a", "create", "http://proxy?serviceName=a", ""},
+		{"b", "remove", "http://proxy?serviceName=b", ""},
+	})
+
+	s.Equal([][]notification{
+		{{"b", "create", "http://proxy?serviceName=b", ""}, {"b", "remove", "http://proxy?serviceName=b", ""}},
+		{{"a", "create", "http://proxy?serviceName=a", ""}},
+	}, actual)
+}
+
+// NewServiceFromEnv
+
+func (s *DispatchTestSuite) Test_NewServiceFromEnv_SetsNotifyStagger() {
+	staggerOrig := os.Getenv("DF_NOTIFY_STAGGER")
+	defer func() { os.Setenv("DF_NOTIFY_STAGGER", staggerOrig) }()
+	os.Setenv("DF_NOTIFY_STAGGER", "250")
+
+	service := NewServiceFromEnv()
+
+	s.Equal(250*time.Millisecond, service.NotifyStagger)
+}
+
+// getUrls
+
+func (s *DispatchTestSuite) Test_GetUrls_SplitsCommaSeparatedUrls() {
+	actual := getUrls("http://proxy-a/reconfigure, http://proxy-b/reconfigure,")
+
+	s.Equal([]string{"http://proxy-a/reconfigure", "http://proxy-b/reconfigure"}, actual)
+}
+
+// getEndpoint
+
+func (s *DispatchTestSuite) Test_GetEndpoint_ReturnsHost() {
+	s.Equal("proxy:8080", getEndpoint("http://proxy:8080/v1/reconfigure?serviceName=a"))
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"regexp"
+	"strings"
+)
+
+var domainRegexp = regexp.MustCompile(`^(\*\.)?([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+func (m *Service) getServiceDomains(s swarm.Service, labels map[string]string) []string {
+	value, ok := labels["serviceDomain"]
+	delete(labels, "serviceDomain")
+	if !ok {
+		// The domains are forwarded even when a label mapper does not include them
+		value = getServiceLabels(s)[m.LabelPrefix+"serviceDomain"]
+	}
+	domains := []string{}
+	for _, d := range strings.Split(value, ",") {
+		d = strings.TrimSpace(d)
+		if len(d) == 0 {
+			continue
+		}
+		if len(d) > 253 || !domainRegexp.MatchString(d) {
+			logPrintf("WARNING: The domain %s of the service %s is not valid and will not be forwarded", d, s.Spec.Name)
+			continue
+		}
+		domains = append(domains, d)
+	}
+	return domains
+}
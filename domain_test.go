@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type DomainTestSuite struct {
+	suite.Suite
+	logs []string
+}
+
+func TestDomainUnitTestSuite(t *testing.T) {
+	s := new(DomainTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {
+		s.logs = append(s.logs, fmt.Sprintf(format, v...))
+	}
+
+	suite.Run(t, s)
+}
+
+func (s *DomainTestSuite) SetupTest() {
+	s.logs = []string{}
+}
+
+// NotifyServicesCreate
+
+func (s *DomainTestSuite) Test_NotifyServicesCreate_SendsSingleDomain() {
+	actual := s.getCreateQuery(map[string]string{"com.df.serviceDomain": "go-demo.com"}, nil)
+
+	s.Equal("serviceName=go-demo&serviceDomain=go-demo.com", actual)
+}
+
+func (s *DomainTestSuite) Test_NotifyServicesCreate_SendsMultipleDomainsAsRepeatedParameters() {
+	actual := s.getCreateQuery(map[string]string{"com.df.serviceDomain": "go-demo.com, *.acme.org,localhost", "com.df.port": "8080"}, nil)
+
+	s.Equal("serviceName=go-demo&port=8080&serviceDomain=go-demo.com&serviceDomain=*.acme.org&serviceDomain=localhost", actual)
+}
+
+func (s *DomainTestSuite) Test_NotifyServicesCreate_DropsInvalidDomains() {
+	actual := s.getCreateQuery(map[string]string{"com.df.serviceDomain": "go-demo.com,-invalid.com,bad_domain.com,a..b"}, nil)
+
+	s.Equal("serviceName=go-demo&serviceDomain=go-demo.com", actual)
+	logs := strings.Join(s.logs, "\n")
+	s.Contains(logs, "WARNING: The domain -invalid.com of the service go-demo is not valid")
+	s.Contains(logs, "WARNING: The domain bad_domain.com of the service go-demo is not valid")
+	s.Contains(logs, "WARNING: The domain a..b of the service go-demo is not valid")
+}
+
+func (s *DomainTestSuite) Test_NotifyServicesCreate_SendsDomains_WhenLabelMapperDoesNotIncludeThem() {
+	mapper := func(srv swarm.Service) map[string]string {
+		return map[string]string{"port": "8080"}
+	}
+
+	actual := s.getCreateQuery(map[string]string{"com.df.serviceDomain": "go-demo.com"}, mapper)
+
+	s.Equal("serviceName=go-demo&port=8080&serviceDomain=go-demo.com", actual)
+}
+
+// Util
+
+func (s *DomainTestSuite) getCreateQuery(labels map[string]string, mapper func(s swarm.Service) map[string]string) string {
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	service.LabelMapper = mapper
+	srv := swarm.Service{}
+	srv.Spec.Name = "go-demo"
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	for k, v := range labels {
+		srv.Spec.Labels[k] = v
+	}
+	service.NotifyServicesCreate([]swarm.Service{srv}, 1, 0)
+	return actualQuery
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+)
+
+type EndpointTemplate struct {
+	Url          string
+	UrlTemplate  string
+	BodyTemplate string
+}
+
+type EndpointTemplateData struct {
+	Event       string
+	ServiceName string
+	Parameters  map[string]string
+}
+
+func getEndpointTemplatesFromEnv() map[string]EndpointTemplate {
+	templates := map[string]EndpointTemplate{}
+	for _, e := range os.Environ() {
+		kv := strings.SplitN(e, "=", 2)
+		for prefix, field := range map[string]string{
+			"DF_TEMPLATE_ENDPOINT_": "endpoint",
+			"DF_TEMPLATE_URL_":      "url",
+			"DF_TEMPLATE_BODY_":     "body",
+		} {
+			if !strings.HasPrefix(kv[0], prefix) || len(kv[0]) == len(prefix) {
+				continue
+			}
+			name := strings.ToLower(strings.TrimPrefix(kv[0], prefix))
+			t := templates[name]
+			switch field {
+			case "endpoint":
+				t.Url = kv[1]
+			case "url":
+				t.UrlTemplate = kv[1]
+			case "body":
+				t.BodyTemplate = kv[1]
+			}
+			templates[name] = t
+		}
+	}
+	return templates
+}
+
+func (m *Service) validateEndpointTemplates() error {
+	for name, t := range m.EndpointTemplates {
+		if len(t.Url) == 0 {
+			return fmt.Errorf("DF_TEMPLATE_ENDPOINT_%s is not set", strings.ToUpper(name))
+		}
+		if _, err := template.New("url").Parse(t.UrlTemplate); err != nil {
+			return fmt.Errorf("DF_TEMPLATE_URL_%s could not be parsed\n%s", strings.ToUpper(name), err.Error())
+		}
+		if _, err := template.New("body").Parse(t.BodyTemplate); err != nil {
+			return fmt.Errorf("DF_TEMPLATE_BODY_%s could not be parsed\n%s", strings.ToUpper(name), err.Error())
+		}
+	}
+	return nil
+}
+
+func (m *Service) getEndpointTemplate(fullUrl string) (EndpointTemplate, bool) {
+	baseUrl := strings.SplitN(fullUrl, "?", 2)[0]
+	for _, t := range m.EndpointTemplates {
+		if t.Url == baseUrl {
+			return t, true
+		}
+	}
+	return EndpointTemplate{}, false
+}
+
+func (m *Service) getEndpointTemplateRequest(t EndpointTemplate, event, fullUrl string) (*http.Request, error) {
+	u, err := url.Parse(fullUrl)
+	if err != nil {
+		return nil, err
+	}
+	data := EndpointTemplateData{Event: event, Parameters: map[string]string{}}
+	for k, v := range u.Query() {
+		data.Parameters[k] = strings.Join(v, ",")
+	}
+	data.ServiceName = data.Parameters["serviceName"]
+	requestUrl := fullUrl
+	if len(t.UrlTemplate) > 0 {
+		if requestUrl, err = renderEndpointTemplate("url", t.UrlTemplate, data); err != nil {
+			return nil, err
+		}
+	}
+	if len(t.BodyTemplate) == 0 {
+		return http.NewRequest("GET", requestUrl, nil)
+	}
+	body, err := renderEndpointTemplate("body", t.BodyTemplate, data)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", requestUrl, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func renderEndpointTemplate(name, text string, data EndpointTemplateData) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
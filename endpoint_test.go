@@ -0,0 +1,139 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+type EndpointTestSuite struct {
+	suite.Suite
+	mu       sync.Mutex
+	requests map[string]string
+}
+
+func TestEndpointUnitTestSuite(t *testing.T) {
+	s := new(EndpointTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+func (s *EndpointTestSuite) SetupTest() {
+	s.requests = map[string]string{}
+}
+
+// NotifyServicesCreate
+
+func (s *EndpointTestSuite) Test_NotifyServicesCreate_RendersTemplatesPerEndpoint() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests[r.URL.Path] = r.Method + " " + r.URL.RequestURI() + " " + string(body)
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL+"/proxy,"+httpSrv.URL+"/dns", "")
+	service.EndpointTemplates = map[string]EndpointTemplate{
+		"proxy": {
+			Url:         httpSrv.URL + "/proxy",
+			UrlTemplate: httpSrv.URL + "/proxy?service={{.ServiceName}}&path={{.Parameters.servicePath}}",
+		},
+		"dns": {
+			Url:          httpSrv.URL + "/dns",
+			BodyTemplate: `{"action":"{{.Event}}","name":"{{.ServiceName}}"}`,
+		},
+	}
+
+	err := service.NotifyServicesCreate(s.getServices(), 1, 0)
+
+	s.NoError(err)
+	s.Equal(map[string]string{
+		"/proxy": "GET /proxy?service=go-demo&path=/demo ",
+		"/dns":   `POST /dns?serviceName=go-demo&servicePath=/demo {"action":"create","name":"go-demo"}`,
+	}, s.requests)
+}
+
+func (s *EndpointTestSuite) Test_NotifyServicesCreate_SendsDefaultNotification_WhenEndpointHasNoTemplates() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests[r.URL.Path] = r.Method + " " + r.URL.RequestURI()
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL+"/proxy", "")
+	service.EndpointTemplates = map[string]EndpointTemplate{
+		"dns": {Url: httpSrv.URL + "/dns", BodyTemplate: `{"name":"{{.ServiceName}}"}`},
+	}
+
+	err := service.NotifyServicesCreate(s.getServices(), 1, 0)
+
+	s.NoError(err)
+	s.Equal(map[string]string{"/proxy": "GET /proxy?serviceName=go-demo&servicePath=/demo"}, s.requests)
+}
+
+// ValidateTemplates
+
+func (s *EndpointTestSuite) Test_ValidateTemplates_ReturnsError_WhenEndpointTemplateIsInvalid() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.EndpointTemplates = map[string]EndpointTemplate{
+		"dns": {Url: "http://dns/records", BodyTemplate: `{"name":"{{.ServiceName"}`},
+	}
+
+	err := service.ValidateTemplates()
+
+	s.Error(err)
+	s.Contains(err.Error(), "DF_TEMPLATE_BODY_DNS")
+}
+
+func (s *EndpointTestSuite) Test_ValidateTemplates_ReturnsError_WhenEndpointIsNotSet() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.EndpointTemplates = map[string]EndpointTemplate{
+		"dns": {BodyTemplate: `{"name":"{{.ServiceName}}"}`},
+	}
+
+	err := service.ValidateTemplates()
+
+	s.Error(err)
+	s.Contains(err.Error(), "DF_TEMPLATE_ENDPOINT_DNS")
+}
+
+// getEndpointTemplatesFromEnv
+
+func (s *EndpointTestSuite) Test_GetEndpointTemplatesFromEnv_ReturnsTemplates() {
+	defer func() {
+		os.Unsetenv("DF_TEMPLATE_ENDPOINT_DNS")
+		os.Unsetenv("DF_TEMPLATE_URL_DNS")
+		os.Unsetenv("DF_TEMPLATE_BODY_DNS")
+	}()
+	os.Setenv("DF_TEMPLATE_ENDPOINT_DNS", "http://dns/records")
+	os.Setenv("DF_TEMPLATE_URL_DNS", "http://dns/records/{{.ServiceName}}")
+	os.Setenv("DF_TEMPLATE_BODY_DNS", `{"name":"{{.ServiceName}}"}`)
+
+	actual := getEndpointTemplatesFromEnv()
+
+	s.Equal(map[string]EndpointTemplate{
+		"dns": {
+			Url:          "http://dns/records",
+			UrlTemplate:  "http://dns/records/{{.ServiceName}}",
+			BodyTemplate: `{"name":"{{.ServiceName}}"}`,
+		},
+	}, actual)
+}
+
+// Util
+
+func (s *EndpointTestSuite) getServices() []swarm.Service {
+	srv := swarm.Service{}
+	srv.Spec.Name = "go-demo"
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true", "com.df.servicePath": "/demo"}
+	return []swarm.Service{srv}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+func (m *Service) getEnrichment(serviceName string) map[string]string {
+	fields := map[string]string{}
+	if len(m.EnrichUrl) == 0 {
+		return fields
+	}
+	client := &http.Client{Timeout: m.EnrichTimeout}
+	resp, err := client.Get(fmt.Sprintf("%s?serviceName=%s", m.EnrichUrl, url.QueryEscape(serviceName)))
+	if err != nil {
+		logPrintf("WARNING: Could not enrich service %s. The notification will be sent without enrichment fields\n%s", serviceName, err.Error())
+		return fields
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		logPrintf("WARNING: Could not enrich service %s. The enrichment service returned status code %d. The notification will be sent without enrichment fields", serviceName, resp.StatusCode)
+		return fields
+	}
+	data := map[string]interface{}{}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		logPrintf("WARNING: Could not decode enrichment of service %s. The notification will be sent without enrichment fields\n%s", serviceName, err.Error())
+		return fields
+	}
+	for k, v := range data {
+		if v == nil {
+			continue
+		}
+		fields[k] = fmt.Sprintf("%v", v)
+	}
+	return fields
+}
+
+func addEnrichment(fullUrl string, fields map[string]string) string {
+	keys := []string{}
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fullUrl = fmt.Sprintf("%s&%s=%s", fullUrl, url.QueryEscape(k), url.QueryEscape(fields[k]))
+	}
+	return fullUrl
+}
+
+// Enrichment and task lookups can be slow so they run once per service while the state lock is released
+func (m *Service) getExtraParamsUnlocked(services []swarm.Service) map[string]string {
+	m.stateMu.Unlock()
+	defer m.stateMu.Lock()
+	params := map[string]string{}
+	for _, s := range services {
+		params[s.Spec.Name] = addEnrichment("", m.getEnrichment(s.Spec.Name)) + m.getNodesParam(s)
+	}
+	return params
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type EnrichTestSuite struct {
+	suite.Suite
+}
+
+func TestEnrichUnitTestSuite(t *testing.T) {
+	s := new(EnrichTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// getEnrichment
+
+func (s *EnrichTestSuite) Test_GetEnrichment_ReturnsFieldsFromEnrichmentService() {
+	actualQuery := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+		w.Write([]byte(`{"team":"payments","replicas":3}`))
+	}))
+	defer srv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.EnrichUrl = srv.URL
+
+	actual := service.getEnrichment("go-demo")
+
+	s.Equal("serviceName=go-demo", actualQuery)
+	s.Equal(map[string]string{"team": "payments", "replicas": "3"}, actual)
+}
+
+func (s *EnrichTestSuite) Test_GetEnrichment_ReturnsEmptyMap_WhenEnrichUrlIsNotSet() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	actual := service.getEnrichment("go-demo")
+
+	s.Empty(actual)
+}
+
+func (s *EnrichTestSuite) Test_GetEnrichment_ReturnsEmptyMap_WhenStatusIsNot200() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.EnrichUrl = srv.URL
+
+	actual := service.getEnrichment("go-demo")
+
+	s.Empty(actual)
+}
+
+func (s *EnrichTestSuite) Test_GetEnrichment_ReturnsEmptyMap_WhenResponseIsNotJson() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not json"))
+	}))
+	defer srv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.EnrichUrl = srv.URL
+
+	actual := service.getEnrichment("go-demo")
+
+	s.Empty(actual)
+}
+
+func (s *EnrichTestSuite) Test_GetEnrichment_ReturnsEmptyMap_WhenTimeoutIsReached() {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.EnrichUrl = srv.URL
+	service.EnrichTimeout = 10 * time.Millisecond
+
+	actual := service.getEnrichment("go-demo")
+
+	s.Empty(actual)
+}
+
+// NotifyServicesCreate
+
+func (s *EnrichTestSuite) Test_NotifyServicesCreate_MergesEnrichmentFields() {
+	enrichSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"team":"payments","owner":"jane doe"}`))
+	}))
+	defer enrichSrv.Close()
+	actualQuery := ""
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer notifSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", notifSrv.URL, "")
+	service.EnrichUrl = enrichSrv.URL
+
+	err := service.NotifyServicesCreate(s.getServices(), 1, 0)
+
+	s.NoError(err)
+	s.Equal("serviceName=go-demo&owner=jane+doe&team=payments", actualQuery)
+}
+
+func (s *EnrichTestSuite) Test_NotifyServicesCreate_SendsNotification_WhenEnrichmentFails() {
+	actualQuery := ""
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer notifSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", notifSrv.URL, "")
+	service.EnrichUrl = "http://127.0.0.1:1/does-not-exist"
+
+	err := service.NotifyServicesCreate(s.getServices(), 1, 0)
+
+	s.NoError(err)
+	s.Equal("serviceName=go-demo", actualQuery)
+}
+
+func (s *EnrichTestSuite) Test_NotifyServicesCreate_RequestsEnrichmentOnce_WhenThereAreMultipleUrls() {
+	enrichCount := 0
+	enrichSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enrichCount++
+		w.Write([]byte(`{"team":"payments"}`))
+	}))
+	defer enrichSrv.Close()
+	actualQueries := []string{}
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQueries = append(actualQueries, r.URL.RawQuery)
+	}))
+	defer notifSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", notifSrv.URL+"/a,"+notifSrv.URL+"/b", "")
+	service.EnrichUrl = enrichSrv.URL
+
+	err := service.NotifyServicesCreate(s.getServices(), 1, 0)
+
+	s.NoError(err)
+	s.Equal(1, enrichCount)
+	s.Equal([]string{"serviceName=go-demo&team=payments", "serviceName=go-demo&team=payments"}, actualQueries)
+}
+
+func (s *EnrichTestSuite) Test_NotifyServicesCreate_RequestsEnrichmentWithoutHoldingStateLock() {
+	var service *Service
+	locked := true
+	enrichSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if service.stateMu.TryLock() {
+			locked = false
+			service.stateMu.Unlock()
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer enrichSrv.Close()
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer notifSrv.Close()
+	service = NewService("unix:///var/run/docker.sock", notifSrv.URL, "")
+	service.EnrichUrl = enrichSrv.URL
+
+	err := service.NotifyServicesCreate(s.getServices(), 1, 0)
+
+	s.NoError(err)
+	s.False(locked)
+}
+
+// Util
+
+func (s *EnrichTestSuite) getServices() []swarm.Service {
+	return []swarm.Service{
+		{Spec: swarm.ServiceSpec{Annotations: swarm.Annotations{Name: "go-demo", Labels: map[string]string{"com.df.notify": "true"}}}},
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"sync"
+)
+
+var eventStream = NewEventStream()
+
+type Event struct {
+	Type        string            `json:"type"`
+	ServiceName string            `json:"serviceName"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Event       string            `json:"event,omitempty"`
+}
+
+type EventStream struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]bool
+}
+
+func (m *EventStream) Subscribe() chan Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ch := make(chan Event, 100)
+	m.subscribers[ch] = true
+	return ch
+}
+
+func (m *EventStream) Unsubscribe(ch chan Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.subscribers[ch]; ok {
+		delete(m.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (m *EventStream) Publish(event Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for ch := range m.subscribers {
+		select {
+		case ch <- event:
+		default:
+			logPrintf("WARNING: Dropping %s event for %s since the subscriber is not keeping up", event.Type, event.ServiceName)
+		}
+	}
+}
+
+func (m *EventStream) PublishServices(eventType string, services []swarm.Service) {
+	for _, s := range services {
+		m.Publish(Event{Type: eventType, ServiceName: s.Spec.Name, Labels: getServiceLabels(s)})
+	}
+}
+
+func (m *EventStream) SubscribersCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.subscribers)
+}
+
+func NewEventStream() *EventStream {
+	return &EventStream{
+		subscribers: make(map[chan Event]bool),
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type EventsTestSuite struct {
+	suite.Suite
+}
+
+func TestEventsUnitTestSuite(t *testing.T) {
+	s := new(EventsTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// Publish
+
+func (s *EventsTestSuite) Test_Publish_SendsEventToAllSubscribers() {
+	stream := NewEventStream()
+	ch1 := stream.Subscribe()
+	ch2 := stream.Subscribe()
+	expected := Event{Type: "create", ServiceName: "go-demo"}
+
+	stream.Publish(expected)
+
+	s.Equal(expected, s.receive(ch1))
+	s.Equal(expected, s.receive(ch2))
+}
+
+func (s *EventsTestSuite) Test_Publish_DoesNotBlock_WhenSubscriberIsNotReading() {
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+	stream := NewEventStream()
+	stream.Subscribe()
+
+	for i := 0; i < 200; i++ {
+		stream.Publish(Event{Type: "create", ServiceName: "go-demo"})
+	}
+}
+
+// PublishServices
+
+func (s *EventsTestSuite) Test_PublishServices_SendsEventsWithLabels() {
+	stream := NewEventStream()
+	ch := stream.Subscribe()
+	labels := map[string]string{"com.df.notify": "true"}
+	services := []swarm.Service{
+		{Spec: swarm.ServiceSpec{Annotations: swarm.Annotations{Name: "go-demo", Labels: labels}}},
+	}
+
+	stream.PublishServices("update", services)
+
+	s.Equal(Event{Type: "update", ServiceName: "go-demo", Labels: labels}, s.receive(ch))
+}
+
+// Unsubscribe
+
+func (s *EventsTestSuite) Test_Unsubscribe_RemovesSubscriber() {
+	stream := NewEventStream()
+	ch := stream.Subscribe()
+
+	stream.Unsubscribe(ch)
+
+	s.Equal(0, stream.SubscribersCount())
+	_, ok := <-ch
+	s.False(ok)
+}
+
+// NotifyServicesRemove
+
+func (s *EventsTestSuite) Test_NotifyServicesRemove_PublishesRemoveEvent_WhenNotificationSucceeds() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL)
+	service.Services["go-demo"] = true
+	service.ServicesCache["go-demo"] = s.getService("go-demo")
+	events := eventStream.Subscribe()
+	defer eventStream.Unsubscribe(events)
+
+	err := service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	s.NoError(err)
+	s.Equal(Event{Type: "remove", ServiceName: "go-demo", Labels: map[string]string{"com.df.notify": "true"}}, s.receive(events))
+}
+
+func (s *EventsTestSuite) Test_NotifyServicesRemove_DoesNotPublishRemoveEvent_WhenNotificationFails() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL)
+	service.Services["go-demo"] = true
+	events := eventStream.Subscribe()
+	defer eventStream.Unsubscribe(events)
+
+	service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+	service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	s.Empty(events)
+}
+
+// ForgetRemovedServices
+
+func (s *EventsTestSuite) Test_ForgetRemovedServices_PublishesRemoveEvent() {
+	service := NewService("unix:///var/run/docker.sock", "", "http://127.0.0.1:1")
+	service.Services["go-demo"] = true
+	events := eventStream.Subscribe()
+	defer eventStream.Unsubscribe(events)
+
+	service.ForgetRemovedServices([]string{"go-demo"})
+
+	s.Equal(Event{Type: "remove", ServiceName: "go-demo", Labels: map[string]string{}}, s.receive(events))
+}
+
+// Util
+
+func (s *EventsTestSuite) getService(name string) swarm.Service {
+	service := swarm.Service{}
+	service.Spec.Name = name
+	service.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	return service
+}
+
+func (s *EventsTestSuite) receive(ch chan Event) Event {
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(time.Second):
+		s.Fail("Event was not received")
+	}
+	return Event{}
+}
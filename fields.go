@@ -0,0 +1,93 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+)
+
+func getServiceMode(s swarm.Service) string {
+	if s.Spec.Mode.Global != nil {
+		return "global"
+	}
+	if s.Spec.Mode.Replicated != nil {
+		return "replicated"
+	}
+	return ""
+}
+
+func getReplicas(s swarm.Service) uint64 {
+	if s.Spec.Mode.Replicated == nil || s.Spec.Mode.Replicated.Replicas == nil {
+		return 0
+	}
+	return *s.Spec.Mode.Replicated.Replicas
+}
+
+func getImage(s swarm.Service) string {
+	if s.Spec.TaskTemplate.ContainerSpec == nil {
+		return ""
+	}
+	return s.Spec.TaskTemplate.ContainerSpec.Image
+}
+
+func getContainerLabels(s swarm.Service) map[string]string {
+	if s.Spec.TaskTemplate.ContainerSpec == nil || s.Spec.TaskTemplate.ContainerSpec.Labels == nil {
+		return map[string]string{}
+	}
+	return s.Spec.TaskTemplate.ContainerSpec.Labels
+}
+
+func getServiceLabels(s swarm.Service) map[string]string {
+	labels := map[string]string{}
+	for k, v := range getContainerLabels(s) {
+		labels[k] = v
+	}
+	// Service labels take precedence over the labels defined in the task template
+	for k, v := range s.Spec.Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+func getEnv(s swarm.Service) []string {
+	if s.Spec.TaskTemplate.ContainerSpec == nil {
+		return []string{}
+	}
+	return s.Spec.TaskTemplate.ContainerSpec.Env
+}
+
+func getSecrets(s swarm.Service) []*swarm.SecretReference {
+	if s.Spec.TaskTemplate.ContainerSpec == nil {
+		return []*swarm.SecretReference{}
+	}
+	return s.Spec.TaskTemplate.ContainerSpec.Secrets
+}
+
+func getConfigs(s swarm.Service) []*swarm.ConfigReference {
+	if s.Spec.TaskTemplate.ContainerSpec == nil {
+		return []*swarm.ConfigReference{}
+	}
+	return s.Spec.TaskTemplate.ContainerSpec.Configs
+}
+
+func getRestartPolicy(s swarm.Service) swarm.RestartPolicy {
+	if s.Spec.TaskTemplate.RestartPolicy == nil {
+		return swarm.RestartPolicy{}
+	}
+	return *s.Spec.TaskTemplate.RestartPolicy
+}
+
+func getPlacementPreferences(s swarm.Service) []swarm.PlacementPreference {
+	if s.Spec.TaskTemplate.Placement == nil {
+		return []swarm.PlacementPreference{}
+	}
+	return s.Spec.TaskTemplate.Placement.Preferences
+}
+
+func getPorts(s swarm.Service) []swarm.PortConfig {
+	if len(s.Endpoint.Ports) > 0 {
+		return s.Endpoint.Ports
+	}
+	if s.Spec.EndpointSpec == nil {
+		return []swarm.PortConfig{}
+	}
+	return s.Spec.EndpointSpec.Ports
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type FieldsTestSuite struct {
+	suite.Suite
+}
+
+func TestFieldsUnitTestSuite(t *testing.T) {
+	s := new(FieldsTestSuite)
+	suite.Run(t, s)
+}
+
+func (s *FieldsTestSuite) Test_Getters_DoNotPanic_WhenSubStructsAreNil() {
+	service := swarm.Service{
+		Spec: swarm.ServiceSpec{
+			Annotations: swarm.Annotations{Name: "go-demo"},
+		},
+	}
+
+	s.NotPanics(func() {
+		s.Equal("", getServiceMode(service))
+		s.Equal(uint64(0), getReplicas(service))
+		s.Equal("", getImage(service))
+		s.Empty(getContainerLabels(service))
+		s.Empty(getEnv(service))
+		s.Empty(getSecrets(service))
+		s.Empty(getConfigs(service))
+		s.Equal(swarm.RestartPolicy{}, getRestartPolicy(service))
+		s.Empty(getPlacementPreferences(service))
+		s.Empty(getPorts(service))
+	})
+}
+
+func (s *FieldsTestSuite) Test_Getters_DoNotPanic_WhenReplicasAreNil() {
+	service := swarm.Service{
+		Spec: swarm.ServiceSpec{
+			Mode: swarm.ServiceMode{Replicated: &swarm.ReplicatedService{}},
+		},
+	}
+
+	s.NotPanics(func() {
+		s.Equal("replicated", getServiceMode(service))
+		s.Equal(uint64(0), getReplicas(service))
+	})
+}
+
+func (s *FieldsTestSuite) Test_Getters_ReturnValues() {
+	replicas := uint64(3)
+	service := swarm.Service{
+		Spec: swarm.ServiceSpec{
+			Mode: swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &replicas}},
+			TaskTemplate: swarm.TaskSpec{
+				ContainerSpec: &swarm.ContainerSpec{
+					Image:  "vfarcic/go-demo",
+					Labels: map[string]string{"com.df.notify": "true"},
+					Env:    []string{"DB=go-demo-db"},
+				},
+				RestartPolicy: &swarm.RestartPolicy{Condition: swarm.RestartPolicyConditionAny},
+				Placement: &swarm.Placement{
+					Preferences: []swarm.PlacementPreference{{Spread: &swarm.SpreadOver{SpreadDescriptor: "node.labels.az"}}},
+				},
+			},
+			EndpointSpec: &swarm.EndpointSpec{
+				Ports: []swarm.PortConfig{{TargetPort: 8080}},
+			},
+		},
+	}
+
+	s.Equal("replicated", getServiceMode(service))
+	s.Equal(uint64(3), getReplicas(service))
+	s.Equal("vfarcic/go-demo", getImage(service))
+	s.Equal(map[string]string{"com.df.notify": "true"}, getContainerLabels(service))
+	s.Equal([]string{"DB=go-demo-db"}, getEnv(service))
+	s.Equal(swarm.RestartPolicyConditionAny, getRestartPolicy(service).Condition)
+	s.Equal(1, len(getPlacementPreferences(service)))
+	s.Equal(uint32(8080), getPorts(service)[0].TargetPort)
+}
+
+func (s *FieldsTestSuite) Test_GetServiceLabels_MergesTaskTemplateLabels() {
+	service := swarm.Service{
+		Spec: swarm.ServiceSpec{
+			Annotations: swarm.Annotations{Labels: map[string]string{"com.df.servicePath": "/service"}},
+			TaskTemplate: swarm.TaskSpec{
+				ContainerSpec: &swarm.ContainerSpec{
+					Labels: map[string]string{"com.df.notify": "true", "com.df.servicePath": "/task"},
+				},
+			},
+		},
+	}
+
+	s.Equal(
+		map[string]string{"com.df.notify": "true", "com.df.servicePath": "/service"},
+		getServiceLabels(service),
+	)
+}
+
+func (s *FieldsTestSuite) Test_GetServiceMode_ReturnsGlobal() {
+	service := swarm.Service{
+		Spec: swarm.ServiceSpec{
+			Mode: swarm.ServiceMode{Global: &swarm.GlobalService{}},
+		},
+	}
+
+	s.Equal("global", getServiceMode(service))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"time"
+)
+
+type flapState struct {
+	labeled     bool
+	transitions []time.Time
+	flapping    bool
+}
+
+func (m *Service) detectFlaps(services []swarm.Service) {
+	if m.FlapThreshold <= 0 {
+		return
+	}
+	existing := map[string]bool{}
+	for _, s := range services {
+		existing[s.Spec.Name] = true
+		labeled := m.hasNotifyLabel(s)
+		state, ok := m.flapStates[s.Spec.Name]
+		if !ok {
+			m.flapStates[s.Spec.Name] = &flapState{labeled: labeled}
+			continue
+		}
+		if labeled != state.labeled {
+			state.labeled = labeled
+			state.transitions = append(state.transitions, time.Now())
+		}
+		recent := []time.Time{}
+		for _, t := range state.transitions {
+			if time.Since(t) < m.FlapWindow {
+				recent = append(recent, t)
+			}
+		}
+		state.transitions = recent
+		if !state.flapping && len(state.transitions) >= m.FlapThreshold {
+			state.flapping = true
+			logPrintf("WARNING: The label %s of the service %s changed %d times within %s. Notifications for the service are suppressed until it stabilizes", m.NotifyLabel, s.Spec.Name, len(state.transitions), m.FlapWindow)
+		} else if state.flapping && len(state.transitions) == 0 {
+			state.flapping = false
+			logPrintf("The service %s stabilized. Notifications for the service are resumed", s.Spec.Name)
+		}
+	}
+	for name := range m.flapStates {
+		if !existing[name] {
+			delete(m.flapStates, name)
+		}
+	}
+}
+
+func (m *Service) isFlapping(serviceName string) bool {
+	state, ok := m.flapStates[serviceName]
+	return ok && state.flapping
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+	"time"
+)
+
+type FlapTestSuite struct {
+	suite.Suite
+}
+
+func TestFlapUnitTestSuite(t *testing.T) {
+	s := new(FlapTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+func (s *FlapTestSuite) SetupTest() {
+	serviceLastCreatedAt = time.Time{}
+}
+
+// detectFlaps
+
+func (s *FlapTestSuite) Test_DetectFlaps_SuppressesNotifications_WhenLabelIsToggledRapidly() {
+	service := s.getFlappingService()
+
+	events := s.toggle(service, 6)
+
+	s.Equal([]string{"create", "remove", "create"}, events)
+	s.True(service.isFlapping("go-demo"))
+}
+
+func (s *FlapTestSuite) Test_DetectFlaps_NotifiesFinalState_WhenServiceStabilizes() {
+	service := s.getFlappingService()
+	s.toggle(service, 6)
+	service.flapStates["go-demo"].transitions = []time.Time{time.Now().Add(-2 * time.Minute)}
+
+	events := s.cycle(service, s.getService(false))
+
+	s.Equal([]string{"remove"}, events)
+	s.False(service.isFlapping("go-demo"))
+}
+
+func (s *FlapTestSuite) Test_DetectFlaps_DoesNotSuppressNotifications_WhenFlapThresholdIsNotSet() {
+	service := s.getFlappingService()
+	service.FlapThreshold = 0
+
+	events := s.toggle(service, 6)
+
+	s.Equal(6, len(events))
+}
+
+func (s *FlapTestSuite) Test_DetectFlaps_NotifiesRemoval_WhenFlappingServiceIsDeleted() {
+	service := s.getFlappingService()
+	s.toggle(service, 5)
+
+	removed := service.GetRemovedServices([]swarm.Service{})
+
+	s.Equal([]string{"go-demo"}, removed)
+}
+
+// NewServiceFromEnv
+
+func (s *FlapTestSuite) Test_NewServiceFromEnv_SetsFlapDetection() {
+	thresholdOrig := os.Getenv("DF_FLAP_THRESHOLD")
+	windowOrig := os.Getenv("DF_FLAP_WINDOW")
+	defer func() {
+		os.Setenv("DF_FLAP_THRESHOLD", thresholdOrig)
+		os.Setenv("DF_FLAP_WINDOW", windowOrig)
+	}()
+	os.Setenv("DF_FLAP_THRESHOLD", "4")
+	os.Setenv("DF_FLAP_WINDOW", "60")
+
+	service := NewServiceFromEnv()
+
+	s.Equal(4, service.FlapThreshold)
+	s.Equal(time.Minute, service.FlapWindow)
+}
+
+// Util
+
+func (s *FlapTestSuite) getFlappingService() *Service {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.FlapThreshold = 3
+	service.FlapWindow = time.Minute
+	return service
+}
+
+func (s *FlapTestSuite) toggle(service *Service, cycles int) []string {
+	events := []string{}
+	for i := 0; i < cycles; i++ {
+		events = append(events, s.cycle(service, s.getService(i%2 == 0))...)
+	}
+	return events
+}
+
+func (s *FlapTestSuite) cycle(service *Service, srv swarm.Service) []string {
+	events := []string{}
+	created, _ := service.GetNewServices([]swarm.Service{srv})
+	for range created {
+		events = append(events, "create")
+	}
+	removed := service.GetRemovedServices([]swarm.Service{srv})
+	for range removed {
+		events = append(events, "remove")
+	}
+	service.NotifyServicesRemove(removed, 1, 0)
+	return events
+}
+
+func (s *FlapTestSuite) getService(labeled bool) swarm.Service {
+	srv := swarm.Service{}
+	srv.ID = "id"
+	srv.Spec.Name = "go-demo"
+	srv.Spec.Labels = map[string]string{}
+	if labeled {
+		srv.Spec.Labels["com.df.notify"] = "true"
+	}
+	return srv
+}
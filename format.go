@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type NotificationBody struct {
+	Event         string            `json:"event"`
+	Parameters    map[string]string `json:"parameters"`
+	ServiceDomain []string          `json:"serviceDomain,omitempty"`
+}
+
+func (m *Service) doNotification(client *http.Client, serviceName, event string, payload TransformResponse) (*http.Response, error) {
+	req, err := m.getTransformedRequest(event, payload)
+	if err != nil {
+		return nil, err
+	}
+	m.setTraceHeader(req, serviceName)
+	return client.Do(req.WithContext(m.getCycleContext()))
+}
+
+func (m *Service) getNotificationRequest(event, fullUrl string) (*http.Request, error) {
+	if t, ok := m.getEndpointTemplate(fullUrl); ok {
+		return m.getEndpointTemplateRequest(t, event, fullUrl)
+	}
+	format := m.getNotificationFormat(fullUrl)
+	if len(format) == 0 {
+		return http.NewRequest("GET", fullUrl, nil)
+	}
+	postUrl, contentType, body, err := m.getNotificationPost(format, event, fullUrl)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", postUrl, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return req, nil
+}
+
+func (m *Service) getNotificationFormat(fullUrl string) string {
+	switch m.EndpointMethods[getEndpoint(fullUrl)] {
+	case "GET":
+		return ""
+	case "POST":
+		return m.getPostFormat()
+	}
+	if m.NotifyMethod != "auto" {
+		return m.NotifyFormat
+	}
+	// Small payloads stay in the query string and only those that receivers might reject are moved to the body
+	if len(fullUrl) <= m.NotifyAutoThreshold {
+		return ""
+	}
+	return m.getPostFormat()
+}
+
+func (m *Service) getPostFormat() string {
+	if len(m.NotifyFormat) == 0 {
+		return "json"
+	}
+	return m.NotifyFormat
+}
+
+func getEndpointMethods(value string) map[string]string {
+	methods := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); len(entry) == 0 {
+			continue
+		}
+		kv := strings.SplitN(entry, "=", 2)
+		method := ""
+		if len(kv) == 2 {
+			method = strings.ToUpper(strings.TrimSpace(kv[1]))
+		}
+		methods[strings.TrimSpace(kv[0])] = method
+	}
+	return methods
+}
+
+func (m *Service) validateEndpointMethods() error {
+	for endpoint, method := range m.EndpointMethods {
+		if method != "GET" && method != "POST" {
+			return fmt.Errorf("DF_ENDPOINT_METHODS entry %s must be in the format <host>=GET or <host>=POST", endpoint)
+		}
+	}
+	return nil
+}
+
+func (m *Service) getNotificationPost(format, event, fullUrl string) (string, string, []byte, error) {
+	u, err := url.Parse(fullUrl)
+	if err != nil {
+		return "", "", nil, err
+	}
+	body := NotificationBody{Event: event, Parameters: map[string]string{}}
+	// Label values are escaped when the URL is built so the query gives them back unchanged
+	for k, v := range u.Query() {
+		if k == "serviceDomain" {
+			body.ServiceDomain = v
+			continue
+		}
+		body.Parameters[k] = v[0]
+	}
+	u.RawQuery = ""
+	switch format {
+	case "json":
+		data, err := json.Marshal(body)
+		return u.String(), "application/json", data, err
+	case "msgpack":
+		return u.String(), "application/msgpack", encodeMsgpackBody(body), nil
+	}
+	return "", "", nil, fmt.Errorf("Notification format %s is not supported", format)
+}
+
+func encodeMsgpackBody(body NotificationBody) []byte {
+	var buf bytes.Buffer
+	if len(body.ServiceDomain) > 0 {
+		writeMsgpackMapHeader(&buf, 3)
+	} else {
+		writeMsgpackMapHeader(&buf, 2)
+	}
+	writeMsgpackString(&buf, "event")
+	writeMsgpackString(&buf, body.Event)
+	writeMsgpackString(&buf, "parameters")
+	keys := []string{}
+	for k := range body.Parameters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	writeMsgpackMapHeader(&buf, len(keys))
+	for _, k := range keys {
+		writeMsgpackString(&buf, k)
+		writeMsgpackString(&buf, body.Parameters[k])
+	}
+	if len(body.ServiceDomain) > 0 {
+		writeMsgpackString(&buf, "serviceDomain")
+		writeMsgpackArrayHeader(&buf, len(body.ServiceDomain))
+		for _, d := range body.ServiceDomain {
+			writeMsgpackString(&buf, d)
+		}
+	}
+	return buf.Bytes()
+}
+
+func writeMsgpackMapHeader(buf *bytes.Buffer, size int) {
+	switch {
+	case size < 16:
+		buf.WriteByte(0x80 | byte(size))
+	case size <= 0xffff:
+		buf.WriteByte(0xde)
+		binary.Write(buf, binary.BigEndian, uint16(size))
+	default:
+		buf.WriteByte(0xdf)
+		binary.Write(buf, binary.BigEndian, uint32(size))
+	}
+}
+
+func writeMsgpackArrayHeader(buf *bytes.Buffer, size int) {
+	switch {
+	case size < 16:
+		buf.WriteByte(0x90 | byte(size))
+	case size <= 0xffff:
+		buf.WriteByte(0xdc)
+		binary.Write(buf, binary.BigEndian, uint16(size))
+	default:
+		buf.WriteByte(0xdd)
+		binary.Write(buf, binary.BigEndian, uint32(size))
+	}
+}
+
+func writeMsgpackString(buf *bytes.Buffer, value string) {
+	size := len(value)
+	switch {
+	case size < 32:
+		buf.WriteByte(0xa0 | byte(size))
+	case size <= 0xff:
+		buf.WriteByte(0xd9)
+		buf.WriteByte(byte(size))
+	case size <= 0xffff:
+		buf.WriteByte(0xda)
+		binary.Write(buf, binary.BigEndian, uint16(size))
+	default:
+		buf.WriteByte(0xdb)
+		binary.Write(buf, binary.BigEndian, uint32(size))
+	}
+	buf.WriteString(value)
+}
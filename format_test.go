@@ -0,0 +1,422 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+type FormatTestSuite struct {
+	suite.Suite
+}
+
+func TestFormatUnitTestSuite(t *testing.T) {
+	s := new(FormatTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// sendNotification
+
+func (s *FormatTestSuite) Test_SendNotification_SendsGetRequest_WhenFormatIsNotSet() {
+	var method, query string
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		query = r.URL.RawQuery
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL+"/v1/docker-flow-proxy/reconfigure?serviceName=go-demo", 1, 0)
+
+	s.NoError(err)
+	s.Equal("GET", method)
+	s.Equal("serviceName=go-demo", query)
+}
+
+func (s *FormatTestSuite) Test_SendNotification_PostsMsgpackBody_WhenFormatIsMsgpack() {
+	var method, path, query, contentType string
+	var body []byte
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		path = r.URL.Path
+		query = r.URL.RawQuery
+		contentType = r.Header.Get("Content-Type")
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyFormat = "msgpack"
+	longValue := strings.Repeat("x", 300)
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL+"/v1/docker-flow-proxy/reconfigure?serviceName=go-demo&distribute=true&long="+longValue, 1, 0)
+
+	s.NoError(err)
+	s.Equal("POST", method)
+	s.Equal("/v1/docker-flow-proxy/reconfigure", path)
+	s.Empty(query)
+	s.Equal("application/msgpack", contentType)
+	actual, rest := s.decodeMsgpack(body)
+	s.Empty(rest)
+	expected := map[string]interface{}{
+		"event": "create",
+		"parameters": map[string]interface{}{
+			"serviceName": "go-demo",
+			"distribute":  "true",
+			"long":        longValue,
+		},
+	}
+	s.Equal(expected, actual)
+}
+
+func (s *FormatTestSuite) Test_SendNotification_PostsJsonBody_WhenFormatIsJson() {
+	var contentType string
+	actual := NotificationBody{}
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		json.NewDecoder(r.Body).Decode(&actual)
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyFormat = "json"
+
+	err := service.sendNotification("go-demo", "remove", httpSrv.URL+"/v1/docker-flow-proxy/remove?serviceName=go-demo", 1, 0)
+
+	s.NoError(err)
+	s.Equal("application/json", contentType)
+	s.Equal(NotificationBody{Event: "remove", Parameters: map[string]string{"serviceName": "go-demo"}}, actual)
+}
+
+func (s *FormatTestSuite) Test_SendNotification_SendsServiceDomainsAsJsonArray() {
+	actual := NotificationBody{}
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&actual)
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyFormat = "json"
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL+"?serviceName=go-demo&serviceDomain=a.com&serviceDomain=b.com", 1, 0)
+
+	s.NoError(err)
+	s.Equal(map[string]string{"serviceName": "go-demo"}, actual.Parameters)
+	s.Equal([]string{"a.com", "b.com"}, actual.ServiceDomain)
+}
+
+func (s *FormatTestSuite) Test_SendNotification_SendsServiceDomainsAsMsgpackArray() {
+	var body []byte
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyFormat = "msgpack"
+
+	service.sendNotification("go-demo", "create", httpSrv.URL+"?serviceName=go-demo&serviceDomain=a.com&serviceDomain=b.com", 1, 0)
+
+	actual, rest := s.decodeMsgpack(body)
+	s.Empty(rest)
+	expected := map[string]interface{}{
+		"event":         "create",
+		"parameters":    map[string]interface{}{"serviceName": "go-demo"},
+		"serviceDomain": []interface{}{"a.com", "b.com"},
+	}
+	s.Equal(expected, actual)
+}
+
+func (s *FormatTestSuite) Test_SendNotification_ReturnsError_WhenFormatIsNotSupported() {
+	called := false
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyFormat = "xml"
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL+"?serviceName=go-demo", 1, 0)
+
+	s.Error(err)
+	s.False(called)
+}
+
+func (s *FormatTestSuite) Test_SendNotification_SendsGetRequest_WhenMethodIsAutoAndUrlIsShort() {
+	var method, query string
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		query = r.URL.RawQuery
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyMethod = "auto"
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL+"?serviceName=go-demo", 1, 0)
+
+	s.NoError(err)
+	s.Equal("GET", method)
+	s.Equal("serviceName=go-demo", query)
+}
+
+func (s *FormatTestSuite) Test_SendNotification_PostsJsonBody_WhenMethodIsAutoAndUrlIsLong() {
+	var method, query, contentType string
+	actual := NotificationBody{}
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		query = r.URL.RawQuery
+		contentType = r.Header.Get("Content-Type")
+		json.NewDecoder(r.Body).Decode(&actual)
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyMethod = "auto"
+	service.NotifyAutoThreshold = 100
+	longValue := strings.Repeat("x", 100)
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL+"?serviceName=go-demo&servicePath="+longValue, 1, 0)
+
+	s.NoError(err)
+	s.Equal("POST", method)
+	s.Empty(query)
+	s.Equal("application/json", contentType)
+	s.Equal(map[string]string{"serviceName": "go-demo", "servicePath": longValue}, actual.Parameters)
+}
+
+func (s *FormatTestSuite) Test_SendNotification_PostsInConfiguredFormat_WhenMethodIsAutoAndUrlIsLong() {
+	var method, contentType string
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		contentType = r.Header.Get("Content-Type")
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyMethod = "auto"
+	service.NotifyFormat = "msgpack"
+	service.NotifyAutoThreshold = 10
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL+"?serviceName=go-demo", 1, 0)
+
+	s.NoError(err)
+	s.Equal("POST", method)
+	s.Equal("application/msgpack", contentType)
+}
+
+// NotifyServicesCreate
+
+func (s *FormatTestSuite) Test_NotifyServicesCreate_SendsGetAndPostRequests_WhenEndpointMethodsDiffer() {
+	var getMethod, getQuery, postMethod, postQuery, postContentType string
+	postBody := NotificationBody{}
+	getSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		getMethod = r.Method
+		getQuery = r.URL.RawQuery
+	}))
+	defer getSrv.Close()
+	postSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postMethod = r.Method
+		postQuery = r.URL.RawQuery
+		postContentType = r.Header.Get("Content-Type")
+		json.NewDecoder(r.Body).Decode(&postBody)
+	}))
+	defer postSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", getSrv.URL+","+postSrv.URL, "")
+	service.EndpointMethods = getEndpointMethods(getEndpoint(getSrv.URL) + "=GET," + getEndpoint(postSrv.URL) + "=post")
+	service.NotifyFormat = "json"
+	srv := swarm.Service{}
+	srv.Spec.Name = "go-demo"
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true", "com.df.servicePath": "/demo"}
+
+	err := service.NotifyServicesCreate([]swarm.Service{srv}, 1, 0)
+
+	s.NoError(err)
+	s.Equal("GET", getMethod)
+	s.Equal("serviceName=go-demo&servicePath=/demo", getQuery)
+	s.Equal("POST", postMethod)
+	s.Empty(postQuery)
+	s.Equal("application/json", postContentType)
+	s.Equal("create", postBody.Event)
+	s.Equal(map[string]string{"serviceName": "go-demo", "servicePath": "/demo"}, postBody.Parameters)
+}
+
+func (s *FormatTestSuite) Test_NotifyServicesCreate_PostsLabelValuesUnchanged_WhenFormatIsJson() {
+	actual := s.getPostedParameters(func(service *Service, receiverUrl string) {
+		service.NotifyFormat = "json"
+	})
+
+	s.Equal(s.getSpecialParameters(), actual)
+}
+
+func (s *FormatTestSuite) Test_NotifyServicesCreate_PostsLabelValuesUnchanged_WhenMethodIsAuto() {
+	actual := s.getPostedParameters(func(service *Service, receiverUrl string) {
+		service.NotifyMethod = "auto"
+		service.NotifyAutoThreshold = 10
+	})
+
+	s.Equal(s.getSpecialParameters(), actual)
+}
+
+func (s *FormatTestSuite) Test_NotifyServicesCreate_PostsLabelValuesUnchanged_WhenEndpointMethodIsPost() {
+	actual := s.getPostedParameters(func(service *Service, receiverUrl string) {
+		service.EndpointMethods = map[string]string{getEndpoint(receiverUrl): "POST"}
+	})
+
+	s.Equal(s.getSpecialParameters(), actual)
+}
+
+func (s *FormatTestSuite) Test_SendNotification_PostsJsonBody_WhenEndpointIsPostAndFormatIsNotSet() {
+	var method, contentType string
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		contentType = r.Header.Get("Content-Type")
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.EndpointMethods = map[string]string{getEndpoint(httpSrv.URL): "POST"}
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL+"?serviceName=go-demo", 1, 0)
+
+	s.NoError(err)
+	s.Equal("POST", method)
+	s.Equal("application/json", contentType)
+}
+
+// ValidateTemplates
+
+func (s *FormatTestSuite) Test_ValidateTemplates_ReturnsError_WhenEndpointMethodIsInvalid() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	for _, value := range []string{"proxy:8080=PUT", "proxy:8080"} {
+		service.EndpointMethods = getEndpointMethods(value)
+
+		s.Error(service.ValidateTemplates(), value)
+	}
+}
+
+// NewServiceFromEnv
+
+func (s *FormatTestSuite) Test_NewServiceFromEnv_SetsNotifyFormat() {
+	formatOrig := os.Getenv("DF_NOTIFY_FORMAT")
+	defer func() { os.Setenv("DF_NOTIFY_FORMAT", formatOrig) }()
+	os.Setenv("DF_NOTIFY_FORMAT", "msgpack")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("msgpack", service.NotifyFormat)
+}
+
+func (s *FormatTestSuite) Test_NewServiceFromEnv_SetsEndpointMethods() {
+	methodsOrig := os.Getenv("DF_ENDPOINT_METHODS")
+	defer func() { os.Setenv("DF_ENDPOINT_METHODS", methodsOrig) }()
+	os.Setenv("DF_ENDPOINT_METHODS", "proxy:8080=GET, audit:9000=post")
+
+	service := NewServiceFromEnv()
+
+	s.Equal(map[string]string{"proxy:8080": "GET", "audit:9000": "POST"}, service.EndpointMethods)
+	s.NoError(service.ValidateTemplates())
+}
+
+func (s *FormatTestSuite) Test_NewServiceFromEnv_SetsNotifyAutoThreshold() {
+	thresholdOrig := os.Getenv("DF_NOTIFY_AUTO_THRESHOLD")
+	defer func() { os.Setenv("DF_NOTIFY_AUTO_THRESHOLD", thresholdOrig) }()
+	os.Unsetenv("DF_NOTIFY_AUTO_THRESHOLD")
+
+	s.Equal(2048, NewServiceFromEnv().NotifyAutoThreshold)
+
+	os.Setenv("DF_NOTIFY_AUTO_THRESHOLD", "4096")
+
+	s.Equal(4096, NewServiceFromEnv().NotifyAutoThreshold)
+}
+
+// Util
+
+func (s *FormatTestSuite) getPostedParameters(configure func(service *Service, receiverUrl string)) map[string]string {
+	actual := NotificationBody{}
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&actual)
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	configure(service, httpSrv.URL)
+	srv := swarm.Service{}
+	srv.Spec.Name = "go-demo"
+	srv.Spec.Labels = map[string]string{
+		"com.df.notify":        "true",
+		"com.df.servicePath":   "/a+b",
+		"com.df.reqPathSearch": "^/api(/|$)%20",
+		"com.df.query":         "a=1&b=2#top",
+	}
+
+	s.NoError(service.NotifyServicesCreate([]swarm.Service{srv}, 1, 0))
+
+	return actual.Parameters
+}
+
+func (s *FormatTestSuite) getSpecialParameters() map[string]string {
+	return map[string]string{
+		"serviceName":   "go-demo",
+		"servicePath":   "/a+b",
+		"reqPathSearch": "^/api(/|$)%20",
+		"query":         "a=1&b=2#top",
+	}
+}
+
+func (s *FormatTestSuite) decodeMsgpack(data []byte) (interface{}, []byte) {
+	if len(data) == 0 {
+		s.Fail("Unexpected end of msgpack data")
+		return nil, data
+	}
+	b := data[0]
+	switch {
+	case b&0xf0 == 0x80:
+		return s.decodeMsgpackMap(int(b&0x0f), data[1:])
+	case b == 0xde:
+		return s.decodeMsgpackMap(int(binary.BigEndian.Uint16(data[1:3])), data[3:])
+	case b == 0xdf:
+		return s.decodeMsgpackMap(int(binary.BigEndian.Uint32(data[1:5])), data[5:])
+	case b&0xf0 == 0x90:
+		return s.decodeMsgpackArray(int(b&0x0f), data[1:])
+	case b&0xe0 == 0xa0:
+		size := int(b & 0x1f)
+		return string(data[1 : 1+size]), data[1+size:]
+	case b == 0xd9:
+		size := int(data[1])
+		return string(data[2 : 2+size]), data[2+size:]
+	case b == 0xda:
+		size := int(binary.BigEndian.Uint16(data[1:3]))
+		return string(data[3 : 3+size]), data[3+size:]
+	case b == 0xdb:
+		size := int(binary.BigEndian.Uint32(data[1:5]))
+		return string(data[5 : 5+size]), data[5+size:]
+	}
+	s.Failf("Unexpected msgpack type", "0x%x", b)
+	return nil, nil
+}
+
+func (s *FormatTestSuite) decodeMsgpackArray(size int, data []byte) (interface{}, []byte) {
+	a := []interface{}{}
+	for i := 0; i < size; i++ {
+		var v interface{}
+		v, data = s.decodeMsgpack(data)
+		a = append(a, v)
+	}
+	return a, data
+}
+
+func (s *FormatTestSuite) decodeMsgpackMap(size int, data []byte) (interface{}, []byte) {
+	m := map[string]interface{}{}
+	for i := 0; i < size; i++ {
+		var k, v interface{}
+		k, data = s.decodeMsgpack(data)
+		v, data = s.decodeMsgpack(data)
+		m[k.(string)] = v
+	}
+	return m, data
+}
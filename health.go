@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+)
+
+func getUnhealthyTasks(tasks []swarm.Task) []swarm.Task {
+	// Only the latest task of each slot counts since older ones stay in the task history
+	latest := map[string]swarm.Task{}
+	for _, t := range tasks {
+		slot := fmt.Sprintf("%d/%s", t.Slot, t.NodeID)
+		if t.Slot > 0 {
+			slot = fmt.Sprintf("%d", t.Slot)
+		}
+		if current, ok := latest[slot]; !ok || t.Meta.CreatedAt.After(current.Meta.CreatedAt) {
+			latest[slot] = t
+		}
+	}
+	unhealthy := []swarm.Task{}
+	for _, t := range latest {
+		// Tasks with a healthcheck stay in the starting state until the container is healthy
+		if t.DesiredState == swarm.TaskStateRunning && t.Status.State != swarm.TaskStateRunning {
+			unhealthy = append(unhealthy, t)
+		}
+	}
+	return unhealthy
+}
+
+func (m *Service) NotifyServicesHealth(services []swarm.Service, retries, interval int) error {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	if len(m.NotifHealthServiceUrl) == 0 {
+		return nil
+	}
+	errs := []error{}
+	for _, s := range services {
+		if _, ok := m.Services[s.Spec.Name]; !ok {
+			continue
+		}
+		tasks, err := m.getServiceTasks(s.ID)
+		if err != nil {
+			logPrintf("WARNING: Could not list tasks of the service %s\n%s", s.Spec.Name, err.Error())
+			continue
+		}
+		unhealthy := getUnhealthyTasks(tasks)
+		healthy := len(unhealthy) == 0
+		previous, ok := m.HealthStates[s.Spec.Name]
+		if !ok {
+			// The first observation is the baseline and is not a transition
+			m.HealthStates[s.Spec.Name] = healthy
+			continue
+		}
+		if previous == healthy {
+			continue
+		}
+		if healthy {
+			logPrintf("All tasks of the service %s are healthy", s.Spec.Name)
+		} else {
+			logPrintf("WARNING: %d tasks of the service %s are not healthy", len(unhealthy), s.Spec.Name)
+		}
+		fullUrl := fmt.Sprintf("%s?serviceName=%s&healthy=%t&unhealthyTasks=%d", m.NotifHealthServiceUrl, s.Spec.Name, healthy, len(unhealthy))
+		logPrintf("Sending service health notification to %s", fullUrl)
+		if err := m.sendNotificationUnlocked(s.Spec.Name, "health", fullUrl, retries, interval); err != nil {
+			errs = append(errs, err)
+		} else {
+			m.HealthStates[s.Spec.Name] = healthy
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("At least one request produced errors. Please consult logs for more details.")
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type HealthTestSuite struct {
+	suite.Suite
+	tasks   []swarm.Task
+	queries []string
+}
+
+func TestHealthUnitTestSuite(t *testing.T) {
+	s := new(HealthTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+func (s *HealthTestSuite) SetupTest() {
+	s.tasks = []swarm.Task{}
+	s.queries = []string{}
+}
+
+// getUnhealthyTasks
+
+func (s *HealthTestSuite) Test_GetUnhealthyTasks_ConsidersOnlyTheLatestTaskOfEachSlot() {
+	tasks := []swarm.Task{
+		s.getTask(1, swarm.TaskStateFailed, swarm.TaskStateShutdown, 2*time.Minute),
+		s.getTask(1, swarm.TaskStateRunning, swarm.TaskStateRunning, time.Minute),
+		s.getTask(2, swarm.TaskStateStarting, swarm.TaskStateRunning, time.Minute),
+	}
+
+	actual := getUnhealthyTasks(tasks)
+
+	s.Equal(1, len(actual))
+	s.Equal(2, actual[0].Slot)
+}
+
+// NotifyServicesHealth
+
+func (s *HealthTestSuite) Test_NotifyServicesHealth_NotifiesHealthTransitions() {
+	dockerSrv := s.newFakeDockerServer()
+	defer dockerSrv.Close()
+	notifSrv := s.newReceiver()
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, notifSrv.URL)
+
+	s.tasks = []swarm.Task{s.getTask(1, swarm.TaskStateRunning, swarm.TaskStateRunning, time.Minute)}
+	service.NotifyServicesHealth(s.getServices(), 1, 0)
+	s.Empty(s.queries, "The initial state should not be notified")
+
+	s.tasks = append(s.tasks, s.getTask(1, swarm.TaskStateStarting, swarm.TaskStateRunning, time.Second))
+	err := service.NotifyServicesHealth(s.getServices(), 1, 0)
+	s.NoError(err)
+	s.Equal([]string{"serviceName=go-demo&healthy=false&unhealthyTasks=1"}, s.queries)
+
+	service.NotifyServicesHealth(s.getServices(), 1, 0)
+	s.Equal(1, len(s.queries), "The same state should not be notified twice")
+
+	s.tasks = append(s.tasks, s.getTask(1, swarm.TaskStateRunning, swarm.TaskStateRunning, 0))
+	service.NotifyServicesHealth(s.getServices(), 1, 0)
+	s.Equal([]string{
+		"serviceName=go-demo&healthy=false&unhealthyTasks=1",
+		"serviceName=go-demo&healthy=true&unhealthyTasks=0",
+	}, s.queries)
+}
+
+func (s *HealthTestSuite) Test_NotifyServicesHealth_DoesNotSendRequests_WhenUrlIsNotSet() {
+	dockerSrv := s.newFakeDockerServer()
+	defer dockerSrv.Close()
+	service := s.getService(dockerSrv, "")
+	s.tasks = []swarm.Task{s.getTask(1, swarm.TaskStateRunning, swarm.TaskStateRunning, time.Minute)}
+
+	err := service.NotifyServicesHealth(s.getServices(), 1, 0)
+
+	s.NoError(err)
+	s.Empty(service.HealthStates)
+}
+
+func (s *HealthTestSuite) Test_NotifyServicesHealth_IgnoresUntrackedServices() {
+	dockerSrv := s.newFakeDockerServer()
+	defer dockerSrv.Close()
+	notifSrv := s.newReceiver()
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, notifSrv.URL)
+	delete(service.Services, "go-demo")
+
+	service.NotifyServicesHealth(s.getServices(), 1, 0)
+
+	s.Empty(service.HealthStates)
+}
+
+// NewServiceFromEnv
+
+func (s *HealthTestSuite) Test_NewServiceFromEnv_SetsNotifHealthServiceUrl() {
+	urlOrig := os.Getenv("DF_NOTIF_HEALTH_URL")
+	defer func() { os.Setenv("DF_NOTIF_HEALTH_URL", urlOrig) }()
+	os.Setenv("DF_NOTIF_HEALTH_URL", "http://proxy/health")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("http://proxy/health", service.NotifHealthServiceUrl)
+}
+
+// Util
+
+func (s *HealthTestSuite) getTask(slot int, state, desiredState swarm.TaskState, age time.Duration) swarm.Task {
+	task := swarm.Task{
+		Slot:         slot,
+		DesiredState: desiredState,
+		Status:       swarm.TaskStatus{State: state},
+	}
+	task.Meta.CreatedAt = time.Now().Add(-age)
+	return task
+}
+
+func (s *HealthTestSuite) getServices() []swarm.Service {
+	service := swarm.Service{ID: "go-demo-id"}
+	service.Spec.Name = "go-demo"
+	service.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	return []swarm.Service{service}
+}
+
+func (s *HealthTestSuite) getService(dockerSrv *httptest.Server, notifUrl string) *Service {
+	service := NewService(strings.Replace(dockerSrv.URL, "http://", "tcp://", 1), "", "")
+	service.NotifHealthServiceUrl = notifUrl
+	service.Services["go-demo"] = true
+	return service
+}
+
+func (s *HealthTestSuite) newReceiver() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.queries = append(s.queries, r.URL.RawQuery)
+	}))
+}
+
+func (s *HealthTestSuite) newFakeDockerServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/tasks") {
+			json.NewEncoder(w).Encode(s.tasks)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+}
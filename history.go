@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"sync"
+	"time"
+)
+
+type RemovalRecord struct {
+	ServiceName string
+	Reason      string
+	Service     swarm.Service
+	RemovedAt   time.Time
+}
+
+type RemovalHistory struct {
+	mu      sync.Mutex
+	records []RemovalRecord
+	max     int
+	ttl     time.Duration
+}
+
+func (m *RemovalHistory) Add(serviceName, reason string, s swarm.Service) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.delete(serviceName)
+	m.records = append(m.records, RemovalRecord{
+		ServiceName: serviceName,
+		Reason:      reason,
+		Service:     s,
+		RemovedAt:   time.Now(),
+	})
+	m.evict()
+}
+
+func (m *RemovalHistory) Delete(serviceName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.delete(serviceName)
+}
+
+func (m *RemovalHistory) GetAll() []RemovalRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.evict()
+	return append([]RemovalRecord{}, m.records...)
+}
+
+func (m *RemovalHistory) delete(serviceName string) {
+	for i, r := range m.records {
+		if r.ServiceName == serviceName {
+			m.records = append(m.records[:i], m.records[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m *RemovalHistory) evict() {
+	// Records are appended in the order of removal so the oldest are always first
+	for len(m.records) > 0 && (len(m.records) > m.max || time.Since(m.records[0].RemovedAt) > m.ttl) {
+		m.records = m.records[1:]
+	}
+}
+
+func NewRemovalHistory(max int, ttl time.Duration) *RemovalHistory {
+	return &RemovalHistory{
+		records: []RemovalRecord{},
+		max:     max,
+		ttl:     ttl,
+	}
+}
+
+func (m *Service) ResendRemovals(retries, interval int) ([]string, error) {
+	// The API runs on its own goroutine so the retained removals are only read once the poll loop is not changing them
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	names := []string{}
+	notifications := []notification{}
+	for _, r := range m.RemovalHistory.GetAll() {
+		removals, err := m.getRemoveNotificationsFor(r.ServiceName, r.Reason, r.Service)
+		if err != nil {
+			logPrintf("ERROR: %s", err.Error())
+			return names, err
+		}
+		names = append(names, r.ServiceName)
+		for _, n := range removals {
+			logPrintf("Re-sending service removed notification to %s", m.redact(r.ServiceName, n.fullUrl))
+			notifications = append(notifications, n)
+		}
+	}
+	if errs := m.sendUnlocked(notifications, retries, interval); len(errs) > 0 {
+		return names, fmt.Errorf("At least one request produced errors. Please consult logs for more details.")
+	}
+	return names, nil
+}
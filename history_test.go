@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+type HistoryTestSuite struct {
+	suite.Suite
+}
+
+func TestHistoryUnitTestSuite(t *testing.T) {
+	s := new(HistoryTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// Add
+
+func (s *HistoryTestSuite) Test_Add_KeepsOnlyTheLatestRemovalOfAService() {
+	history := NewRemovalHistory(10, time.Hour)
+
+	history.Add("go-demo", "labelDropped", swarm.Service{})
+	history.Add("go-demo", "removed", swarm.Service{})
+
+	actual := history.GetAll()
+	s.Equal(1, len(actual))
+	s.Equal("removed", actual[0].Reason)
+}
+
+func (s *HistoryTestSuite) Test_Add_EvictsOldestRecords_WhenMaxIsReached() {
+	history := NewRemovalHistory(2, time.Hour)
+
+	history.Add("a", "removed", swarm.Service{})
+	history.Add("b", "removed", swarm.Service{})
+	history.Add("c", "removed", swarm.Service{})
+
+	s.Equal([]string{"b", "c"}, s.getNames(history))
+}
+
+// GetAll
+
+func (s *HistoryTestSuite) Test_GetAll_EvictsExpiredRecords() {
+	history := NewRemovalHistory(10, time.Minute)
+	history.Add("a", "removed", swarm.Service{})
+	history.Add("b", "removed", swarm.Service{})
+	history.records[0].RemovedAt = time.Now().Add(-2 * time.Minute)
+
+	s.Equal([]string{"b"}, s.getNames(history))
+}
+
+// NewServiceFromEnv
+
+func (s *HistoryTestSuite) Test_NewServiceFromEnv_BoundsRemovalHistory() {
+	maxOrig := os.Getenv("DF_REMOVAL_HISTORY_MAX")
+	ttlOrig := os.Getenv("DF_REMOVAL_HISTORY_TTL")
+	defer func() {
+		os.Setenv("DF_REMOVAL_HISTORY_MAX", maxOrig)
+		os.Setenv("DF_REMOVAL_HISTORY_TTL", ttlOrig)
+	}()
+	os.Setenv("DF_REMOVAL_HISTORY_MAX", "2")
+	os.Setenv("DF_REMOVAL_HISTORY_TTL", "60")
+
+	history := NewServiceFromEnv().RemovalHistory
+	history.Add("a", "removed", swarm.Service{})
+	history.Add("b", "removed", swarm.Service{})
+	history.Add("c", "removed", swarm.Service{})
+
+	s.Equal([]string{"b", "c"}, s.getNames(history))
+
+	history.records[0].RemovedAt = time.Now().Add(-61 * time.Second)
+
+	s.Equal([]string{"c"}, s.getNames(history))
+}
+
+func (s *HistoryTestSuite) Test_NewServiceFromEnv_SetsDefaultRemovalHistoryBounds() {
+	maxOrig := os.Getenv("DF_REMOVAL_HISTORY_MAX")
+	ttlOrig := os.Getenv("DF_REMOVAL_HISTORY_TTL")
+	defer func() {
+		os.Setenv("DF_REMOVAL_HISTORY_MAX", maxOrig)
+		os.Setenv("DF_REMOVAL_HISTORY_TTL", ttlOrig)
+	}()
+	os.Unsetenv("DF_REMOVAL_HISTORY_MAX")
+	os.Unsetenv("DF_REMOVAL_HISTORY_TTL")
+
+	history := NewServiceFromEnv().RemovalHistory
+
+	s.Equal(1000, history.max)
+	s.Equal(24*time.Hour, history.ttl)
+}
+
+// NotifyServicesRemove
+
+func (s *HistoryTestSuite) Test_NotifyServicesRemove_AddsServicesToHistory() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL)
+	srv := swarm.Service{}
+	srv.Spec.Name = "go-demo"
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	service.Services["go-demo"] = true
+	service.ServicesCache["go-demo"] = srv
+	service.RemovalReasons["go-demo"] = "removed"
+
+	service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	actual := service.RemovalHistory.GetAll()
+	s.Equal(1, len(actual))
+	s.Equal("go-demo", actual[0].ServiceName)
+	s.Equal("removed", actual[0].Reason)
+	s.Equal(srv, actual[0].Service)
+}
+
+// GetNewServices
+
+func (s *HistoryTestSuite) Test_GetNewServices_RemovesRecreatedServicesFromHistory() {
+	serviceLastCreatedAt = time.Time{}
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.RemovalHistory.Add("go-demo", "removed", swarm.Service{})
+	srv := swarm.Service{}
+	srv.Spec.Name = "go-demo"
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	srv.Meta.CreatedAt = time.Now()
+
+	service.GetNewServices([]swarm.Service{srv})
+
+	s.Empty(service.RemovalHistory.GetAll())
+}
+
+// ResendRemovals
+
+func (s *HistoryTestSuite) Test_ResendRemovals_UsesRemoveTemplateWithRetainedLabels() {
+	actualPath := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualPath = r.URL.RequestURI()
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifRemoveTemplate = httpSrv.URL + "/remove?serviceName={{.ServiceName}}&path={{index .Labels \"com.df.servicePath\"}}"
+	srv := swarm.Service{}
+	srv.Spec.Labels = map[string]string{"com.df.servicePath": "/demo"}
+	service.RemovalHistory.Add("go-demo", "removed", srv)
+
+	actual, err := service.ResendRemovals(1, 0)
+
+	s.NoError(err)
+	s.Equal([]string{"go-demo"}, actual)
+	s.Equal("/remove?serviceName=go-demo&path=/demo", actualPath)
+}
+
+func (s *HistoryTestSuite) Test_ResendRemovals_ReturnsError_WhenNotificationFails() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL)
+	service.RemovalHistory.Add("go-demo", "removed", swarm.Service{})
+
+	_, err := service.ResendRemovals(1, 0)
+
+	s.Error(err)
+}
+
+func (s *HistoryTestSuite) Test_ResendRemovals_DoesNotRaceWithPollLoop() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL)
+	service.RedactLabels = []string{"com.df.token"}
+	service.RemovalHistory.Add("removed", "removed", swarm.Service{})
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 20; i++ {
+			service.ResendRemovals(1, 0)
+		}
+		close(done)
+	}()
+
+	for i := 0; i < 1000; i++ {
+		srv := swarm.Service{}
+		srv.Spec.Name = fmt.Sprintf("go-demo-%d", i)
+		srv.Spec.Labels = map[string]string{"com.df.notify": "true", "com.df.token": "secret"}
+		srv.Meta.CreatedAt = time.Now()
+		service.GetNewServices([]swarm.Service{srv})
+	}
+	<-done
+
+	s.Equal([]string{"removed"}, s.getNames(service.RemovalHistory))
+}
+
+// Util
+
+func (s *HistoryTestSuite) getNames(history *RemovalHistory) []string {
+	names := []string{}
+	for _, r := range history.GetAll() {
+		names = append(names, r.ServiceName)
+	}
+	return names
+}
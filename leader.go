@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"golang.org/x/net/context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const leaderKey = "swarmListenerLeader"
+const instancesKey = "swarmListenerInstances"
+
+var isLeader = func() bool { return true }
+
+type Locker interface {
+	TryLock(instanceId string, lease time.Duration) (bool, error)
+}
+
+type Registry interface {
+	Register(instanceId string, lease time.Duration) ([]string, error)
+}
+
+type LeaderElection struct {
+	Lock       Locker
+	Registry   Registry
+	InstanceId string
+	Lease      time.Duration
+	leader     bool
+	joined     bool
+}
+
+func (m *LeaderElection) IsLeader() bool {
+	if m.Registry != nil {
+		peers, err := m.Registry.Register(m.InstanceId, m.Lease)
+		if err != nil {
+			logPrintf("WARNING: Could not register the instance %s\n%s", m.InstanceId, err.Error())
+		}
+		if !m.joined {
+			m.joined = true
+			// Peers already notified the existing services so the first iteration only tracks them
+			if err == nil && len(peers) > 0 {
+				logPrintf("Instance %s joined the listeners %s and will track services before sending notifications", m.InstanceId, strings.Join(peers, ", "))
+				return false
+			}
+		}
+	}
+	leader, err := m.Lock.TryLock(m.InstanceId, m.Lease)
+	if err != nil {
+		logPrintf("WARNING: Could not acquire the leader lock\n%s", err.Error())
+		leader = false
+	}
+	if leader && !m.leader {
+		logPrintf("Instance %s became the leader and will send notifications", m.InstanceId)
+	} else if !leader && m.leader {
+		logPrintf("Instance %s is not the leader any more and will stop sending notifications", m.InstanceId)
+	}
+	m.leader = leader
+	return leader
+}
+
+func NewLeaderElection(lock Locker, instanceId string, lease time.Duration) *LeaderElection {
+	return &LeaderElection{
+		Lock:       lock,
+		InstanceId: instanceId,
+		Lease:      lease,
+	}
+}
+
+type ServiceLabelLock struct {
+	Host        string
+	ServiceName string
+	LabelPrefix string
+}
+
+func (m *ServiceLabelLock) TryLock(instanceId string, lease time.Duration) (bool, error) {
+	dc, err := newDockerClient(m.Host)
+	if err != nil {
+		return false, err
+	}
+	s, _, err := dc.ServiceInspectWithRaw(context.Background(), m.ServiceName, types.ServiceInspectOptions{})
+	if err != nil {
+		return false, err
+	}
+	leaderLabel := m.LabelPrefix + leaderKey
+	holder, expiresAt := parseLeaderLabel(s.Spec.Labels[leaderLabel])
+	now := time.Now()
+	if holder != instanceId && now.Before(expiresAt) {
+		return false, nil
+	}
+	if holder == instanceId && expiresAt.Sub(now) > lease/2 {
+		return true, nil
+	}
+	spec := s.Spec
+	if spec.Labels == nil {
+		spec.Labels = map[string]string{}
+	}
+	spec.Labels[leaderLabel] = fmt.Sprintf("%s|%d", instanceId, now.Add(lease).Unix())
+	// The update fails when another instance modified the service since it was inspected so only one of them wins
+	if _, err := dc.ServiceUpdate(context.Background(), s.ID, s.Version, spec, types.ServiceUpdateOptions{}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (m *ServiceLabelLock) Register(instanceId string, lease time.Duration) ([]string, error) {
+	dc, err := newDockerClient(m.Host)
+	if err != nil {
+		return []string{}, err
+	}
+	s, _, err := dc.ServiceInspectWithRaw(context.Background(), m.ServiceName, types.ServiceInspectOptions{})
+	if err != nil {
+		return []string{}, err
+	}
+	now := time.Now()
+	instancesLabel := m.LabelPrefix + instancesKey
+	instances := parseInstancesLabel(s.Spec.Labels[instancesLabel])
+	peers := []string{}
+	changed := false
+	for id, expiresAt := range instances {
+		if now.After(expiresAt) {
+			delete(instances, id)
+			changed = true
+		} else if id != instanceId {
+			peers = append(peers, id)
+		}
+	}
+	sort.Strings(peers)
+	if expiresAt, ok := instances[instanceId]; !ok || expiresAt.Sub(now) <= lease/2 {
+		instances[instanceId] = now.Add(lease)
+		changed = true
+	}
+	if !changed {
+		return peers, nil
+	}
+	spec := s.Spec
+	if spec.Labels == nil {
+		spec.Labels = map[string]string{}
+	}
+	spec.Labels[instancesLabel] = formatInstancesLabel(instances)
+	if _, err := dc.ServiceUpdate(context.Background(), s.ID, s.Version, spec, types.ServiceUpdateOptions{}); err != nil {
+		return peers, err
+	}
+	return peers, nil
+}
+
+func parseInstancesLabel(value string) map[string]time.Time {
+	instances := map[string]time.Time{}
+	for _, entry := range strings.Split(value, ",") {
+		if id, expiresAt := parseLeaderLabel(entry); len(id) > 0 {
+			instances[id] = expiresAt
+		}
+	}
+	return instances
+}
+
+func formatInstancesLabel(instances map[string]time.Time) string {
+	entries := []string{}
+	for id, expiresAt := range instances {
+		entries = append(entries, fmt.Sprintf("%s|%d", id, expiresAt.Unix()))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+func parseLeaderLabel(value string) (string, time.Time) {
+	parts := strings.SplitN(value, "|", 2)
+	if len(parts) != 2 {
+		return "", time.Time{}
+	}
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}
+	}
+	return parts[0], time.Unix(expiresAt, 0)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type LeaderTestSuite struct {
+	suite.Suite
+}
+
+func TestLeaderUnitTestSuite(t *testing.T) {
+	s := new(LeaderTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// IsLeader
+
+func (s *LeaderTestSuite) Test_IsLeader_ReturnsTrueOnlyForOneInstance() {
+	lock := newMemoryLock()
+	leader := NewLeaderElection(lock, "instance-1", time.Minute)
+	follower := NewLeaderElection(lock, "instance-2", time.Minute)
+
+	s.True(leader.IsLeader())
+	s.False(follower.IsLeader())
+	s.True(leader.IsLeader())
+	s.False(follower.IsLeader())
+}
+
+func (s *LeaderTestSuite) Test_IsLeader_PromotesFollower_WhenLeaseExpires() {
+	lock := newMemoryLock()
+	leader := NewLeaderElection(lock, "instance-1", time.Millisecond)
+	follower := NewLeaderElection(lock, "instance-2", time.Minute)
+	leader.IsLeader()
+
+	time.Sleep(2 * time.Millisecond)
+
+	s.True(follower.IsLeader())
+	s.False(leader.IsLeader())
+}
+
+func (s *LeaderTestSuite) Test_IsLeader_ReturnsFalse_WhenLockFails() {
+	lock := newMemoryLock()
+	lock.err = fmt.Errorf("This is an error")
+	election := NewLeaderElection(lock, "instance-1", time.Minute)
+
+	s.False(election.IsLeader())
+}
+
+func (s *LeaderTestSuite) Test_IsLeader_ReturnsFalseOnFirstCall_WhenInstanceJoinsOtherInstances() {
+	lock := newMemoryLock()
+	first := NewLeaderElection(lock, "instance-1", time.Millisecond)
+	first.Registry = lock
+	second := NewLeaderElection(lock, "instance-2", time.Minute)
+	second.Registry = lock
+	s.True(first.IsLeader())
+	lock.instances["instance-1"] = time.Now().Add(time.Minute)
+	time.Sleep(2 * time.Millisecond)
+
+	s.False(second.IsLeader())
+	s.True(second.IsLeader())
+}
+
+func (s *LeaderTestSuite) Test_IsLeader_ReturnsTrueOnFirstCall_WhenInstanceIsAlone() {
+	lock := newMemoryLock()
+	election := NewLeaderElection(lock, "instance-1", time.Minute)
+	election.Registry = lock
+
+	s.True(election.IsLeader())
+	s.Contains(lock.instances, "instance-1")
+}
+
+func (s *LeaderTestSuite) Test_IsLeader_IgnoresExpiredInstances() {
+	lock := newMemoryLock()
+	lock.instances["instance-1"] = time.Now().Add(-time.Minute)
+	election := NewLeaderElection(lock, "instance-2", time.Minute)
+	election.Registry = lock
+
+	s.True(election.IsLeader())
+	s.NotContains(lock.instances, "instance-1")
+}
+
+// parseInstancesLabel
+
+func (s *LeaderTestSuite) Test_ParseInstancesLabel_ReturnsInstancesWrittenByFormatInstancesLabel() {
+	instances := map[string]time.Time{
+		"instance-1": time.Unix(1500000000, 0),
+		"instance-2": time.Unix(1500000060, 0),
+	}
+
+	label := formatInstancesLabel(instances)
+
+	s.Equal("instance-1|1500000000,instance-2|1500000060", label)
+	s.Equal(instances, parseInstancesLabel(label))
+}
+
+// parseLeaderLabel
+
+func (s *LeaderTestSuite) Test_ParseLeaderLabel_ReturnsHolderAndExpiration() {
+	holder, expiresAt := parseLeaderLabel("instance-1|1500000000")
+
+	s.Equal("instance-1", holder)
+	s.Equal(time.Unix(1500000000, 0), expiresAt)
+}
+
+func (s *LeaderTestSuite) Test_ParseLeaderLabel_ReturnsEmptyValues_WhenLabelIsMalformed() {
+	for _, value := range []string{"", "instance-1", "instance-1|not-a-number"} {
+		holder, expiresAt := parseLeaderLabel(value)
+
+		s.Equal("", holder)
+		s.True(expiresAt.IsZero())
+	}
+}
+
+// ServiceLabelLock
+
+func (s *LeaderTestSuite) Test_ServiceLabelLock_WritesLabelsWithLabelPrefix() {
+	updates := []swarm.ServiceSpec{}
+	dockerSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/services/lock-id/update"):
+			spec := swarm.ServiceSpec{}
+			json.NewDecoder(r.Body).Decode(&spec)
+			updates = append(updates, spec)
+			w.Write([]byte("{}"))
+		case strings.HasSuffix(r.URL.Path, "/services/lock"):
+			srv := swarm.Service{ID: "lock-id"}
+			srv.Spec.Name = "lock"
+			json.NewEncoder(w).Encode(srv)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer dockerSrv.Close()
+	lock := &ServiceLabelLock{Host: strings.Replace(dockerSrv.URL, "http://", "tcp://", 1), ServiceName: "lock", LabelPrefix: "acme."}
+
+	_, lockErr := lock.TryLock("instance-1", time.Minute)
+	_, registerErr := lock.Register("instance-1", time.Minute)
+
+	s.NoError(lockErr)
+	s.NoError(registerErr)
+	s.Equal(2, len(updates))
+	s.Contains(updates[0].Labels, "acme.swarmListenerLeader")
+	s.Contains(updates[1].Labels, "acme.swarmListenerInstances")
+}
+
+// Mocks
+
+type memoryLock struct {
+	mu        sync.Mutex
+	holder    string
+	expiresAt time.Time
+	instances map[string]time.Time
+	err       error
+}
+
+func (m *memoryLock) TryLock(instanceId string, lease time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return false, m.err
+	}
+	if m.holder != instanceId && time.Now().Before(m.expiresAt) {
+		return false, nil
+	}
+	m.holder = instanceId
+	m.expiresAt = time.Now().Add(lease)
+	return true, nil
+}
+
+func (m *memoryLock) Register(instanceId string, lease time.Duration) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return []string{}, m.err
+	}
+	peers := []string{}
+	for id, expiresAt := range m.instances {
+		if time.Now().After(expiresAt) {
+			delete(m.instances, id)
+		} else if id != instanceId {
+			peers = append(peers, id)
+		}
+	}
+	m.instances[instanceId] = time.Now().Add(lease)
+	return peers, nil
+}
+
+func newMemoryLock() *memoryLock {
+	return &memoryLock{instances: map[string]time.Time{}}
+}
@@ -1,25 +1,130 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"sync"
 	"time"
 )
 
 func main() {
 	logPrintf("Starting Docker Flow: Swarm Listener")
 	service := NewServiceFromEnv()
+	if err := service.ValidateTemplates(); err != nil {
+		logPrintf("ERROR: %s", err.Error())
+		os.Exit(1)
+	}
+	if err := service.LoadState(); err != nil {
+		logPrintf("WARNING: Could not load the state file %s\n%s", service.StateFile, err.Error())
+	}
+	if err := service.LoadStateSource(); err != nil {
+		logPrintf("WARNING: Could not load the known services from %s\n%s", service.StateSourceUrl, err.Error())
+	}
+	args := GetArgs()
 	serve := NewServe(service)
+	serve.EnablePprof = args.EnablePprof
 	go serve.Run()
 
-	args := GetArgs()
+	if args.LeaderElection {
+		instanceId, _ := os.Hostname()
+		lock := &ServiceLabelLock{Host: service.Host, ServiceName: args.LeaderLockService, LabelPrefix: service.LabelPrefix}
+		election := NewLeaderElection(lock, instanceId, time.Second*time.Duration(args.LeaderLease))
+		election.Registry = lock
+		isLeader = election.IsLeader
+	}
+	if args.RunOnce {
+		os.Exit(runOnce(service, args))
+	}
 	logPrintf("Starting iterations")
 	for {
-		if len(service.NotifCreateServiceUrl) > 0 {
-			allServices, _ := service.GetServices()
-			newServices, _ := service.GetNewServices(allServices)
-			service.NotifyServicesCreate(newServices, args.Retry, args.RetryInterval)
-			removedServices := service.GetRemovedServices(allServices)
-			service.NotifyServicesRemove(removedServices, args.Retry, args.RetryInterval)
+		if len(service.NotifCreateServiceUrl) > 0 || len(service.Targets) > 0 || service.NotifyMethod == "stdout" {
+			notifyServices(service, args)
+			if err := service.SaveState(); err != nil {
+				logPrintf("WARNING: Could not save the state file %s\n%s", service.StateFile, err.Error())
+			}
 		}
-		time.Sleep(time.Second * time.Duration(args.Interval))
+		time.Sleep(args.GetEffectiveInterval())
+	}
+}
+
+func runOnce(service Servicer, args *Args) int {
+	logPrintf("Running a single iteration")
+	if err := notifyServices(service, args); err != nil {
+		logPrintf("ERROR: %s", err.Error())
+		return 1
+	}
+	return 0
+}
+
+func notifyServices(service Servicer, args *Args) error {
+	start := time.Now()
+	allServices, err := service.GetServices()
+	reportDaemonStatus(args, err)
+	if err != nil {
+		return err
+	}
+	newServices, _ := service.GetNewServices(allServices)
+	updatedServices := service.GetUpdatedServices(allServices)
+	removedServices := service.GetRemovedServices(allServices)
+	newServices, updatedServices, removedServices = service.CorrelateReplacements(newServices, updatedServices, removedServices)
+	newServices, updatedServices = service.SettleChanges(allServices, newServices, updatedServices)
+	eventStream.PublishServices("create", newServices)
+	eventStream.PublishServices("update", updatedServices)
+	if !isLeader() {
+		service.ForgetRemovedServices(removedServices)
+		return nil
+	}
+	cycleErr := service.StartCycle(args.Retry, args.RetryInterval)
+	newServices, updatedServices, removedServices = notificationQueue.Take(newServices, updatedServices, removedServices, args.MaxNotifications)
+	notifyStart := time.Now()
+	var createErr, updateErr, removeErr error
+	create := func() {
+		createErr = service.NotifyServicesCreate(newServices, args.Retry, args.RetryInterval)
+		updateErr = service.NotifyServicesUpdate(updatedServices, args.Retry, args.RetryInterval)
+	}
+	remove := func() {
+		removeErr = service.NotifyServicesRemove(removedServices, args.Retry, args.RetryInterval)
+	}
+	switch args.NotifyOrder {
+	case "remove_first":
+		remove()
+		create()
+	case "parallel":
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			create()
+		}()
+		go func() {
+			defer wg.Done()
+			remove()
+		}()
+		wg.Wait()
+	default:
+		create()
+		remove()
+	}
+	service.EndCycle()
+	stuckErr := service.NotifyServicesStuck(allServices, args.Retry, args.RetryInterval)
+	createFailureErr := service.NotifyServicesCreateFailure(allServices, args.Retry, args.RetryInterval)
+	healthErr := service.NotifyServicesHealth(allServices, args.Retry, args.RetryInterval)
+	summary := CycleSummary{
+		Created:                len(newServices),
+		Updated:                len(updatedServices),
+		Removed:                len(removedServices),
+		NotificationDurationMs: int64(time.Since(notifyStart) / time.Millisecond),
+	}
+	for _, err := range []error{cycleErr, createErr, updateErr, removeErr, stuckErr, createFailureErr, healthErr} {
+		if err != nil {
+			summary.Failures++
+		}
+	}
+	summary.DurationMs = int64(time.Since(start) / time.Millisecond)
+	sendCycleSummary(args, summary)
+	sendIdleNotification(args, summary)
+	if summary.Failures > 0 {
+		return fmt.Errorf("At least one notification failed. Please consult logs for more details.")
 	}
+	return nil
 }
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type MainTestSuite struct {
+	suite.Suite
+}
+
+func TestMainUnitTestSuite(t *testing.T) {
+	s := new(MainTestSuite)
+	suite.Run(t, s)
+}
+
+// notifyServices
+
+func (s *MainTestSuite) Test_NotifyServices_NotifiesCreatesBeforeRemoves_WhenNotifyOrderIsCreateFirst() {
+	mockObj := getServicerMock("")
+
+	notifyServices(mockObj, &Args{Retry: 1, NotifyOrder: "create_first"})
+
+	s.Equal(
+		[]string{"NotifyServicesCreate", "NotifyServicesUpdate", "NotifyServicesRemove"},
+		s.getNotifyCalls(mockObj),
+	)
+}
+
+func (s *MainTestSuite) Test_NotifyServices_NotifiesRemovesBeforeCreates_WhenNotifyOrderIsRemoveFirst() {
+	mockObj := getServicerMock("")
+
+	notifyServices(mockObj, &Args{Retry: 1, NotifyOrder: "remove_first"})
+
+	s.Equal(
+		[]string{"NotifyServicesRemove", "NotifyServicesCreate", "NotifyServicesUpdate"},
+		s.getNotifyCalls(mockObj),
+	)
+}
+
+func (s *MainTestSuite) Test_NotifyServices_NotifiesCreatesAndRemovesConcurrently_WhenNotifyOrderIsParallel() {
+	createStarted := make(chan bool)
+	overlapped := false
+	mockObj := new(ServicerMock)
+	mockObj.On("GetServices").Return([]swarm.Service{}, nil)
+	mockObj.On("GetNewServices", mock.Anything).Return([]swarm.Service{}, nil)
+	mockObj.On("GetUpdatedServices", mock.Anything).Return([]swarm.Service{})
+	mockObj.On("GetRemovedServices", mock.Anything).Return([]string{})
+	mockObj.On("NotifyServicesUpdate", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockObj.On("NotifyServicesCreate", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { close(createStarted) }).
+		Return(nil)
+	mockObj.On("NotifyServicesRemove", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			// The create phase can only start before the remove phase ends when both run concurrently
+			select {
+			case <-createStarted:
+				overlapped = true
+			case <-time.After(time.Second):
+			}
+		}).
+		Return(nil)
+	mockObj.On("CorrelateReplacements", mock.Anything, mock.Anything, mock.Anything).Return()
+	mockObj.On("SettleChanges", mock.Anything, mock.Anything, mock.Anything).Return()
+	mockObj.On("NotifyServicesStuck", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockObj.On("NotifyServicesHealth", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockObj.On("StartCycle", mock.Anything, mock.Anything).Return(nil)
+	mockObj.On("EndCycle").Return()
+	mockObj.On("NotifyServicesCreateFailure", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	notifyServices(mockObj, &Args{Retry: 1, NotifyOrder: "parallel"})
+
+	s.True(overlapped)
+}
+
+func (s *MainTestSuite) Test_NotifyServices_DoesNotRaceOnServiceState_WhenNotifyOrderIsParallel() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer httpSrv.Close()
+	// Removals are not sent anywhere so that their bookkeeping overlaps with the create requests
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	servicer := &staticServicer{Service: service}
+	for i := 0; i < 20; i++ {
+		// Each iteration creates, updates, and removes services at the same time
+		servicer.services = []swarm.Service{
+			s.getLabeledService(fmt.Sprintf("created-%d", i), i),
+			s.getLabeledService("updated", i),
+		}
+
+		s.NotPanics(func() {
+			notifyServices(servicer, &Args{Retry: 1, NotifyOrder: "parallel"})
+		})
+	}
+
+	s.Contains(service.Services, "updated")
+	s.Contains(service.Services, "created-19")
+	s.NotContains(service.Services, "created-18")
+}
+
+func (s *MainTestSuite) Test_NotifyServices_ReturnsError_WhenNotificationFails() {
+	mockObj := getServicerMock("NotifyServicesRemove")
+	mockObj.On("NotifyServicesRemove", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("This is an error"))
+
+	err := notifyServices(mockObj, &Args{Retry: 1})
+
+	s.Error(err)
+}
+
+func (s *MainTestSuite) Test_NotifyServices_StartsCycleBeforeNotifying() {
+	mockObj := getServicerMock("")
+
+	notifyServices(mockObj, &Args{Retry: 3, RetryInterval: 5})
+
+	mockObj.AssertCalled(s.T(), "StartCycle", 3, 5)
+}
+
+func (s *MainTestSuite) Test_NotifyServices_EndsCycleBeforeStuckHealthAndCreateFailureNotifications() {
+	mockObj := getServicerMock("")
+
+	notifyServices(mockObj, &Args{Retry: 1})
+
+	calls := []string{}
+	for _, c := range mockObj.Calls {
+		switch c.Method {
+		case "NotifyServicesRemove", "EndCycle", "NotifyServicesStuck", "NotifyServicesCreateFailure", "NotifyServicesHealth":
+			calls = append(calls, c.Method)
+		}
+	}
+	s.Equal(
+		[]string{"NotifyServicesRemove", "EndCycle", "NotifyServicesStuck", "NotifyServicesCreateFailure", "NotifyServicesHealth"},
+		calls,
+	)
+}
+
+func (s *MainTestSuite) Test_NotifyServices_ReturnsError_WhenDeferredNotificationsFail() {
+	mockObj := getServicerMock("StartCycle")
+	mockObj.On("StartCycle", mock.Anything, mock.Anything).Return(fmt.Errorf("This is an error"))
+
+	err := notifyServices(mockObj, &Args{Retry: 1})
+
+	s.Error(err)
+}
+
+func (s *MainTestSuite) Test_NotifyServices_SendsCycleSummary_WhenCycleHasChanges() {
+	actual := CycleSummary{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&actual)
+	}))
+	defer srv.Close()
+	mockObj := new(ServicerMock)
+	mockObj.On("GetServices").Return([]swarm.Service{}, nil)
+	mockObj.On("GetNewServices", mock.Anything).Return([]swarm.Service{s.getService("s1"), s.getService("s2")}, nil)
+	mockObj.On("GetUpdatedServices", mock.Anything).Return([]swarm.Service{s.getService("s3")})
+	mockObj.On("GetRemovedServices", mock.Anything).Return([]string{"s4"})
+	mockObj.On("CorrelateReplacements", mock.Anything, mock.Anything, mock.Anything).Return()
+	mockObj.On("SettleChanges", mock.Anything, mock.Anything, mock.Anything).Return()
+	mockObj.On("StartCycle", mock.Anything, mock.Anything).Return(nil)
+	mockObj.On("EndCycle").Return()
+	mockObj.On("NotifyServicesCreate", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockObj.On("NotifyServicesUpdate", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockObj.On("NotifyServicesRemove", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("This is an error"))
+	mockObj.On("NotifyServicesStuck", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockObj.On("NotifyServicesHealth", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockObj.On("NotifyServicesCreateFailure", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	notifyServices(mockObj, &Args{Retry: 1, CycleWebhookUrl: srv.URL})
+
+	s.Equal(2, actual.Created)
+	s.Equal(1, actual.Updated)
+	s.Equal(1, actual.Removed)
+	s.Equal(1, actual.Failures)
+}
+
+func (s *MainTestSuite) Test_NotifyServices_DoesNotSendCycleSummary_WhenCycleHasNoChanges() {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+	mockObj := getServicerMock("")
+
+	notifyServices(mockObj, &Args{Retry: 1, CycleWebhookUrl: srv.URL})
+
+	s.False(called)
+}
+
+func (s *MainTestSuite) Test_NotifyServices_SendsCycleSummary_WhenCycleHasNoChangesAndCycleWebhookAllIsSet() {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+	mockObj := getServicerMock("")
+
+	notifyServices(mockObj, &Args{Retry: 1, CycleWebhookUrl: srv.URL, CycleWebhookAll: true})
+
+	s.True(called)
+}
+
+func (s *MainTestSuite) Test_NotifyServices_DoesNotNotify_WhenInstanceIsNotTheLeader() {
+	isLeaderOrig := isLeader
+	defer func() { isLeader = isLeaderOrig }()
+	isLeader = func() bool { return false }
+	mockObj := getServicerMock("")
+
+	notifyServices(mockObj, &Args{Retry: 1})
+
+	mockObj.AssertCalled(s.T(), "GetNewServices", mock.Anything)
+	s.Equal([]string{}, s.getNotifyCalls(mockObj))
+}
+
+func (s *MainTestSuite) Test_NotifyServices_ForgetsRemovedServices_WhenInstanceIsNotTheLeader() {
+	isLeaderOrig := isLeader
+	defer func() { isLeader = isLeaderOrig }()
+	isLeader = func() bool { return false }
+	mockObj := getServicerMock("GetRemovedServices")
+	mockObj.On("GetRemovedServices", mock.Anything).Return([]string{"go-demo"})
+
+	notifyServices(mockObj, &Args{Retry: 1})
+
+	mockObj.AssertCalled(s.T(), "ForgetRemovedServices", []string{"go-demo"})
+}
+
+// runOnce
+
+func (s *MainTestSuite) Test_RunOnce_RunsSingleIteration() {
+	mockObj := getServicerMock("")
+
+	actual := runOnce(mockObj, &Args{Retry: 1})
+
+	s.Equal(0, actual)
+	mockObj.AssertNumberOfCalls(s.T(), "GetServices", 1)
+	mockObj.AssertNumberOfCalls(s.T(), "NotifyServicesCreate", 1)
+}
+
+func (s *MainTestSuite) Test_RunOnce_ReturnsNonZeroCode_WhenNotificationFails() {
+	mockObj := getServicerMock("NotifyServicesCreate")
+	mockObj.On("NotifyServicesCreate", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("This is an error"))
+
+	actual := runOnce(mockObj, &Args{Retry: 1})
+
+	s.Equal(1, actual)
+}
+
+func (s *MainTestSuite) Test_RunOnce_ReturnsNonZeroCode_WhenServicesCannotBeRetrieved() {
+	mockObj := getServicerMock("GetServices")
+	mockObj.On("GetServices").Return([]swarm.Service{}, fmt.Errorf("This is an error"))
+
+	actual := runOnce(mockObj, &Args{Retry: 1})
+
+	s.Equal(1, actual)
+	mockObj.AssertNotCalled(s.T(), "NotifyServicesCreate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Util
+
+type staticServicer struct {
+	*Service
+	services []swarm.Service
+}
+
+func (m *staticServicer) GetServices() ([]swarm.Service, error) {
+	return m.services, nil
+}
+
+func (s *MainTestSuite) getLabeledService(name string, version int) swarm.Service {
+	service := s.getService(name)
+	service.Spec.Labels = map[string]string{"com.df.notify": "true", "com.df.version": fmt.Sprintf("%d", version)}
+	service.Spec.TaskTemplate.ForceUpdate = uint64(version)
+	service.Meta.CreatedAt = time.Now()
+	service.Version.Index = uint64(version + 1)
+	return service
+}
+
+func (s *MainTestSuite) getService(name string) swarm.Service {
+	service := swarm.Service{}
+	service.Spec.Name = name
+	service.ID = name
+	return service
+}
+
+func (s *MainTestSuite) getNotifyCalls(mockObj *ServicerMock) []string {
+	calls := []string{}
+	for _, c := range mockObj.Calls {
+		switch c.Method {
+		case "NotifyServicesCreate", "NotifyServicesUpdate", "NotifyServicesRemove":
+			calls = append(calls, c.Method)
+		}
+	}
+	return calls
+}
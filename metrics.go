@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"strings"
+	"sync"
+)
+
+var metrics = NewMetrics()
+
+type Metrics struct {
+	mu                   sync.RWMutex
+	labelKeys            int
+	malformedLabelValues int
+}
+
+func (m *Metrics) ObserveServices(services []swarm.Service, prefix string) {
+	keys := map[string]bool{}
+	for _, s := range services {
+		for k := range getServiceLabels(s) {
+			if strings.HasPrefix(k, prefix) {
+				keys[k] = true
+			}
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.labelKeys = len(keys)
+}
+
+func (m *Metrics) ObserveNewService(s swarm.Service, prefix string) {
+	malformed := 0
+	for k, v := range getServiceLabels(s) {
+		if strings.HasPrefix(k, prefix) && isMalformedLabelValue(v) {
+			malformed++
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.malformedLabelValues += malformed
+}
+
+func (m *Metrics) GetLabelKeys() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.labelKeys
+}
+
+func (m *Metrics) GetMalformedLabelValues() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.malformedLabelValues
+}
+
+func (m *Metrics) Render() string {
+	var buf bytes.Buffer
+	buf.WriteString("# HELP docker_flow_swarm_listener_label_keys Number of distinct com.df.* label keys across services\n")
+	buf.WriteString("# TYPE docker_flow_swarm_listener_label_keys gauge\n")
+	buf.WriteString(fmt.Sprintf("docker_flow_swarm_listener_label_keys %d\n", m.GetLabelKeys()))
+	buf.WriteString("# HELP docker_flow_swarm_listener_malformed_label_values_total Number of malformed com.df.* label values\n")
+	buf.WriteString("# TYPE docker_flow_swarm_listener_malformed_label_values_total counter\n")
+	buf.WriteString(fmt.Sprintf("docker_flow_swarm_listener_malformed_label_values_total %d\n", m.GetMalformedLabelValues()))
+	return buf.String()
+}
+
+func isMalformedLabelValue(value string) bool {
+	return len(strings.TrimSpace(value)) == 0 || strings.ContainsAny(value, " \t\n\r&#")
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+}
+
+func TestMetricsUnitTestSuite(t *testing.T) {
+	s := new(MetricsTestSuite)
+	suite.Run(t, s)
+}
+
+// ObserveServices
+
+func (s *MetricsTestSuite) Test_ObserveServices_SetsLabelKeysToDistinctDfLabels() {
+	m := NewMetrics()
+	services := []swarm.Service{
+		s.getService(map[string]string{"com.df.notify": "true", "com.df.servicePath": "/demo", "other": "x"}),
+		s.getService(map[string]string{"com.df.notify": "true", "com.df.port": "8080"}),
+	}
+
+	m.ObserveServices(services, "com.df.")
+
+	s.Equal(3, m.GetLabelKeys())
+}
+
+// ObserveNewService
+
+func (s *MetricsTestSuite) Test_ObserveNewService_CountsMalformedLabelValues() {
+	m := NewMetrics()
+
+	m.ObserveNewService(s.getService(map[string]string{
+		"com.df.notify":      "true",
+		"com.df.servicePath": "/demo&port=1",
+		"com.df.port":        "",
+		"other":              "",
+	}), "com.df.")
+
+	s.Equal(2, m.GetMalformedLabelValues())
+}
+
+// Render
+
+func (s *MetricsTestSuite) Test_Render_ReturnsPrometheusTextFormat() {
+	m := NewMetrics()
+	m.ObserveServices([]swarm.Service{s.getService(map[string]string{"com.df.notify": "true"})}, "com.df.")
+
+	actual := m.Render()
+
+	s.Contains(actual, "# TYPE docker_flow_swarm_listener_label_keys gauge\n")
+	s.Contains(actual, "docker_flow_swarm_listener_label_keys 1\n")
+	s.Contains(actual, "docker_flow_swarm_listener_malformed_label_values_total 0\n")
+}
+
+// Util
+
+func (s *MetricsTestSuite) getService(labels map[string]string) swarm.Service {
+	return swarm.Service{
+		Spec: swarm.ServiceSpec{
+			Annotations: swarm.Annotations{Name: "go-demo", Labels: labels},
+		},
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+)
+
+func isModeChange(previous, s swarm.Service) bool {
+	// Services restored from the state file have no mode and must not look changed
+	previousMode := getServiceMode(previous)
+	mode := getServiceMode(s)
+	return len(previousMode) > 0 && len(mode) > 0 && previousMode != mode
+}
+
+func (m *Service) isRecreatedWithNewMode(s swarm.Service) bool {
+	cached, ok := m.ServicesCache[s.Spec.Name]
+	return ok && m.Services[s.Spec.Name] && isModeChange(cached, s)
+}
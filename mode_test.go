@@ -0,0 +1,103 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type ModeTestSuite struct {
+	suite.Suite
+}
+
+func TestModeUnitTestSuite(t *testing.T) {
+	s := new(ModeTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+func (s *ModeTestSuite) SetupTest() {
+	serviceLastCreatedAt = time.Time{}
+}
+
+// NotifyServicesUpdate
+
+func (s *ModeTestSuite) Test_NotifyServicesUpdate_SendsSingleUpdate_WhenModeChanges() {
+	actual := []string{}
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual = append(actual, r.URL.Path+"?"+r.URL.RawQuery)
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL+"/create", httpSrv.URL+"/remove")
+	service.NotifUpdateServiceUrl = httpSrv.URL + "/update"
+	replicated := s.getReplicatedService(time.Now().Add(-time.Minute))
+	newServices, _ := service.GetNewServices([]swarm.Service{replicated})
+	service.NotifyServicesCreate(newServices, 1, 0)
+	actual = []string{}
+	global := s.getGlobalService(time.Now())
+
+	newServices, _ = service.GetNewServices([]swarm.Service{global})
+	updatedServices := service.GetUpdatedServices([]swarm.Service{global})
+	removedServices := service.GetRemovedServices([]swarm.Service{global})
+	service.NotifyServicesCreate(newServices, 1, 0)
+	service.NotifyServicesUpdate(updatedServices, 1, 0)
+	service.NotifyServicesRemove(removedServices, 1, 0)
+
+	s.Empty(newServices)
+	s.Empty(removedServices)
+	s.Equal([]string{"/update?serviceName=go-demo&changedFields=mode&mode=global"}, actual)
+	s.Equal("global", getServiceMode(service.ServicesCache["go-demo"]))
+}
+
+func (s *ModeTestSuite) Test_GetNewServices_DoesNotReturnRecreatedService_InTheNextIteration() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.GetNewServices([]swarm.Service{s.getReplicatedService(time.Now().Add(-time.Minute))})
+	global := s.getGlobalService(time.Now())
+	service.GetNewServices([]swarm.Service{global})
+	service.GetUpdatedServices([]swarm.Service{global})
+
+	actual, _ := service.GetNewServices([]swarm.Service{global})
+
+	s.Empty(actual)
+}
+
+// isModeChange
+
+func (s *ModeTestSuite) Test_IsModeChange_ReturnsFalse_WhenPreviousModeIsUnknown() {
+	restored := swarm.Service{}
+	restored.Spec.Name = "go-demo"
+
+	s.False(isModeChange(restored, s.getGlobalService(time.Now())))
+	s.True(isModeChange(s.getReplicatedService(time.Now()), s.getGlobalService(time.Now())))
+	s.False(isModeChange(s.getGlobalService(time.Now()), s.getGlobalService(time.Now())))
+}
+
+// Util
+
+func (s *ModeTestSuite) getReplicatedService(createdAt time.Time) swarm.Service {
+	srv := swarm.Service{ID: "id-1"}
+	srv.Spec.Name = "go-demo"
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	replicas := uint64(2)
+	srv.Spec.Mode.Replicated = &swarm.ReplicatedService{Replicas: &replicas}
+	srv.Meta.CreatedAt = createdAt
+	srv.Version.Index = 1
+	return srv
+}
+
+func (s *ModeTestSuite) getGlobalService(createdAt time.Time) swarm.Service {
+	srv := swarm.Service{ID: "id-2"}
+	srv.Spec.Name = "go-demo"
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	srv.Spec.Mode.Global = &swarm.GlobalService{}
+	srv.Meta.CreatedAt = createdAt
+	srv.Version.Index = 1
+	return srv
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"compress/gzip"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"golang.org/x/net/http2"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var retrySleep = time.Sleep
+
+func (m *Service) sendNotification(serviceName, event, fullUrl string, retries, interval int) error {
+	return m.sendTransformed(serviceName, event, TransformResponse{Url: fullUrl}, retries, interval)
+}
+
+func (m *Service) sendTransformed(serviceName, event string, payload TransformResponse, retries, interval int) error {
+	client := m.getHttpClient()
+	for i := 1; i <= retries; i++ {
+		if m.isCycleTimedOut() {
+			return errCycleTimeout
+		}
+		resp, err := m.doNotification(client, serviceName, event, payload)
+		if err != nil && m.isCycleTimedOut() {
+			return errCycleTimeout
+		}
+		directive := Directive{}
+		if err == nil {
+			directive = m.readDirective(serviceName, resp)
+		}
+		if err == nil && resp.StatusCode == http.StatusOK {
+			resp.Body.Close()
+			if receiptId := resp.Header.Get("X-Receipt-Id"); len(receiptId) > 0 || len(directive.ConfirmedRoutes) > 0 {
+				m.Receipts.Record(serviceName, event, receiptId, directive.ConfirmedRoutes)
+			}
+			return nil
+		} else if err == nil && directive.isNoRetry() {
+			resp.Body.Close()
+			logPrintf("The receiver accepted the %s notification of the service %s with status code %d and asked not to retry it", event, serviceName, resp.StatusCode)
+			return nil
+		} else if i < retries {
+			delay := time.Second * time.Duration(m.getRetryInterval(err, interval))
+			if err == nil {
+				resp.Body.Close()
+				if retryAfter, ok := getRetryAfter(resp); ok {
+					delay = retryAfter
+				}
+			}
+			if !m.takeRetryBudget(delay) {
+				logPrintf("WARNING: The retry budget of the iteration is spent. The %s notification of the service %s is deferred to the next iteration", event, serviceName)
+				return errRetryBudgetSpent
+			}
+			if delay > 0 {
+				retrySleep(delay)
+			}
+		} else {
+			if err != nil {
+				logPrintf("ERROR: %s", m.redactInFlight(serviceName, err.Error()))
+				return err
+			}
+			body := readResponseBody(resp)
+			resp.Body.Close()
+			msg := fmt.Errorf("Request %s returned status code %d\n%s", m.redactInFlight(serviceName, payload.Url), resp.StatusCode, string(body[:]))
+			logPrintf("ERROR: %s", msg)
+			return msg
+		}
+	}
+	return nil
+}
+
+func readResponseBody(resp *http.Response) []byte {
+	var reader io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			logPrintf("WARNING: Could not decompress the response body\n%s", err.Error())
+			return []byte{}
+		}
+		defer gz.Close()
+		reader = gz
+	}
+	body, _ := ioutil.ReadAll(reader)
+	return body
+}
+
+func (m *Service) getHttpClient() *http.Client {
+	return &http.Client{
+		Timeout:   m.NotifyTimeout,
+		Transport: m.getTransport(),
+	}
+}
+
+func (m *Service) getTransport() http.RoundTripper {
+	m.transportOnce.Do(func() {
+		if !m.NotifyHttp2 && !m.NotifyInsecure && m.TLSClientConfig == nil {
+			m.transport = http.DefaultTransport
+			return
+		}
+		tlsConfig := m.TLSClientConfig
+		if m.NotifyInsecure {
+			if tlsConfig == nil {
+				tlsConfig = &tls.Config{}
+			} else {
+				tlsConfig = tlsConfig.Clone()
+			}
+			tlsConfig.InsecureSkipVerify = true
+		}
+		transport := &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		}
+		if m.NotifyHttp2 {
+			// Receivers that do not negotiate HTTP/2 are still served over HTTP/1.1
+			if err := http2.ConfigureTransport(transport); err != nil {
+				logPrintf("WARNING: Could not enable HTTP/2 for notifications\n%s", err.Error())
+			}
+		}
+		m.transport = transport
+	})
+	return m.transport
+}
+
+func (m *Service) getRetryInterval(err error, interval int) int {
+	switch getErrorClass(err) {
+	case "refused":
+		if m.RetryIntervalRefused >= 0 {
+			return m.RetryIntervalRefused
+		}
+	case "timeout":
+		if m.RetryIntervalTimeout >= 0 {
+			return m.RetryIntervalTimeout
+		}
+	}
+	return interval
+}
+
+func getRetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if len(value) == 0 {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Second * time.Duration(seconds), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func getErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return "refused"
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "timeout"
+	}
+	return ""
+}
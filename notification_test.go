@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+type NotificationTestSuite struct {
+	suite.Suite
+	sleeps []time.Duration
+}
+
+func TestNotificationUnitTestSuite(t *testing.T) {
+	s := new(NotificationTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+func (s *NotificationTestSuite) SetupTest() {
+	s.sleeps = []time.Duration{}
+	retrySleep = func(d time.Duration) {
+		s.sleeps = append(s.sleeps, d)
+	}
+}
+
+func (s *NotificationTestSuite) TearDownTest() {
+	retrySleep = time.Sleep
+}
+
+// sendNotification
+
+func (s *NotificationTestSuite) Test_SendNotification_UsesRefusedRetryInterval_WhenConnectionIsRefused() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := httpSrv.URL
+	httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.RetryIntervalRefused = 1
+	service.RetryIntervalTimeout = 30
+
+	err := service.sendNotification("go-demo", "create", url, 3, 5)
+
+	s.Error(err)
+	s.Equal([]time.Duration{time.Second, time.Second}, s.sleeps)
+}
+
+func (s *NotificationTestSuite) Test_SendNotification_UsesTimeoutRetryInterval_WhenRequestTimesOut() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyTimeout = 10 * time.Millisecond
+	service.RetryIntervalRefused = 1
+	service.RetryIntervalTimeout = 30
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL, 2, 5)
+
+	s.Error(err)
+	s.Equal([]time.Duration{30 * time.Second}, s.sleeps)
+}
+
+func (s *NotificationTestSuite) Test_SendNotification_UsesRetryInterval_WhenStatusIsNotOK() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.RetryIntervalRefused = 1
+	service.RetryIntervalTimeout = 30
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL, 2, 5)
+
+	s.Error(err)
+	s.Equal([]time.Duration{5 * time.Second}, s.sleeps)
+}
+
+func (s *NotificationTestSuite) Test_SendNotification_UsesRetryInterval_WhenClassIntervalsAreNotSet() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := httpSrv.URL
+	httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	service.sendNotification("go-demo", "create", url, 2, 5)
+
+	s.Equal([]time.Duration{5 * time.Second}, s.sleeps)
+}
+
+func (s *NotificationTestSuite) Test_SendNotification_WaitsRetryAfterSeconds_WhenStatusIsTooManyRequests() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "42")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL, 2, 5)
+
+	s.Error(err)
+	s.Equal([]time.Duration{42 * time.Second}, s.sleeps)
+}
+
+func (s *NotificationTestSuite) Test_SendNotification_WaitsUntilRetryAfterDate_WhenStatusIsServiceUnavailable() {
+	retryAt := time.Now().Add(2 * time.Minute)
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", retryAt.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL, 2, 5)
+
+	s.Error(err)
+	s.Equal(1, len(s.sleeps))
+	s.InDelta(float64(2*time.Minute), float64(s.sleeps[0]), float64(2*time.Second))
+}
+
+func (s *NotificationTestSuite) Test_SendNotification_IgnoresRetryAfter_WhenStatusIsInternalServerError() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "42")
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL, 2, 5)
+
+	s.Error(err)
+	s.Equal([]time.Duration{5 * time.Second}, s.sleeps)
+}
+
+func (s *NotificationTestSuite) Test_SendNotification_ReturnsDecompressedBody_WhenErrorResponseIsGzipEncoded() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusInternalServerError)
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte("Proxy could not be reconfigured"))
+		gz.Close()
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL, 1, 0)
+
+	s.Error(err)
+	s.Contains(err.Error(), "Proxy could not be reconfigured")
+}
+
+// getHttpClient
+
+func (s *NotificationTestSuite) Test_GetHttpClient_NegotiatesHttp2_WhenNotifyHttp2IsTrue() {
+	actualProto := ""
+	httpSrv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualProto = r.Proto
+	}))
+	httpSrv.EnableHTTP2 = true
+	httpSrv.StartTLS()
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyHttp2 = true
+	service.TLSClientConfig = s.getTLSConfig(httpSrv)
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL, 1, 0)
+
+	s.NoError(err)
+	s.Equal("HTTP/2.0", actualProto)
+}
+
+func (s *NotificationTestSuite) Test_GetHttpClient_FallsBackToHttp1_WhenReceiverDoesNotSupportHttp2() {
+	actualProto := ""
+	httpSrv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualProto = r.Proto
+	}))
+	httpSrv.StartTLS()
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyHttp2 = true
+	service.TLSClientConfig = s.getTLSConfig(httpSrv)
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL, 1, 0)
+
+	s.NoError(err)
+	s.Equal("HTTP/1.1", actualProto)
+}
+
+func (s *NotificationTestSuite) Test_GetHttpClient_UsesDefaultTransport_WhenNotifyHttp2IsFalse() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	s.Equal(http.DefaultTransport, service.getHttpClient().Transport)
+}
+
+func (s *NotificationTestSuite) Test_GetHttpClient_SkipsTLSVerification_WhenNotifyInsecureIsTrue() {
+	httpSrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyInsecure = true
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL, 1, 0)
+
+	s.NoError(err)
+}
+
+func (s *NotificationTestSuite) Test_GetHttpClient_VerifiesTLS_WhenNotifyInsecureIsFalse() {
+	httpSrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	err := service.sendNotification("go-demo", "create", httpSrv.URL, 1, 0)
+
+	s.Error(err)
+}
+
+// NewServiceFromEnv
+
+func (s *NotificationTestSuite) Test_NewServiceFromEnv_SetsNotifyInsecureAndLogsWarning() {
+	insecureOrig := os.Getenv("DF_NOTIFY_INSECURE")
+	logPrintfOrig := logPrintf
+	defer func() {
+		os.Setenv("DF_NOTIFY_INSECURE", insecureOrig)
+		logPrintf = logPrintfOrig
+	}()
+	os.Setenv("DF_NOTIFY_INSECURE", "true")
+	logs := []string{}
+	logPrintf = func(format string, v ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, v...))
+	}
+
+	service := NewServiceFromEnv()
+
+	s.True(service.NotifyInsecure)
+	s.Contains(logs, "WARNING: DF_NOTIFY_INSECURE is enabled. TLS certificates of notification receivers will NOT be verified. Do not use it in production!")
+}
+
+// readResponseBody
+
+func (s *NotificationTestSuite) Test_ReadResponseBody_DecompressesGzipEncodedBody() {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("This is an error"))
+	gz.Close()
+	resp := &http.Response{
+		Header: http.Header{"Content-Encoding": []string{"gzip"}},
+		Body:   ioutil.NopCloser(&buf),
+	}
+
+	s.Equal("This is an error", string(readResponseBody(resp)))
+}
+
+func (s *NotificationTestSuite) Test_ReadResponseBody_ReturnsPlainBody() {
+	resp := &http.Response{
+		Header: http.Header{},
+		Body:   ioutil.NopCloser(bytes.NewBufferString("This is an error")),
+	}
+
+	s.Equal("This is an error", string(readResponseBody(resp)))
+}
+
+func (s *NotificationTestSuite) Test_ReadResponseBody_ReturnsEmptyBody_WhenGzipIsInvalid() {
+	resp := &http.Response{
+		Header: http.Header{"Content-Encoding": []string{"gzip"}},
+		Body:   ioutil.NopCloser(bytes.NewBufferString("not gzip")),
+	}
+
+	s.Empty(readResponseBody(resp))
+}
+
+// getRetryAfter
+
+func (s *NotificationTestSuite) Test_GetRetryAfter_ReturnsFalse_WhenHeaderIsInvalid() {
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"soon"}},
+	}
+
+	_, ok := getRetryAfter(resp)
+
+	s.False(ok)
+}
+
+func (s *NotificationTestSuite) Test_GetRetryAfter_ReturnsZero_WhenDateIsInThePast() {
+	resp := &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{"Retry-After": []string{"Wed, 21 Oct 2015 07:28:00 GMT"}},
+	}
+
+	actual, ok := getRetryAfter(resp)
+
+	s.True(ok)
+	s.Equal(time.Duration(0), actual)
+}
+
+// getErrorClass
+
+func (s *NotificationTestSuite) Test_GetErrorClass_ReturnsEmptyString_WhenErrorIsUnknown() {
+	s.Equal("", getErrorClass(nil))
+	s.Equal("", getErrorClass(fmt.Errorf("This is an error")))
+}
+
+// Util
+
+func (s *NotificationTestSuite) getTLSConfig(srv *httptest.Server) *tls.Config {
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	return &tls.Config{RootCAs: pool}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"sort"
+	"strings"
+)
+
+func (m *Service) getOversizedLabels(s swarm.Service) []string {
+	keys := []string{}
+	if m.MaxLabelValueLength <= 0 {
+		return keys
+	}
+	for k, v := range getServiceLabels(s) {
+		if strings.HasPrefix(k, m.LabelPrefix) && len(v) > m.MaxLabelValueLength {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *Service) truncateLabels(serviceName string, labels map[string]string) map[string]string {
+	if m.MaxLabelValueLength <= 0 {
+		return labels
+	}
+	for k, v := range labels {
+		if len(v) > m.MaxLabelValueLength {
+			logPrintf("WARNING: The value of the label %s of the service %s is longer than %d characters and was truncated", k, serviceName, m.MaxLabelValueLength)
+			labels[k] = v[:m.MaxLabelValueLength]
+		}
+	}
+	return labels
+}
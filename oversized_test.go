@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type OversizedTestSuite struct {
+	suite.Suite
+	logs []string
+}
+
+func TestOversizedUnitTestSuite(t *testing.T) {
+	s := new(OversizedTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {
+		s.logs = append(s.logs, fmt.Sprintf(format, v...))
+	}
+
+	suite.Run(t, s)
+}
+
+func (s *OversizedTestSuite) SetupTest() {
+	s.logs = []string{}
+	serviceLastCreatedAt = time.Time{}
+}
+
+// NotifyServicesCreate
+
+func (s *OversizedTestSuite) Test_NotifyServicesCreate_TruncatesOversizedLabelValues() {
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	service.MaxLabelValueLength = 5
+	service.OversizedLabelAction = "truncate"
+
+	service.NotifyServicesCreate([]swarm.Service{s.getService("go-demo", "/abcdefghij")}, 1, 0)
+
+	s.Equal("serviceName=go-demo&servicePath=/abcd", actualQuery)
+	s.Contains(strings.Join(s.logs, "\n"), "WARNING: The value of the label servicePath of the service go-demo is longer than 5 characters")
+}
+
+func (s *OversizedTestSuite) Test_NotifyServicesCreate_DoesNotTruncate_WhenMaxLabelValueLengthIsNotSet() {
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+
+	service.NotifyServicesCreate([]swarm.Service{s.getService("go-demo", "/abcdefghij")}, 1, 0)
+
+	s.Equal("serviceName=go-demo&servicePath=/abcdefghij", actualQuery)
+}
+
+// GetNewServices
+
+func (s *OversizedTestSuite) Test_GetNewServices_ReturnsServicesWithOversizedLabels_WhenActionIsTruncate() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.MaxLabelValueLength = 5
+	service.OversizedLabelAction = "truncate"
+
+	actual, _ := service.GetNewServices([]swarm.Service{s.getService("go-demo", "/abcdefghij")})
+
+	s.Equal(1, len(actual))
+}
+
+func (s *OversizedTestSuite) Test_GetNewServices_SkipsServicesWithOversizedLabels_WhenActionIsReject() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.MaxLabelValueLength = 5
+	service.OversizedLabelAction = "reject"
+	services := []swarm.Service{
+		s.getService("go-demo", "/abcdefghij"),
+		s.getService("other", "/abc"),
+	}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(1, len(actual))
+	s.Equal("other", actual[0].Spec.Name)
+	s.NotContains(service.Services, "go-demo")
+}
+
+// NewServiceFromEnv
+
+func (s *OversizedTestSuite) Test_NewServiceFromEnv_SetsOversizedLabelSettings() {
+	lengthOrig := os.Getenv("DF_MAX_LABEL_VALUE_LENGTH")
+	actionOrig := os.Getenv("DF_OVERSIZED_LABEL_ACTION")
+	defer func() {
+		os.Setenv("DF_MAX_LABEL_VALUE_LENGTH", lengthOrig)
+		os.Setenv("DF_OVERSIZED_LABEL_ACTION", actionOrig)
+	}()
+	os.Setenv("DF_MAX_LABEL_VALUE_LENGTH", "1024")
+	os.Setenv("DF_OVERSIZED_LABEL_ACTION", "reject")
+
+	service := NewServiceFromEnv()
+
+	s.Equal(1024, service.MaxLabelValueLength)
+	s.Equal("reject", service.OversizedLabelAction)
+}
+
+func (s *OversizedTestSuite) Test_NewServiceFromEnv_SetsOversizedLabelActionToTruncate_WhenEnvIsNotPresent() {
+	actionOrig := os.Getenv("DF_OVERSIZED_LABEL_ACTION")
+	defer func() { os.Setenv("DF_OVERSIZED_LABEL_ACTION", actionOrig) }()
+	os.Unsetenv("DF_OVERSIZED_LABEL_ACTION")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("truncate", service.OversizedLabelAction)
+}
+
+// Util
+
+func (s *OversizedTestSuite) getService(name, servicePath string) swarm.Service {
+	srv := swarm.Service{}
+	srv.Spec.Name = name
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true", "com.df.servicePath": servicePath}
+	return srv
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"strconv"
+	"strings"
+)
+
+func (m *Service) getUnexposedPorts(s swarm.Service) []string {
+	unexposed := []string{}
+	if len(m.ValidatePorts) == 0 {
+		return unexposed
+	}
+	value, ok := getServiceLabels(s)[m.LabelPrefix+"port"]
+	if !ok || len(value) == 0 {
+		return unexposed
+	}
+	exposed := map[string]bool{}
+	ports := append([]swarm.PortConfig{}, s.Endpoint.Ports...)
+	if s.Spec.EndpointSpec != nil {
+		ports = append(ports, s.Spec.EndpointSpec.Ports...)
+	}
+	for _, p := range ports {
+		exposed[strconv.Itoa(int(p.TargetPort))] = true
+		if p.PublishedPort > 0 {
+			exposed[strconv.Itoa(int(p.PublishedPort))] = true
+		}
+	}
+	for _, port := range strings.Split(value, ",") {
+		port = strings.TrimSpace(port)
+		if len(port) > 0 && !exposed[port] {
+			unexposed = append(unexposed, port)
+		}
+	}
+	return unexposed
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+	"time"
+)
+
+type PortsTestSuite struct {
+	suite.Suite
+	logs []string
+}
+
+func TestPortsUnitTestSuite(t *testing.T) {
+	s := new(PortsTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {
+		s.logs = append(s.logs, fmt.Sprintf(format, v...))
+	}
+
+	suite.Run(t, s)
+}
+
+func (s *PortsTestSuite) SetupTest() {
+	s.logs = []string{}
+	serviceLastCreatedAt = time.Time{}
+}
+
+// getUnexposedPorts
+
+func (s *PortsTestSuite) Test_GetUnexposedPorts_ReturnsEmptySlice_WhenPortIsPublished() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.ValidatePorts = "warn"
+
+	actual := service.getUnexposedPorts(s.getService("go-demo", "8080", 8080))
+
+	s.Empty(actual)
+}
+
+func (s *PortsTestSuite) Test_GetUnexposedPorts_ReturnsPorts_WhenPortIsNotPublished() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.ValidatePorts = "warn"
+
+	actual := service.getUnexposedPorts(s.getService("go-demo", "8080,9090", 8080))
+
+	s.Equal([]string{"9090"}, actual)
+}
+
+func (s *PortsTestSuite) Test_GetUnexposedPorts_ReturnsEmptySlice_WhenValidationIsDisabled() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	actual := service.getUnexposedPorts(s.getService("go-demo", "9090", 8080))
+
+	s.Empty(actual)
+}
+
+// GetNewServices
+
+func (s *PortsTestSuite) Test_GetNewServices_LogsWarning_WhenPortIsNotExposed() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.ValidatePorts = "warn"
+	services := []swarm.Service{
+		s.getService("go-demo", "8080", 8080),
+		s.getService("other", "9090", 8080),
+	}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(2, len(actual))
+	s.Equal([]string{"WARNING: The ports 9090 of the service other are not exposed"}, s.logs)
+}
+
+func (s *PortsTestSuite) Test_GetNewServices_SkipsServices_WhenPortIsNotExposedAndValidationIsSkip() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.ValidatePorts = "skip"
+	services := []swarm.Service{
+		s.getService("go-demo", "8080", 8080),
+		s.getService("other", "9090", 8080),
+	}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(1, len(actual))
+	s.Equal("go-demo", actual[0].Spec.Name)
+	s.NotContains(service.Services, "other")
+}
+
+// NewServiceFromEnv
+
+func (s *PortsTestSuite) Test_NewServiceFromEnv_SetsValidatePorts() {
+	validateOrig := os.Getenv("DF_VALIDATE_PORTS")
+	defer func() { os.Setenv("DF_VALIDATE_PORTS", validateOrig) }()
+	os.Setenv("DF_VALIDATE_PORTS", "skip")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("skip", service.ValidatePorts)
+}
+
+// Util
+
+func (s *PortsTestSuite) getService(name, port string, targetPort uint32) swarm.Service {
+	srv := swarm.Service{}
+	srv.Spec.Name = name
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true", "com.df.port": port}
+	srv.Endpoint.Ports = []swarm.PortConfig{{TargetPort: targetPort, PublishedPort: 30000}}
+	return srv
+}
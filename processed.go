@@ -0,0 +1,19 @@
+package main
+
+import (
+	"encoding/json"
+)
+
+func (m *Service) markProcessed(eventType string, serviceNames []string, errs map[string]error) {
+	for _, name := range serviceNames {
+		if _, failed := errs[name]; failed {
+			continue
+		}
+		m.NotificationCounts.Add(name, eventType)
+		event := Event{Type: "processed", ServiceName: name, Event: eventType}
+		if data, err := json.Marshal(event); err == nil {
+			logPrintf("PROCESSED: %s", string(data))
+		}
+		eventStream.Publish(event)
+	}
+}
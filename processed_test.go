@@ -0,0 +1,117 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type ProcessedTestSuite struct {
+	suite.Suite
+	mu   sync.Mutex
+	logs []string
+}
+
+func TestProcessedUnitTestSuite(t *testing.T) {
+	s := new(ProcessedTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {
+		if strings.HasPrefix(format, "PROCESSED: ") {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.logs = append(s.logs, strings.Replace(format, "%s", v[0].(string), 1))
+		}
+	}
+
+	suite.Run(t, s)
+}
+
+func (s *ProcessedTestSuite) SetupTest() {
+	s.logs = []string{}
+}
+
+// NotifyServicesCreate
+
+func (s *ProcessedTestSuite) Test_NotifyServicesCreate_EmitsProcessedMarkerPerService() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	events := eventStream.Subscribe()
+	defer eventStream.Unsubscribe(events)
+
+	err := service.NotifyServicesCreate(s.getServices("go-demo", "go-api"), 1, 0)
+
+	s.NoError(err)
+	s.ElementsMatch([]string{
+		`PROCESSED: {"type":"processed","serviceName":"go-demo","event":"create"}`,
+		`PROCESSED: {"type":"processed","serviceName":"go-api","event":"create"}`,
+	}, s.logs)
+	s.ElementsMatch([]Event{
+		{Type: "processed", ServiceName: "go-demo", Event: "create"},
+		{Type: "processed", ServiceName: "go-api", Event: "create"},
+	}, []Event{<-events, <-events})
+}
+
+func (s *ProcessedTestSuite) Test_NotifyServicesCreate_DoesNotEmitProcessedMarker_WhenNotificationFails() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("serviceName") == "go-api" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+
+	service.NotifyServicesCreate(s.getServices("go-demo", "go-api"), 1, 0)
+
+	s.Equal([]string{`PROCESSED: {"type":"processed","serviceName":"go-demo","event":"create"}`}, s.logs)
+}
+
+// NotifyServicesUpdate
+
+func (s *ProcessedTestSuite) Test_NotifyServicesUpdate_EmitsProcessedMarkerPerService() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+
+	err := service.NotifyServicesUpdate(s.getServices("go-demo"), 1, 0)
+
+	s.NoError(err)
+	s.Equal([]string{`PROCESSED: {"type":"processed","serviceName":"go-demo","event":"update"}`}, s.logs)
+}
+
+// NotifyServicesRemove
+
+func (s *ProcessedTestSuite) Test_NotifyServicesRemove_EmitsProcessedMarkerPerService() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL)
+	service.Services["go-demo"] = true
+	service.Services["go-api"] = true
+
+	err := service.NotifyServicesRemove([]string{"go-demo", "go-api"}, 1, 0)
+
+	s.NoError(err)
+	s.ElementsMatch([]string{
+		`PROCESSED: {"type":"processed","serviceName":"go-demo","event":"remove"}`,
+		`PROCESSED: {"type":"processed","serviceName":"go-api","event":"remove"}`,
+	}, s.logs)
+}
+
+// Util
+
+func (s *ProcessedTestSuite) getServices(names ...string) []swarm.Service {
+	services := []swarm.Service{}
+	for _, name := range names {
+		srv := swarm.Service{ID: name + "-id"}
+		srv.Spec.Name = name
+		srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+		services = append(services, srv)
+	}
+	return services
+}
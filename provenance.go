@@ -0,0 +1,19 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"strings"
+)
+
+func (m *Service) getProvenance(s swarm.Service) map[string]string {
+	provenance := map[string]string{}
+	for _, label := range m.ProvenanceLabels {
+		value, ok := getServiceLabels(s)[label]
+		if !ok || len(value) == 0 {
+			continue
+		}
+		// Provenance is sent under the last segment of the label (e.g. namespace for com.docker.stack.namespace)
+		provenance[label[strings.LastIndex(label, ".")+1:]] = value
+	}
+	return provenance
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+type ProvenanceTestSuite struct {
+	suite.Suite
+}
+
+func TestProvenanceUnitTestSuite(t *testing.T) {
+	s := new(ProvenanceTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// NotifyServicesCreate
+
+func (s *ProvenanceTestSuite) Test_NotifyServicesCreate_IncludesProvenance_WhenLabelsArePresent() {
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	service.ProvenanceLabels = []string{"com.docker.stack.namespace", "com.df.deployedBy", "com.example.pipeline"}
+	srv := swarm.Service{}
+	srv.Spec.Name = "go-demo"
+	srv.Spec.Labels = map[string]string{
+		"com.df.notify":              "true",
+		"com.df.deployedBy":          "jenkins",
+		"com.docker.stack.namespace": "prod",
+	}
+
+	err := service.NotifyServicesCreate([]swarm.Service{srv}, 1, 0)
+
+	s.NoError(err)
+	s.Equal("serviceName=go-demo&deployedBy=jenkins&namespace=prod", actualQuery)
+}
+
+// getProvenance
+
+func (s *ProvenanceTestSuite) Test_GetProvenance_ReturnsEmptyMap_WhenProvenanceLabelsAreNotSet() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	srv := swarm.Service{}
+	srv.Spec.Labels = map[string]string{"com.docker.stack.namespace": "prod"}
+
+	s.Empty(service.getProvenance(srv))
+}
+
+// NewServiceFromEnv
+
+func (s *ProvenanceTestSuite) Test_NewServiceFromEnv_SetsProvenanceLabels() {
+	labelsOrig := os.Getenv("DF_PROVENANCE_LABELS")
+	defer func() { os.Setenv("DF_PROVENANCE_LABELS", labelsOrig) }()
+	os.Setenv("DF_PROVENANCE_LABELS", "com.docker.stack.namespace,com.df.deployedBy")
+
+	service := NewServiceFromEnv()
+
+	s.Equal([]string{"com.docker.stack.namespace", "com.df.deployedBy"}, service.ProvenanceLabels)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"sync"
+)
+
+var notificationQueue = NewNotificationQueue()
+
+type queuedNotification struct {
+	event       string
+	serviceName string
+	service     swarm.Service
+}
+
+type NotificationQueue struct {
+	mu      sync.Mutex
+	pending []queuedNotification
+}
+
+func (q *NotificationQueue) Take(newServices, updatedServices []swarm.Service, removedServices []string, max int) ([]swarm.Service, []swarm.Service, []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if max <= 0 && len(q.pending) == 0 {
+		return newServices, updatedServices, removedServices
+	}
+	for _, s := range newServices {
+		q.add(queuedNotification{event: "create", serviceName: s.Spec.Name, service: s})
+	}
+	for _, s := range updatedServices {
+		q.add(queuedNotification{event: "update", serviceName: s.Spec.Name, service: s})
+	}
+	for _, name := range removedServices {
+		q.add(queuedNotification{event: "remove", serviceName: name})
+	}
+	count := len(q.pending)
+	if max > 0 && count > max {
+		count = max
+	}
+	created := []swarm.Service{}
+	updated := []swarm.Service{}
+	removed := []string{}
+	for _, n := range q.pending[:count] {
+		switch n.event {
+		case "create":
+			created = append(created, n.service)
+		case "update":
+			updated = append(updated, n.service)
+		case "remove":
+			removed = append(removed, n.serviceName)
+		}
+	}
+	q.pending = q.pending[count:]
+	if len(q.pending) > 0 {
+		logPrintf("Deferring %d notifications to the next iterations", len(q.pending))
+	}
+	return created, updated, removed
+}
+
+func (q *NotificationQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+func (q *NotificationQueue) add(n queuedNotification) {
+	for i, p := range q.pending {
+		if p.serviceName != n.serviceName {
+			continue
+		}
+		if p.event == "remove" {
+			// Deferred removals are detected again while the service is still tracked and must not pile up
+			if n.event == "remove" {
+				return
+			}
+			continue
+		}
+		switch n.event {
+		case "create", "update":
+			// A pending create or update is sent with the latest spec instead of queueing another notification
+			q.pending[i].service = n.service
+			return
+		case "remove":
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			q.pending = append(q.pending, n)
+			return
+		}
+	}
+	q.pending = append(q.pending, n)
+}
+
+func NewNotificationQueue() *NotificationQueue {
+	return &NotificationQueue{}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type QueueTestSuite struct {
+	suite.Suite
+}
+
+func TestQueueUnitTestSuite(t *testing.T) {
+	s := new(QueueTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// Take
+
+func (s *QueueTestSuite) Test_Take_ReturnsAllNotifications_WhenMaxIsNotSet() {
+	q := NewNotificationQueue()
+	created := s.getServices("a", "b", "c")
+
+	actualCreated, actualUpdated, actualRemoved := q.Take(created, []swarm.Service{}, []string{"d"}, 0)
+
+	s.Equal(created, actualCreated)
+	s.Empty(actualUpdated)
+	s.Equal([]string{"d"}, actualRemoved)
+	s.Equal(0, q.Len())
+}
+
+func (s *QueueTestSuite) Test_Take_SpreadsNotificationsAcrossCycles_WhenMaxIsExceeded() {
+	q := NewNotificationQueue()
+
+	created, updated, removed := q.Take(s.getServices("a", "b", "c"), s.getServices("d"), []string{"e"}, 2)
+	s.Equal(s.getServices("a", "b"), created)
+	s.Empty(updated)
+	s.Empty(removed)
+
+	created, updated, removed = q.Take(s.getServices("f"), []swarm.Service{}, []string{}, 2)
+	s.Equal(s.getServices("c"), created)
+	s.Equal(s.getServices("d"), updated)
+	s.Empty(removed)
+
+	created, updated, removed = q.Take([]swarm.Service{}, []swarm.Service{}, []string{}, 2)
+	s.Equal(s.getServices("f"), created)
+	s.Empty(updated)
+	s.Equal([]string{"e"}, removed)
+	s.Equal(0, q.Len())
+}
+
+func (s *QueueTestSuite) Test_Take_SendsLatestSpec_WhenDeferredServiceIsUpdated() {
+	q := NewNotificationQueue()
+	q.Take(s.getServices("a", "b"), []swarm.Service{}, []string{}, 1)
+	updated := s.getServices("b")
+	updated[0].Spec.Labels = map[string]string{"com.df.port": "8080"}
+
+	created, actualUpdated, _ := q.Take([]swarm.Service{}, updated, []string{}, 1)
+
+	s.Equal(updated, created)
+	s.Empty(actualUpdated)
+	s.Equal(0, q.Len())
+}
+
+func (s *QueueTestSuite) Test_Take_DropsDeferredNotifications_WhenServiceIsRemoved() {
+	q := NewNotificationQueue()
+	q.Take(s.getServices("a", "b"), []swarm.Service{}, []string{}, 1)
+
+	created, updated, removed := q.Take([]swarm.Service{}, []swarm.Service{}, []string{"b"}, 1)
+
+	s.Empty(created)
+	s.Empty(updated)
+	s.Equal([]string{"b"}, removed)
+	s.Equal(0, q.Len())
+}
+
+// add
+
+func (s *QueueTestSuite) Test_Add_QueuesRemovalOnce_WhenSameRemovalIsAddedTwice() {
+	q := NewNotificationQueue()
+
+	q.add(queuedNotification{event: "remove", serviceName: "x"})
+	q.add(queuedNotification{event: "remove", serviceName: "x"})
+
+	s.Equal(1, q.Len())
+	_, _, removed := q.Take([]swarm.Service{}, []swarm.Service{}, []string{}, 0)
+	s.Equal([]string{"x"}, removed)
+}
+
+func (s *QueueTestSuite) Test_Take_DoesNotGrow_WhenDeferredRemovalIsDetectedAgain() {
+	q := NewNotificationQueue()
+	q.Take(s.getServices("a", "b"), []swarm.Service{}, []string{"x"}, 1)
+
+	q.Take([]swarm.Service{}, []swarm.Service{}, []string{"x"}, 1)
+
+	s.Equal(1, q.Len())
+}
+
+// Util
+
+func (s *QueueTestSuite) getServices(names ...string) []swarm.Service {
+	services := []swarm.Service{}
+	for _, name := range names {
+		srv := swarm.Service{}
+		srv.Spec.Name = name
+		services = append(services, srv)
+	}
+	return services
+}
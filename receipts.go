@@ -0,0 +1,97 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type Receipt struct {
+	ServiceName     string    `json:"serviceName"`
+	Event           string    `json:"event"`
+	ReceiptId       string    `json:"receiptId"`
+	ConfirmedRoutes []string  `json:"confirmedRoutes,omitempty"`
+	ReceivedAt      time.Time `json:"receivedAt"`
+}
+
+type Receipts struct {
+	mu      sync.RWMutex
+	max     int
+	touches uint64
+	items   map[string]Receipt
+	touched map[string]uint64
+}
+
+func (m *Receipts) Add(serviceName, event, receiptId string) {
+	m.Record(serviceName, event, receiptId, nil)
+}
+
+func (m *Receipts) Record(serviceName, event, receiptId string, confirmedRoutes []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := serviceName + "/" + event
+	if _, ok := m.items[key]; !ok {
+		if m.max <= 0 {
+			return
+		}
+		if len(m.items) >= m.max {
+			m.evict()
+		}
+	}
+	m.touches++
+	m.touched[key] = m.touches
+	m.items[key] = Receipt{
+		ServiceName:     serviceName,
+		Event:           event,
+		ReceiptId:       receiptId,
+		ConfirmedRoutes: confirmedRoutes,
+		ReceivedAt:      time.Now(),
+	}
+}
+
+// The receipt recorded least recently makes room for the new one
+func (m *Receipts) evict() {
+	oldest := ""
+	for key, touched := range m.touched {
+		if len(oldest) == 0 || touched < m.touched[oldest] {
+			oldest = key
+		}
+	}
+	delete(m.items, oldest)
+	delete(m.touched, oldest)
+}
+
+func (m *Receipts) Forget(serviceName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, r := range m.items {
+		if r.ServiceName == serviceName {
+			delete(m.items, key)
+			delete(m.touched, key)
+		}
+	}
+}
+
+func (m *Receipts) GetAll() []Receipt {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	receipts := []Receipt{}
+	for _, r := range m.items {
+		receipts = append(receipts, r)
+	}
+	sort.Slice(receipts, func(i, j int) bool {
+		if receipts[i].ServiceName == receipts[j].ServiceName {
+			return receipts[i].Event < receipts[j].Event
+		}
+		return receipts[i].ServiceName < receipts[j].ServiceName
+	})
+	return receipts
+}
+
+func NewReceipts(max int) *Receipts {
+	return &Receipts{
+		max:     max,
+		items:   make(map[string]Receipt),
+		touched: make(map[string]uint64),
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+type ReceiptsTestSuite struct {
+	suite.Suite
+}
+
+func TestReceiptsUnitTestSuite(t *testing.T) {
+	s := new(ReceiptsTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// Add
+
+func (s *ReceiptsTestSuite) Test_Add_StoresReceiptPerServiceAndEvent() {
+	receipts := NewReceipts(10)
+
+	receipts.Add("go-demo", "create", "receipt-1")
+	receipts.Add("go-demo", "remove", "receipt-2")
+	receipts.Add("go-demo", "create", "receipt-3")
+
+	actual := receipts.GetAll()
+	s.Equal(2, len(actual))
+	s.Equal("create", actual[0].Event)
+	s.Equal("receipt-3", actual[0].ReceiptId)
+	s.Equal("remove", actual[1].Event)
+	s.Equal("receipt-2", actual[1].ReceiptId)
+}
+
+func (s *ReceiptsTestSuite) Test_Add_EvictsLeastRecentlyRecordedReceipt_WhenMaxIsReached() {
+	receipts := NewReceipts(2)
+
+	receipts.Add("s1", "create", "receipt-1")
+	receipts.Add("s2", "create", "receipt-2")
+	receipts.Add("s1", "create", "receipt-3")
+	receipts.Add("s3", "create", "receipt-4")
+
+	actual := receipts.GetAll()
+	s.Equal(2, len(actual))
+	s.Equal("receipt-3", actual[0].ReceiptId)
+	s.Equal("receipt-4", actual[1].ReceiptId)
+}
+
+func (s *ReceiptsTestSuite) Test_Add_DoesNotStoreReceipts_WhenMaxIsZero() {
+	receipts := NewReceipts(0)
+
+	receipts.Add("go-demo", "create", "receipt-1")
+
+	s.Empty(receipts.GetAll())
+}
+
+// Forget
+
+func (s *ReceiptsTestSuite) Test_Forget_RemovesAllReceiptsOfService() {
+	receipts := NewReceipts(10)
+	receipts.Add("go-demo", "create", "receipt-1")
+	receipts.Add("go-demo", "update", "receipt-2")
+	receipts.Add("other", "create", "receipt-3")
+
+	receipts.Forget("go-demo")
+
+	actual := receipts.GetAll()
+	s.Equal(1, len(actual))
+	s.Equal("other", actual[0].ServiceName)
+}
+
+// GetAll
+
+func (s *ReceiptsTestSuite) Test_GetAll_ReturnsEmptySlice_WhenThereAreNoReceipts() {
+	receipts := NewReceipts(10)
+
+	s.Equal([]Receipt{}, receipts.GetAll())
+}
+
+// NotifyServicesRemove
+
+func (s *ReceiptsTestSuite) Test_NotifyServicesRemove_ForgetsReceiptsOfRemovedService() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Receipt-Id", "receipt-1")
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL)
+	service.Services["go-demo"] = true
+	service.Receipts.Add("go-demo", "create", "receipt-0")
+
+	err := service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	s.NoError(err)
+	s.Empty(service.GetReceipts())
+}
+
+// NewServiceFromEnv
+
+func (s *ReceiptsTestSuite) Test_NewServiceFromEnv_SetsReceiptsMax() {
+	maxOrig := os.Getenv("DF_RECEIPTS_MAX")
+	defer func() { os.Setenv("DF_RECEIPTS_MAX", maxOrig) }()
+	os.Setenv("DF_RECEIPTS_MAX", "5")
+
+	service := NewServiceFromEnv()
+
+	s.Equal(5, service.Receipts.max)
+}
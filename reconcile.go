@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"sort"
+)
+
+type ReconcileResult struct {
+	Created []string `json:"created"`
+	Removed []string `json:"removed"`
+	Error   string   `json:"error,omitempty"`
+}
+
+func (m *Service) Reconcile(retries, interval int) (ReconcileResult, error) {
+	result := ReconcileResult{Created: []string{}, Removed: []string{}}
+	if len(m.ReconcileSourceUrl) == 0 {
+		return result, fmt.Errorf("DF_RECONCILE_SOURCE_URL is not set")
+	}
+	names, err := m.getKnownServices(m.ReconcileSourceUrl)
+	if err != nil {
+		logPrintf("ERROR: Could not fetch the services known to %s\n%s", m.ReconcileSourceUrl, err.Error())
+		return result, err
+	}
+	// The API runs on its own goroutine so the tracked services are only read once the poll loop is not changing them
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	known := map[string]bool{}
+	for _, name := range names {
+		known[name] = true
+	}
+	missing := []swarm.Service{}
+	for name := range m.Services {
+		if !known[name] {
+			missing = append(missing, m.ServicesCache[name])
+			result.Created = append(result.Created, name)
+		}
+	}
+	sort.Strings(result.Created)
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Spec.Name < missing[j].Spec.Name })
+	notifications := []notification{}
+	for _, name := range names {
+		if m.Services[name] {
+			continue
+		}
+		// The receiver knows about a service the listener does not track so there is no reason nor labels to send
+		removals, err := m.getRemoveNotificationsFor(name, "", swarm.Service{})
+		if err != nil {
+			return result, err
+		}
+		for _, n := range removals {
+			logPrintf("Sending service removed notification to %s", n.fullUrl)
+			notifications = append(notifications, n)
+		}
+		result.Removed = append(result.Removed, name)
+	}
+	sort.Strings(result.Removed)
+	logPrintf("Reconciling %d missing and %d unknown services with %s", len(result.Created), len(result.Removed), m.ReconcileSourceUrl)
+	createErr := m.notifyServicesCreate(missing, retries, interval)
+	removeErrs := m.sendUnlocked(notifications, retries, interval)
+	if createErr != nil || len(removeErrs) > 0 {
+		return result, fmt.Errorf("At least one request produced errors. Please consult logs for more details.")
+	}
+	return result, nil
+}
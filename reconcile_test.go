@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+type ReconcileTestSuite struct {
+	suite.Suite
+}
+
+func TestReconcileUnitTestSuite(t *testing.T) {
+	s := new(ReconcileTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// Reconcile
+
+func (s *ReconcileTestSuite) Test_Reconcile_ReturnsError_WhenSourceUrlIsNotSet() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	_, err := service.Reconcile(1, 0)
+
+	s.Error(err)
+}
+
+func (s *ReconcileTestSuite) Test_Reconcile_ReturnsError_WhenSourceFails() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.ReconcileSourceUrl = httpSrv.URL
+	service.Services["go-demo"] = true
+
+	actual, err := service.Reconcile(1, 0)
+
+	s.Error(err)
+	s.Empty(actual.Created)
+	s.Empty(actual.Removed)
+}
+
+func (s *ReconcileTestSuite) Test_Reconcile_DoesNothing_WhenReceiverIsInSync() {
+	requests := 0
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services" {
+			w.Write([]byte(`["go-demo"]`))
+			return
+		}
+		requests++
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, httpSrv.URL)
+	service.ReconcileSourceUrl = httpSrv.URL + "/services"
+	service.Services["go-demo"] = true
+
+	actual, err := service.Reconcile(1, 0)
+
+	s.NoError(err)
+	s.Equal(ReconcileResult{Created: []string{}, Removed: []string{}}, actual)
+	s.Equal(0, requests)
+}
+
+func (s *ReconcileTestSuite) Test_Reconcile_DoesNotRaceWithPollLoop() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services" {
+			w.Write([]byte(`["unknown"]`))
+		}
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, httpSrv.URL)
+	service.ReconcileSourceUrl = httpSrv.URL + "/services"
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 10; i++ {
+			service.Reconcile(1, 0)
+		}
+		close(done)
+	}()
+
+	for i := 0; i < 10; i++ {
+		srv := swarm.Service{}
+		srv.Spec.Name = fmt.Sprintf("go-demo-%d", i)
+		srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+		srv.Meta.CreatedAt = time.Now()
+		newServices, _ := service.GetNewServices([]swarm.Service{srv})
+		service.NotifyServicesCreate(newServices, 1, 0)
+		service.NotifyServicesRemove(service.GetRemovedServices([]swarm.Service{srv}), 1, 0)
+	}
+	<-done
+
+	s.Contains(service.Services, "go-demo-9")
+}
+
+// NewServiceFromEnv
+
+func (s *ReconcileTestSuite) Test_NewServiceFromEnv_SetsReconcileSourceUrl() {
+	sourceOrig := os.Getenv("DF_RECONCILE_SOURCE_URL")
+	defer func() { os.Setenv("DF_RECONCILE_SOURCE_URL", sourceOrig) }()
+	os.Setenv("DF_RECONCILE_SOURCE_URL", "http://proxy:8080/v1/docker-flow-proxy/services")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("http://proxy:8080/v1/docker-flow-proxy/services", service.ReconcileSourceUrl)
+}
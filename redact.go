@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+func (m *Service) redact(serviceName, text string) string {
+	s, ok := m.ServicesCache[serviceName]
+	if !ok {
+		return text
+	}
+	labels := getServiceLabels(s)
+	for _, k := range m.RedactLabels {
+		v := labels[k]
+		if len(v) == 0 {
+			continue
+		}
+		values := []string{v}
+		// Decoded labels are sent with the decoded value so that is the one that can show up in the logs
+		if decoded, err := base64.StdEncoding.DecodeString(v); err == nil && len(decoded) > 0 && m.isDecodedLabel(k) {
+			values = append(values, string(decoded))
+		}
+		for _, value := range values {
+			text = strings.Replace(text, value, "***", -1)
+			text = strings.Replace(text, escapeQueryValue(value), "***", -1)
+		}
+	}
+	return text
+}
+
+func (m *Service) redactInFlight(serviceName, text string) string {
+	// Requests are sent without holding the state lock so it is taken only to look up the labels
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	return m.redact(serviceName, text)
+}
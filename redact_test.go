@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+type RedactTestSuite struct {
+	suite.Suite
+	logs          []string
+	logPrintfOrig func(format string, v ...interface{})
+}
+
+func TestRedactUnitTestSuite(t *testing.T) {
+	s := new(RedactTestSuite)
+	suite.Run(t, s)
+}
+
+func (s *RedactTestSuite) SetupTest() {
+	s.logs = []string{}
+	s.logPrintfOrig = logPrintf
+	logPrintf = func(format string, v ...interface{}) {
+		s.logs = append(s.logs, fmt.Sprintf(format, v...))
+	}
+}
+
+func (s *RedactTestSuite) TearDownTest() {
+	logPrintf = s.logPrintfOrig
+}
+
+// redact
+
+func (s *RedactTestSuite) Test_Redact_ReplacesRedactedLabelValues() {
+	service := s.getService("")
+
+	actual := service.redact("go-demo", "http://proxy?serviceName=go-demo&authToken=s3cr3t&port=8080")
+
+	s.Equal("http://proxy?serviceName=go-demo&authToken=***&port=8080", actual)
+}
+
+func (s *RedactTestSuite) Test_Redact_ReplacesTaskTemplateLabelValues() {
+	service := s.getService("")
+	srv := service.ServicesCache["go-demo"]
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	srv.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{
+		Labels: map[string]string{"com.df.authToken": "s3cr3t"},
+	}
+	service.ServicesCache["go-demo"] = srv
+
+	actual := service.redact("go-demo", "http://proxy?serviceName=go-demo&authToken=s3cr3t")
+
+	s.Equal("http://proxy?serviceName=go-demo&authToken=***", actual)
+}
+
+func (s *RedactTestSuite) Test_Redact_ReplacesEscapedLabelValues() {
+	service := s.getService("")
+	srv := service.ServicesCache["go-demo"]
+	srv.Spec.Labels["com.df.authToken"] = "s3&cr3t"
+	service.ServicesCache["go-demo"] = srv
+
+	actual := service.redact("go-demo", "http://proxy?serviceName=go-demo&authToken=s3%26cr3t")
+
+	s.Equal("http://proxy?serviceName=go-demo&authToken=***", actual)
+}
+
+func (s *RedactTestSuite) Test_Redact_ReturnsText_WhenServiceIsNotTracked() {
+	service := s.getService("")
+
+	actual := service.redact("unknown", "authToken=s3cr3t")
+
+	s.Equal("authToken=s3cr3t", actual)
+}
+
+// NotifyServicesCreate
+
+func (s *RedactTestSuite) Test_NotifyServicesCreate_DoesNotLogRedactedValues() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer httpSrv.Close()
+	service := s.getService(httpSrv.URL)
+
+	service.NotifyServicesCreate([]swarm.Service{service.ServicesCache["go-demo"]}, 1, 0)
+
+	s.NotEmpty(s.logs)
+	for _, l := range s.logs {
+		s.NotContains(l, "s3cr3t")
+	}
+	s.Contains(s.logs[0], "authToken=***")
+}
+
+func (s *RedactTestSuite) Test_NotifyServicesCreate_DoesNotLogDecodedRedactedValues() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer httpSrv.Close()
+	service := s.getService(httpSrv.URL)
+	service.DecodeLabels = []string{"com.df.authToken"}
+	srv := service.ServicesCache["go-demo"]
+	// czNjcjN0 is s3cr3t
+	srv.Spec.Labels["com.df.authToken"] = "czNjcjN0"
+	service.ServicesCache["go-demo"] = srv
+
+	service.NotifyServicesCreate([]swarm.Service{srv}, 1, 0)
+
+	s.NotEmpty(s.logs)
+	for _, l := range s.logs {
+		s.NotContains(l, "s3cr3t")
+		s.NotContains(l, "czNjcjN0")
+	}
+	s.Contains(s.logs[0], "authToken=***")
+}
+
+// NewServiceFromEnv
+
+func (s *RedactTestSuite) Test_NewServiceFromEnv_SetsRedactLabels() {
+	redactOrig := os.Getenv("DF_LOG_REDACT_LABELS")
+	defer func() { os.Setenv("DF_LOG_REDACT_LABELS", redactOrig) }()
+	os.Setenv("DF_LOG_REDACT_LABELS", "com.df.authToken,com.df.password")
+
+	service := NewServiceFromEnv()
+
+	s.Equal([]string{"com.df.authToken", "com.df.password"}, service.RedactLabels)
+}
+
+// Util
+
+func (s *RedactTestSuite) getService(notifUrl string) *Service {
+	service := NewService("unix:///var/run/docker.sock", notifUrl, "")
+	service.RedactLabels = []string{"com.df.authToken"}
+	srv := swarm.Service{}
+	srv.Spec.Name = "go-demo"
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true", "com.df.authToken": "s3cr3t"}
+	service.Services["go-demo"] = true
+	service.ServicesCache["go-demo"] = srv
+	return service
+}
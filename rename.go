@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+)
+
+func (m *Service) detectRenames(services []swarm.Service) {
+	if m.TrackBy != "id" {
+		return
+	}
+	names := map[string]string{}
+	for name := range m.Services {
+		if id := m.ServicesCache[name].ID; len(id) > 0 {
+			names[id] = name
+		}
+	}
+	for _, s := range services {
+		oldName, ok := names[s.ID]
+		if !ok || len(s.ID) == 0 || oldName == s.Spec.Name || !m.hasNotifyLabel(s) {
+			continue
+		}
+		logPrintf("Service %s was renamed to %s", oldName, s.Spec.Name)
+		m.renamedServices[s.Spec.Name] = m.ServicesCache[oldName]
+		m.forgetService(oldName)
+		m.Services[s.Spec.Name] = true
+		m.ServicesCache[s.Spec.Name] = s
+		m.SpecDigests[s.Spec.Name] = getSpecDigest(s, m.UpdateWatchFields, m.LabelPrefix)
+	}
+}
+
+func isRename(previous, s swarm.Service) bool {
+	return len(s.ID) > 0 && previous.ID == s.ID && previous.Spec.Name != s.Spec.Name
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+type RenameTestSuite struct {
+	suite.Suite
+}
+
+func TestRenameUnitTestSuite(t *testing.T) {
+	s := new(RenameTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+func (s *RenameTestSuite) SetupTest() {
+	serviceLastCreatedAt = time.Time{}
+}
+
+// GetUpdatedServices
+
+func (s *RenameTestSuite) Test_GetUpdatedServices_ReturnsRenamedService_WhenTrackByIsId() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.TrackBy = "id"
+	srv := s.getSwarmService("go-demo")
+	service.GetNewServices([]swarm.Service{srv})
+	service.GetRemovedServices([]swarm.Service{srv})
+	renamed := s.getSwarmService("go-demo-renamed")
+	renamed.Version.Index = 2
+	services := []swarm.Service{renamed}
+
+	newServices, _ := service.GetNewServices(services)
+	updatedServices := service.GetUpdatedServices(services)
+	removedServices := service.GetRemovedServices(services)
+
+	s.Empty(newServices)
+	s.Equal([]swarm.Service{renamed}, updatedServices)
+	s.Empty(removedServices)
+	s.Equal(map[string]bool{"go-demo-renamed": true}, service.Services)
+	s.Equal("go-demo", service.PreviousServices["go-demo-renamed"].Spec.Name)
+}
+
+func (s *RenameTestSuite) Test_GetRemovedServices_ReturnsOldName_WhenTrackByIsName() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	srv := s.getSwarmService("go-demo")
+	service.GetNewServices([]swarm.Service{srv})
+	renamed := s.getSwarmService("go-demo-renamed")
+	renamed.Meta.CreatedAt = srv.Meta.CreatedAt.Add(time.Second)
+	services := []swarm.Service{renamed}
+
+	newServices, _ := service.GetNewServices(services)
+	removedServices := service.GetRemovedServices(services)
+
+	s.Equal([]swarm.Service{renamed}, newServices)
+	s.Equal([]string{"go-demo"}, removedServices)
+}
+
+// NotifyServicesUpdate
+
+func (s *RenameTestSuite) Test_NotifyServicesUpdate_SendsPreviousServiceName_WhenServiceIsRenamed() {
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifUpdateServiceUrl = httpSrv.URL
+	service.TrackBy = "id"
+	service.GetNewServices([]swarm.Service{s.getSwarmService("go-demo")})
+	services := []swarm.Service{s.getSwarmService("go-demo-renamed")}
+	service.GetNewServices(services)
+
+	err := service.NotifyServicesUpdate(service.GetUpdatedServices(services), 1, 0)
+
+	s.NoError(err)
+	s.Equal("serviceName=go-demo-renamed&changedFields=name&previousServiceName=go-demo", actualQuery)
+}
+
+// NewServiceFromEnv
+
+func (s *RenameTestSuite) Test_NewServiceFromEnv_SetsTrackBy() {
+	trackByOrig := os.Getenv("DF_TRACK_BY")
+	defer func() { os.Setenv("DF_TRACK_BY", trackByOrig) }()
+	os.Setenv("DF_TRACK_BY", "id")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("id", service.TrackBy)
+}
+
+func (s *RenameTestSuite) Test_NewServiceFromEnv_SetsTrackByToName_WhenEnvIsNotPresent() {
+	trackByOrig := os.Getenv("DF_TRACK_BY")
+	defer func() { os.Setenv("DF_TRACK_BY", trackByOrig) }()
+	os.Unsetenv("DF_TRACK_BY")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("name", service.TrackBy)
+}
+
+// Util
+
+func (s *RenameTestSuite) getSwarmService(name string) swarm.Service {
+	srv := swarm.Service{ID: "go-demo-id"}
+	srv.Spec.Name = name
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	srv.Meta.CreatedAt = time.Now().Add(-time.Minute)
+	srv.Version.Index = 1
+	return srv
+}
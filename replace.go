@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"time"
+)
+
+func (m *Service) CorrelateReplacements(newServices, updatedServices []swarm.Service, removedServices []string) ([]swarm.Service, []swarm.Service, []string) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	if m.ReplaceWindow <= 0 {
+		return newServices, updatedServices, removedServices
+	}
+	pendingKeys := map[string]string{}
+	for _, name := range removedServices {
+		if m.RemovalReasons[name] != "removed" {
+			continue
+		}
+		if _, ok := m.PendingRemovals[name]; !ok {
+			m.PendingRemovals[name] = time.Now()
+		}
+		pendingKeys[m.getNotificationKey(m.ServicesCache[name])] = name
+	}
+	created := []swarm.Service{}
+	updated := append([]swarm.Service{}, updatedServices...)
+	for _, s := range newServices {
+		old, ok := pendingKeys[m.getNotificationKey(s)]
+		if !ok {
+			created = append(created, s)
+			continue
+		}
+		logPrintf("Service %s replaced %s. An update notification will be sent instead of remove and create notifications", s.Spec.Name, old)
+		previous := m.ServicesCache[old]
+		delete(pendingKeys, m.getNotificationKey(s))
+		m.forgetService(old)
+		m.PreviousServices[s.Spec.Name] = previous
+		updated = append(updated, s)
+	}
+	removed := []string{}
+	for _, name := range removedServices {
+		if _, ok := m.Services[name]; !ok {
+			continue
+		}
+		if pendingAt, ok := m.PendingRemovals[name]; ok && time.Since(pendingAt) < m.ReplaceWindow {
+			continue
+		}
+		removed = append(removed, name)
+	}
+	return created, updated, removed
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+type ReplaceTestSuite struct {
+	suite.Suite
+}
+
+func TestReplaceUnitTestSuite(t *testing.T) {
+	s := new(ReplaceTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// CorrelateReplacements
+
+func (s *ReplaceTestSuite) Test_CorrelateReplacements_ReturnsInputs_WhenReplaceWindowIsNotSet() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	newServices := []swarm.Service{s.getService("go-demo-v2", "go-demo")}
+
+	created, updated, removed := service.CorrelateReplacements(newServices, []swarm.Service{}, []string{"go-demo-v1"})
+
+	s.Equal(newServices, created)
+	s.Empty(updated)
+	s.Equal([]string{"go-demo-v1"}, removed)
+}
+
+func (s *ReplaceTestSuite) Test_CorrelateReplacements_ConvertsReplaceToUpdate_WhenRoutingKeysMatch() {
+	service := s.getServiceWithOld()
+	newService := s.getService("go-demo-v2", "go-demo")
+
+	created, updated, removed := service.CorrelateReplacements([]swarm.Service{newService}, []swarm.Service{}, []string{"go-demo-v1"})
+
+	s.Empty(created)
+	s.Equal([]swarm.Service{newService}, updated)
+	s.Empty(removed)
+	s.NotContains(service.Services, "go-demo-v1")
+	s.Contains(service.PreviousServices, "go-demo-v2")
+}
+
+func (s *ReplaceTestSuite) Test_CorrelateReplacements_HoldsRemoval_WhileWindowIsOpen() {
+	service := s.getServiceWithOld()
+
+	created, _, removed := service.CorrelateReplacements([]swarm.Service{}, []swarm.Service{}, []string{"go-demo-v1"})
+
+	s.Empty(created)
+	s.Empty(removed)
+	s.Contains(service.PendingRemovals, "go-demo-v1")
+}
+
+func (s *ReplaceTestSuite) Test_CorrelateReplacements_ConvertsReplaceToUpdate_WhenServiceIsCreatedInLaterIteration() {
+	service := s.getServiceWithOld()
+	newService := s.getService("go-demo-v2", "go-demo")
+	service.CorrelateReplacements([]swarm.Service{}, []swarm.Service{}, []string{"go-demo-v1"})
+
+	created, updated, removed := service.CorrelateReplacements([]swarm.Service{newService}, []swarm.Service{}, []string{"go-demo-v1"})
+
+	s.Empty(created)
+	s.Equal(1, len(updated))
+	s.Empty(removed)
+}
+
+func (s *ReplaceTestSuite) Test_CorrelateReplacements_ReturnsRemoval_WhenWindowPassed() {
+	service := s.getServiceWithOld()
+	service.PendingRemovals["go-demo-v1"] = time.Now().Add(-time.Hour)
+
+	_, _, removed := service.CorrelateReplacements([]swarm.Service{}, []swarm.Service{}, []string{"go-demo-v1"})
+
+	s.Equal([]string{"go-demo-v1"}, removed)
+}
+
+func (s *ReplaceTestSuite) Test_CorrelateReplacements_DoesNotCorrelateServicesWithDifferentRoutingKeys() {
+	service := s.getServiceWithOld()
+	newService := s.getService("other", "other")
+
+	created, updated, _ := service.CorrelateReplacements([]swarm.Service{newService}, []swarm.Service{}, []string{"go-demo-v1"})
+
+	s.Equal(1, len(created))
+	s.Empty(updated)
+}
+
+// notifyServices
+
+func (s *ReplaceTestSuite) Test_NotifyServices_SendsSingleUpdate_WhenServiceIsReplaced() {
+	requests := []string{}
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, fmt.Sprintf("%s?%s", r.URL.Path, r.URL.RawQuery))
+	}))
+	defer httpSrv.Close()
+	service := s.getServiceWithOld()
+	service.NotifCreateServiceUrl = httpSrv.URL + "/create"
+	service.NotifUpdateServiceUrl = httpSrv.URL + "/update"
+	service.NotifRemoveServiceUrl = httpSrv.URL + "/remove"
+	newService := s.getService("go-demo-v2", "go-demo")
+	newServices, updated, removed := service.CorrelateReplacements([]swarm.Service{newService}, []swarm.Service{}, []string{"go-demo-v1"})
+
+	service.NotifyServicesCreate(newServices, 1, 0)
+	service.NotifyServicesUpdate(updated, 1, 0)
+	service.NotifyServicesRemove(removed, 1, 0)
+
+	s.Equal([]string{"/update?serviceName=go-demo-v2&serviceName=go-demo&changedFields="}, requests)
+}
+
+// NewServiceFromEnv
+
+func (s *ReplaceTestSuite) Test_NewServiceFromEnv_SetsReplaceWindow() {
+	windowOrig := os.Getenv("DF_REPLACE_WINDOW")
+	defer func() { os.Setenv("DF_REPLACE_WINDOW", windowOrig) }()
+	os.Setenv("DF_REPLACE_WINDOW", "30")
+
+	service := NewServiceFromEnv()
+
+	s.Equal(30*time.Second, service.ReplaceWindow)
+}
+
+// Util
+
+func (s *ReplaceTestSuite) getService(name, routingKey string) swarm.Service {
+	srv := swarm.Service{}
+	srv.Spec.Name = name
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true", "com.df.serviceName": routingKey}
+	return srv
+}
+
+func (s *ReplaceTestSuite) getServiceWithOld() *Service {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.ReplaceWindow = time.Minute
+	service.Services["go-demo-v1"] = true
+	service.ServicesCache["go-demo-v1"] = s.getService("go-demo-v1", "go-demo")
+	service.RemovalReasons["go-demo-v1"] = "removed"
+	return service
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"encoding/json"
+	"golang.org/x/net/websocket"
 	"net/http"
+	"net/http/pprof"
+	"strings"
 )
 
 var httpListenAndServe = http.ListenAndServe
@@ -13,8 +17,19 @@ type Server interface {
 	Run()
 }
 
+type Status struct {
+	Receipts           []Receipt           `json:"receipts"`
+	NotificationCounts []NotificationCount `json:"notificationCounts"`
+}
+
+type ResyncResponse struct {
+	Services []string `json:"services"`
+	Error    string   `json:"error,omitempty"`
+}
+
 type Serve struct {
-	Service Servicer
+	Service     Servicer
+	EnablePprof bool
 }
 
 func (m *Serve) Run() error {
@@ -32,11 +47,90 @@ func (m *Serve) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		go m.Service.NotifyServicesCreate(services, 10, 5)
 		// TODO: Add response message
 		w.WriteHeader(http.StatusOK)
+	case "/v1/docker-flow-swarm-listener/resync-removed":
+		if req.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		services, err := m.Service.ResendRemovals(1, 0)
+		response := ResyncResponse{Services: services}
+		status := http.StatusOK
+		if err != nil {
+			response.Error = err.Error()
+			status = http.StatusInternalServerError
+		}
+		js, _ := json.Marshal(response)
+		w.WriteHeader(status)
+		w.Write(js)
+	case "/v1/docker-flow-swarm-listener/reconcile":
+		if req.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		result, err := m.Service.Reconcile(1, 0)
+		status := http.StatusOK
+		if err != nil {
+			result.Error = err.Error()
+			status = http.StatusInternalServerError
+		}
+		js, _ := json.Marshal(result)
+		w.WriteHeader(status)
+		w.Write(js)
+	case "/v1/docker-flow-swarm-listener/status":
+		status := Status{
+			Receipts:           m.Service.GetReceipts(),
+			NotificationCounts: m.Service.GetNotificationCounts(),
+		}
+		js, _ := json.Marshal(status)
+		w.WriteHeader(http.StatusOK)
+		w.Write(js)
+	case "/v1/docker-flow-swarm-listener/metrics":
+		httpWriterSetContentType(w, "text/plain; version=0.0.4")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(metrics.Render()))
+	case "/v1/docker-flow-swarm-listener/events/ws":
+		websocket.Handler(m.streamEvents).ServeHTTP(w, req)
 	default:
+		if m.EnablePprof && strings.HasPrefix(req.URL.Path, "/v1/docker-flow-swarm-listener/debug/pprof/") {
+			http.StripPrefix("/v1/docker-flow-swarm-listener", getPprofMux()).ServeHTTP(w, req)
+			return
+		}
 		w.WriteHeader(http.StatusNotFound)
 	}
 }
 
+func (m *Serve) streamEvents(ws *websocket.Conn) {
+	events := eventStream.Subscribe()
+	defer eventStream.Unsubscribe(events)
+	closed := make(chan bool)
+	go func() {
+		// Clients are not expected to send anything so any read result means that the connection is gone
+		var msg string
+		websocket.Message.Receive(ws, &msg)
+		close(closed)
+	}()
+	for {
+		select {
+		case event := <-events:
+			if err := websocket.JSON.Send(ws, event); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+func getPprofMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
 func NewServe(service Servicer) *Serve {
 	return &Serve{
 		Service: service,
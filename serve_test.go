@@ -1,11 +1,16 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/docker/docker/api/types/swarm"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/websocket"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -113,6 +118,232 @@ func (s *ServerTestSuite) Test_ServeHTTP_InvokesNotifyServicesCreate_WhenUrlIsNo
 	mockObj.AssertCalled(s.T(), "NotifyServicesCreate", services, 10, 5)
 }
 
+func (s *ServerTestSuite) Test_ServeHTTP_ReturnsReceipts_WhenUrlIsStatus() {
+	mockObj := getServicerMock("GetReceipts")
+	receipts := []Receipt{{ServiceName: "go-demo", Event: "create", ReceiptId: "receipt-123"}}
+	mockObj.On("GetReceipts").Return(receipts)
+	req, _ := http.NewRequest("GET", "/v1/docker-flow-swarm-listener/status", nil)
+	rw := httptest.NewRecorder()
+
+	srv := NewServe(mockObj)
+	srv.ServeHTTP(rw, req)
+
+	actual := Status{}
+	json.Unmarshal(rw.Body.Bytes(), &actual)
+	s.Equal(http.StatusOK, rw.Code)
+	s.Equal(1, len(actual.Receipts))
+	s.Equal("go-demo", actual.Receipts[0].ServiceName)
+	s.Equal("create", actual.Receipts[0].Event)
+	s.Equal("receipt-123", actual.Receipts[0].ReceiptId)
+}
+
+func (s *ServerTestSuite) Test_ServeHTTP_ReturnsNotificationCounts_WhenUrlIsStatus() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, httpSrv.URL)
+	goDemo := swarm.Service{Spec: swarm.ServiceSpec{Annotations: swarm.Annotations{Name: "go-demo", Labels: map[string]string{"com.df.notify": "true"}}}}
+	other := swarm.Service{Spec: swarm.ServiceSpec{Annotations: swarm.Annotations{Name: "other", Labels: map[string]string{"com.df.notify": "true"}}}}
+	service.NotifyServicesCreate([]swarm.Service{goDemo, other}, 1, 0)
+	service.NotifyServicesUpdate([]swarm.Service{goDemo}, 1, 0)
+	service.NotifyServicesUpdate([]swarm.Service{goDemo}, 1, 0)
+	service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+	service.NotifyServicesCreate([]swarm.Service{goDemo}, 1, 0)
+	req, _ := http.NewRequest("GET", "/v1/docker-flow-swarm-listener/status", nil)
+	rw := httptest.NewRecorder()
+
+	srv := NewServe(service)
+	srv.ServeHTTP(rw, req)
+
+	actual := Status{}
+	json.Unmarshal(rw.Body.Bytes(), &actual)
+	s.Equal(http.StatusOK, rw.Code)
+	s.Equal([]NotificationCount{
+		{ServiceName: "go-demo", Create: 2, Update: 2, Remove: 1},
+		{ServiceName: "other", Create: 1},
+	}, actual.NotificationCounts)
+}
+
+func (s *ServerTestSuite) Test_ServeHTTP_ResendsRetainedRemovals_WhenUrlIsResyncRemoved() {
+	var paths []string
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.RequestURI())
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL+"/remove")
+	service.RemovalHistory.Add("go-demo", "removed", swarm.Service{})
+	service.RemovalHistory.Add("go-demo-2", "labelDropped", swarm.Service{})
+	req, _ := http.NewRequest("POST", "/v1/docker-flow-swarm-listener/resync-removed", nil)
+	rw := httptest.NewRecorder()
+
+	srv := NewServe(service)
+	srv.ServeHTTP(rw, req)
+
+	s.Equal(200, rw.Code)
+	s.JSONEq(`{"services":["go-demo","go-demo-2"]}`, rw.Body.String())
+	s.Equal([]string{"/remove?serviceName=go-demo&reason=removed", "/remove?serviceName=go-demo-2&reason=labelDropped"}, paths)
+}
+
+func (s *ServerTestSuite) Test_ServeHTTP_ReturnsInternalServerError_WhenResyncRemovedFails() {
+	mockObj := getServicerMock("ResendRemovals")
+	mockObj.On("ResendRemovals", 1, 0).Return([]string{"go-demo"}, fmt.Errorf("This is an error"))
+	req, _ := http.NewRequest("POST", "/v1/docker-flow-swarm-listener/resync-removed", nil)
+	rw := httptest.NewRecorder()
+
+	srv := NewServe(mockObj)
+	srv.ServeHTTP(rw, req)
+
+	s.Equal(500, rw.Code)
+	s.JSONEq(`{"services":["go-demo"],"error":"This is an error"}`, rw.Body.String())
+}
+
+func (s *ServerTestSuite) Test_ServeHTTP_ReturnsMethodNotAllowed_WhenResyncRemovedIsNotPost() {
+	mockObj := getServicerMock("")
+	req, _ := http.NewRequest("GET", "/v1/docker-flow-swarm-listener/resync-removed", nil)
+	rw := getResponseWriterMock()
+
+	srv := NewServe(mockObj)
+	srv.ServeHTTP(rw, req)
+
+	rw.AssertCalled(s.T(), "WriteHeader", 405)
+	mockObj.AssertNotCalled(s.T(), "ResendRemovals", mock.Anything, mock.Anything)
+}
+
+func (s *ServerTestSuite) Test_ServeHTTP_ReconcilesWithReceiver_WhenUrlIsReconcile() {
+	var mu sync.Mutex
+	paths := []string{}
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services" {
+			w.Write([]byte(`["go-demo","stale"]`))
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.RequestURI())
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL+"/reconfigure", httpSrv.URL+"/remove")
+	service.ReconcileSourceUrl = httpSrv.URL + "/services"
+	for _, name := range []string{"go-demo", "missing"} {
+		srv := swarm.Service{}
+		srv.Spec.Name = name
+		srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+		service.Services[name] = true
+		service.ServicesCache[name] = srv
+	}
+	req, _ := http.NewRequest("POST", "/v1/docker-flow-swarm-listener/reconcile", nil)
+	rw := httptest.NewRecorder()
+
+	srv := NewServe(service)
+	srv.ServeHTTP(rw, req)
+
+	s.Equal(200, rw.Code)
+	s.JSONEq(`{"created":["missing"],"removed":["stale"]}`, rw.Body.String())
+	s.ElementsMatch([]string{"/reconfigure?serviceName=missing", "/remove?serviceName=stale"}, paths)
+}
+
+func (s *ServerTestSuite) Test_ServeHTTP_ReturnsInternalServerError_WhenReconcileFails() {
+	mockObj := getServicerMock("Reconcile")
+	mockObj.On("Reconcile", 1, 0).Return(ReconcileResult{Created: []string{}, Removed: []string{}}, fmt.Errorf("This is an error"))
+	req, _ := http.NewRequest("POST", "/v1/docker-flow-swarm-listener/reconcile", nil)
+	rw := httptest.NewRecorder()
+
+	srv := NewServe(mockObj)
+	srv.ServeHTTP(rw, req)
+
+	s.Equal(500, rw.Code)
+	s.JSONEq(`{"created":[],"removed":[],"error":"This is an error"}`, rw.Body.String())
+}
+
+func (s *ServerTestSuite) Test_ServeHTTP_ReturnsMethodNotAllowed_WhenReconcileIsNotPost() {
+	mockObj := getServicerMock("")
+	req, _ := http.NewRequest("GET", "/v1/docker-flow-swarm-listener/reconcile", nil)
+	rw := getResponseWriterMock()
+
+	srv := NewServe(mockObj)
+	srv.ServeHTTP(rw, req)
+
+	rw.AssertCalled(s.T(), "WriteHeader", 405)
+	mockObj.AssertNotCalled(s.T(), "Reconcile", mock.Anything, mock.Anything)
+}
+
+func (s *ServerTestSuite) Test_ServeHTTP_ReturnsMetrics_WhenUrlIsMetrics() {
+	req, _ := http.NewRequest("GET", "/v1/docker-flow-swarm-listener/metrics", nil)
+	rw := httptest.NewRecorder()
+
+	srv := NewServe(getServicerMock(""))
+	srv.ServeHTTP(rw, req)
+
+	s.Equal(http.StatusOK, rw.Code)
+	s.Contains(rw.Body.String(), "docker_flow_swarm_listener_label_keys")
+	s.Contains(rw.Body.String(), "docker_flow_swarm_listener_malformed_label_values_total")
+}
+
+func (s *ServerTestSuite) Test_ServeHTTP_StreamsEvents_WhenUrlIsEventsWs() {
+	httpSrv := httptest.NewServer(NewServe(getServicerMock("")))
+	defer func() { httpSrv.Close() }()
+	url := strings.Replace(httpSrv.URL, "http://", "ws://", 1) + "/v1/docker-flow-swarm-listener/events/ws"
+	ws, err := websocket.Dial(url, "", httpSrv.URL)
+	s.Require().NoError(err)
+	defer func() { ws.Close() }()
+	s.waitForSubscribers(1)
+	expected := Event{Type: "create", ServiceName: "go-demo", Labels: map[string]string{"com.df.notify": "true"}}
+
+	eventStream.Publish(expected)
+
+	actual := Event{}
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	err = websocket.JSON.Receive(ws, &actual)
+	s.NoError(err)
+	s.Equal(expected, actual)
+}
+
+func (s *ServerTestSuite) Test_ServeHTTP_Unsubscribes_WhenEventsWsClientDisconnects() {
+	httpSrv := httptest.NewServer(NewServe(getServicerMock("")))
+	defer func() { httpSrv.Close() }()
+	url := strings.Replace(httpSrv.URL, "http://", "ws://", 1) + "/v1/docker-flow-swarm-listener/events/ws"
+	ws, err := websocket.Dial(url, "", httpSrv.URL)
+	s.Require().NoError(err)
+	s.waitForSubscribers(1)
+
+	ws.Close()
+
+	s.waitForSubscribers(0)
+	s.Equal(0, eventStream.SubscribersCount())
+}
+
+func (s *ServerTestSuite) Test_ServeHTTP_ServesPprof_WhenEnabled() {
+	req, _ := http.NewRequest("GET", "/v1/docker-flow-swarm-listener/debug/pprof/cmdline", nil)
+	rw := httptest.NewRecorder()
+
+	srv := NewServe(getServicerMock(""))
+	srv.EnablePprof = true
+	srv.ServeHTTP(rw, req)
+
+	s.Equal(200, rw.Code)
+}
+
+func (s *ServerTestSuite) Test_ServeHTTP_ServesPprofIndex_WhenEnabled() {
+	req, _ := http.NewRequest("GET", "/v1/docker-flow-swarm-listener/debug/pprof/", nil)
+	rw := httptest.NewRecorder()
+
+	srv := NewServe(getServicerMock(""))
+	srv.EnablePprof = true
+	srv.ServeHTTP(rw, req)
+
+	s.Equal(200, rw.Code)
+	s.Contains(rw.Body.String(), "goroutine")
+}
+
+func (s *ServerTestSuite) Test_ServeHTTP_ReturnsStatusNotFound_WhenPprofIsDisabled() {
+	req, _ := http.NewRequest("GET", "/v1/docker-flow-swarm-listener/debug/pprof/cmdline", nil)
+	rw := getResponseWriterMock()
+
+	srv := NewServe(getServicerMock(""))
+	srv.ServeHTTP(rw, req)
+
+	rw.AssertCalled(s.T(), "WriteHeader", 404)
+}
+
 // NewServe
 
 func (s *ServerTestSuite) Test_NewServe_SetsService() {
@@ -122,6 +353,14 @@ func (s *ServerTestSuite) Test_NewServe_SetsService() {
 	s.Equal(service, serve.Service)
 }
 
+// Util
+
+func (s *ServerTestSuite) waitForSubscribers(expected int) {
+	for i := 0; i < 100 && eventStream.SubscribersCount() != expected; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 // Mocks
 
 type ResponseWriterMock struct {
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"crypto/tls"
 	"fmt"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/swarm"
@@ -9,9 +11,12 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
-	"io/ioutil"
 )
 
 var logPrintf = log.Printf
@@ -22,19 +27,142 @@ type Service struct {
 	Host                  string
 	NotifCreateServiceUrl string
 	NotifRemoveServiceUrl string
+	NotifUpdateServiceUrl string
+	Targets               map[string]Target
+	EndpointTemplates     map[string]EndpointTemplate
+	NotifRemoveTemplate   string
+	RemoveBodyTemplate    string
+	RejectDuplicateKeys   bool
+	ManagedByLabel        string
+	RequireSecret         string
+	ShardIndex            int
+	ShardTotal            int
+	LabelPrefix           string
+	NotifyLabel           string
+	NotifyLabelsAny       []string
+	LabelOrder            []string
+	MaxLabelValueLength   int
+	OversizedLabelAction  string
+	ValidatePorts         string
+	EnrichUrl             string
+	EnrichTimeout         time.Duration
+	NotifStuckServiceUrl  string
+	StuckTaskTimeout      time.Duration
+	StuckServices         map[string]bool
+	NotifHealthServiceUrl string
+	HealthStates          map[string]bool
+	IncludeNodes          bool
+	LabelMapper           func(s swarm.Service) map[string]string
+	KeyMapper             func(s swarm.Service) string
+	NotifCreateFailureUrl string
+	CreateFailureWindow   time.Duration
+	CreatedServices       map[string]time.Time
+	RedactLabels          []string
+	DecodeLabels          []string
+	ProvenanceLabels      []string
+	EndpointConcurrency   int
+	NotifyStagger         time.Duration
+	UpdateWatchFields     []string
+	TransformUrl          string
+	TransformTimeout      time.Duration
+	TransformFailOpen     bool
+	ReplaceWindow         time.Duration
+	PendingRemovals       map[string]time.Time
+	RemoveWindow          string
+	deferredRemovals      map[string]bool
+	SettleDelay           time.Duration
+	FlapThreshold         int
+	FlapWindow            time.Duration
+	flapStates            map[string]*flapState
+	settlingServices      map[string]settlingService
+	NotifyHttp2           bool
+	NotifyFormat          string
+	NotifyMethod          string
+	NotifyAutoThreshold   int
+	EndpointMethods       map[string]string
+	NotifyInsecure        bool
+	LogLevel              string
+	skipLogs              map[string]skipLog
+	WriteBackStatus       string
+	StateFile             string
+	ResyncScope           string
+	loadedServices        map[string]bool
+	stateLoadedAt         time.Time
+	StartupGrace          time.Duration
+	StateSourceUrl        string
+	ReconcileSourceUrl    string
+	TrackBy               string
+	renamedServices       map[string]swarm.Service
+	sourcedServices       map[string]bool
+	rejectedServices      map[string]bool
+	TLSClientConfig       *tls.Config
+	transport             http.RoundTripper
+	transportOnce         sync.Once
+	stateMu               sync.Mutex
 	Services              map[string]bool
+	ServicesCache         map[string]swarm.Service
+	PreviousServices      map[string]swarm.Service
+	InactiveServices      map[string]bool
+	RemovalReasons        map[string]string
+	SpecDigests           map[string]string
+	Receipts              *Receipts
+	NotificationCounts    *NotificationCounts
+	RemovalHistory        *RemovalHistory
+	LastSpecs             *SpecCache
+	DaemonId              string
+	daemonChanged         bool
+	lastCreatedAtReset    bool
+	removalPass           uint64
+	seenPasses            map[string]uint64
+	NotifyTimeout         time.Duration
+	CycleRetryBudget      time.Duration
+	retryBudgetLeft       time.Duration
+	deferred              []notification
+	budgetMu              sync.Mutex
+	NotifyCycleTimeout    time.Duration
+	cycleCtx              context.Context
+	cycleCancel           context.CancelFunc
+	cycleMu               sync.Mutex
+	RetryIntervalRefused  int
+	RetryIntervalTimeout  int
+	TraceHeader           string
+	TraceLabel            string
+	traceIds              map[string]string
+	traceMu               sync.Mutex
+}
+
+type TemplateData struct {
+	ServiceName string
+	Url         string
+	Labels      map[string]string
+	Reason      string
+	Spec        LastSpec
 }
 
 type Servicer interface {
 	GetServices() ([]swarm.Service, error)
 	GetNewServices(services []swarm.Service) ([]swarm.Service, error)
+	GetUpdatedServices(services []swarm.Service) []swarm.Service
+	GetRemovedServices(services []swarm.Service) []string
 	NotifyServicesCreate(services []swarm.Service, retries, interval int) error
+	NotifyServicesUpdate(services []swarm.Service, retries, interval int) error
 	NotifyServicesRemove(services []string, retries, interval int) error
+	ForgetRemovedServices(services []string)
+	NotifyServicesStuck(services []swarm.Service, retries, interval int) error
+	NotifyServicesHealth(services []swarm.Service, retries, interval int) error
+	NotifyServicesCreateFailure(services []swarm.Service, retries, interval int) error
+	CorrelateReplacements(newServices, updatedServices []swarm.Service, removedServices []string) ([]swarm.Service, []swarm.Service, []string)
+	SettleChanges(services, newServices, updatedServices []swarm.Service) ([]swarm.Service, []swarm.Service)
+	GetReceipts() []Receipt
+	GetNotificationCounts() []NotificationCount
+	StartCycle(retries, interval int) error
+	EndCycle()
+	Reconcile(retries, interval int) (ReconcileResult, error)
+	ResendRemovals(retries, interval int) ([]string, error)
 }
 
 func (m *Service) GetServices() ([]swarm.Service, error) {
-	defaultHeaders := map[string]string{"User-Agent": "engine-api-cli-1.0"}
-	dc, err := dockerClient(m.Host, "v1.22", nil, defaultHeaders)
+	dc, err := newDockerClient(m.Host)
 
 	if err != nil {
 		return []swarm.Service{}, err
@@ -44,123 +172,707 @@ func (m *Service) GetServices() ([]swarm.Service, error) {
 	if err != nil {
 		return []swarm.Service{}, err
 	}
+	if info, err := dc.Info(context.Background()); err == nil {
+		m.stateMu.Lock()
+		m.checkDaemonId(getDaemonId(info))
+		m.stateMu.Unlock()
+	}
 
 	return services, nil
 }
 
+func (m *Service) checkDaemonId(daemonId string) {
+	if len(daemonId) == 0 {
+		return
+	}
+	if len(m.DaemonId) > 0 && m.DaemonId != daemonId {
+		logPrintf("Docker daemon changed from %s to %s. Services will be reconciled with the tracked ones.", m.DaemonId, daemonId)
+		m.daemonChanged = true
+	}
+	m.DaemonId = daemonId
+}
+
+func getDaemonId(info types.Info) string {
+	if info.Swarm.Cluster != nil && len(info.Swarm.Cluster.ID) > 0 {
+		return info.Swarm.Cluster.ID
+	}
+	return info.ID
+}
+
+func (m *Service) reconcileAfterDaemonChange(services []swarm.Service) []swarm.Service {
+	m.daemonChanged = false
+	newServices := []swarm.Service{}
+	for _, s := range services {
+		if len(m.getSkipReason(s)) > 0 {
+			continue
+		}
+		if serviceLastCreatedAt.Before(s.Meta.CreatedAt) {
+			serviceLastCreatedAt = s.Meta.CreatedAt
+		}
+		if _, ok := m.Services[s.Spec.Name]; ok {
+			m.ServicesCache[s.Spec.Name] = s
+			continue
+		}
+		newServices = append(newServices, s)
+		metrics.ObserveNewService(s, m.LabelPrefix)
+		m.Services[s.Spec.Name] = true
+		m.ServicesCache[s.Spec.Name] = s
+		m.SpecDigests[s.Spec.Name] = getSpecDigest(s, m.UpdateWatchFields, m.LabelPrefix)
+		delete(m.InactiveServices, s.Spec.Name)
+	}
+	return newServices
+}
+
+func newDockerClient(host string) (*client.Client, error) {
+	defaultHeaders := map[string]string{"User-Agent": "engine-api-cli-1.0"}
+	return dockerClient(host, "v1.22", nil, defaultHeaders)
+}
+
 func (m *Service) GetNewServices(services []swarm.Service) ([]swarm.Service, error) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	metrics.ObserveServices(services, m.LabelPrefix)
+	m.detectRenames(services)
+	m.detectFlaps(services)
+	m.checkLastCreatedAt(services)
+	if m.daemonChanged {
+		m.lastCreatedAtReset = false
+		return m.reconcileAfterDaemonChange(services), nil
+	}
+	lastCreatedAtReset := m.lastCreatedAtReset
+	m.lastCreatedAtReset = false
 	newServices := []swarm.Service{}
+	rejected := map[string]bool{}
 	tmpCreatedAt := serviceLastCreatedAt
 	for _, s := range services {
-		if tmpCreatedAt.Nanosecond() == 0 || s.Meta.CreatedAt.After(tmpCreatedAt) {
-			if _, ok := s.Spec.Labels["com.df.notify"]; ok {
-				newServices = append(newServices, s)
-				m.Services[s.Spec.Name] = true
+		if _, renamed := m.renamedServices[s.Spec.Name]; renamed {
+			continue
+		}
+		reactivated := m.InactiveServices[s.Spec.Name] && len(m.getInactiveReason(s)) == 0
+		byTimestamp := tmpCreatedAt.Nanosecond() == 0 || s.Meta.CreatedAt.After(tmpCreatedAt) || reactivated
+		// Membership catches labeled services the creation time misses, e.g. labels added after a restore
+		if byTimestamp || m.isUntracked(s) {
+			if reason := m.getSkipReason(s); len(reason) > 0 {
+				m.logSkip(s.Spec.Name, reason)
+				continue
+			}
+			if m.isRecreatedWithNewMode(s) {
+				// The mode cannot be changed in place so the service was recreated and is left to be notified as updated
+				logPrintf("The mode of the service %s changed to %s. It will be notified as updated", s.Spec.Name, getServiceMode(s))
 				if serviceLastCreatedAt.Before(s.Meta.CreatedAt) {
 					serviceLastCreatedAt = s.Meta.CreatedAt
 				}
+				continue
+			}
+			if owner, found := m.getDuplicateKeyOwner(s); found {
+				// Rejected services stay untracked and are checked again on every poll so the warning is logged only once
+				if !m.rejectedServices[s.Spec.Name] {
+					logPrintf(
+						"WARNING: Services %s and %s produce the same notification key %s",
+						owner,
+						s.Spec.Name,
+						m.getNotificationKey(s),
+					)
+				}
+				if m.RejectDuplicateKeys {
+					rejected[s.Spec.Name] = true
+					m.logSkip(s.Spec.Name, "the notification key is a duplicate")
+					continue
+				}
+			}
+			if !byTimestamp && !lastCreatedAtReset {
+				logPrintf("Service %s is not tracked even though it was created before the last known service. It will be notified as created", s.Spec.Name)
+			}
+			if ports := m.getUnexposedPorts(s); len(ports) > 0 {
+				logPrintf("WARNING: The ports %s of the service %s are not exposed", strings.Join(ports, ","), s.Spec.Name)
+			}
+			if m.isKnownToReceiver(s) {
+				// The receiver already knows about the service so it is only tracked
+				delete(m.sourcedServices, s.Spec.Name)
+			} else {
+				newServices = append(newServices, s)
+				metrics.ObserveNewService(s, m.LabelPrefix)
+			}
+			m.Services[s.Spec.Name] = true
+			m.ServicesCache[s.Spec.Name] = s
+			m.SpecDigests[s.Spec.Name] = getSpecDigest(s, m.UpdateWatchFields, m.LabelPrefix)
+			m.RemovalHistory.Delete(s.Spec.Name)
+			delete(m.InactiveServices, s.Spec.Name)
+			if len(m.NotifCreateFailureUrl) > 0 && time.Since(s.Meta.CreatedAt) <= m.CreateFailureWindow {
+				m.CreatedServices[s.Spec.Name] = s.Meta.CreatedAt
+			}
+			if serviceLastCreatedAt.Before(s.Meta.CreatedAt) {
+				serviceLastCreatedAt = s.Meta.CreatedAt
 			}
 		}
 	}
+	m.sourcedServices = map[string]bool{}
+	m.rejectedServices = rejected
 	return newServices, nil
 }
 
-func (m *Service) GetRemovedServices(services []swarm.Service) []string {
-	tmpMap := make(map[string]bool)
-	for k, _ := range m.Services {
-		tmpMap[k] = true
+func (m *Service) isUntracked(service swarm.Service) bool {
+	name := service.Spec.Name
+	return !m.Services[name] && !m.InactiveServices[name] && m.hasNotifyLabel(service)
+}
+
+func (m *Service) isManaged(service swarm.Service) bool {
+	if len(m.ManagedByLabel) == 0 {
+		return true
 	}
-	for _, v := range services {
-		if _, ok := m.Services[v.Spec.Name]; ok {
-			delete(tmpMap, v.Spec.Name)
+	kv := strings.SplitN(m.ManagedByLabel, "=", 2)
+	value, ok := getServiceLabels(service)[kv[0]]
+	if len(kv) == 1 {
+		return ok
+	}
+	return ok && value == kv[1]
+}
+
+func (m *Service) hasRequiredSecret(service swarm.Service) bool {
+	if len(m.RequireSecret) == 0 {
+		return true
+	}
+	for _, secret := range getSecrets(service) {
+		if secret != nil && secret.SecretName == m.RequireSecret {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Service) getDuplicateKeyOwner(service swarm.Service) (string, bool) {
+	key := m.getNotificationKey(service)
+	for name, s := range m.ServicesCache {
+		if name != service.Spec.Name && m.getNotificationKey(s) == key {
+			return name, true
 		}
 	}
+	return "", false
+}
+
+func (m *Service) getNotificationKey(service swarm.Service) string {
+	if m.KeyMapper != nil {
+		return m.KeyMapper(service)
+	}
+	if alias, ok := getServiceLabels(service)[m.LabelPrefix+"serviceName"]; ok && len(alias) > 0 {
+		return alias
+	}
+	return service.Spec.Name
+}
+
+func (m *Service) GetRemovedServices(services []swarm.Service) []string {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	// Each pass marks the tracked and inactive services it sees so that the rest are found without copying the tracked set
+	m.removalPass++
 	rs := []string{}
-	for k, _ := range tmpMap {
-		rs = append(rs, k)
+	for _, v := range services {
+		name := v.Spec.Name
+		_, tracked := m.Services[name]
+		if (!tracked && !m.InactiveServices[name]) || m.seenPasses[name] == m.removalPass {
+			continue
+		}
+		m.seenPasses[name] = m.removalPass
+		if !tracked {
+			continue
+		}
+		if reason := m.getInactiveReason(v); len(reason) > 0 {
+			rs = m.addRemoval(rs, name, reason)
+		}
+	}
+	for name := range m.Services {
+		if m.seenPasses[name] != m.removalPass {
+			rs = m.addRemoval(rs, name, "removed")
+		}
 	}
-	return rs
+	for name := range m.InactiveServices {
+		if m.seenPasses[name] != m.removalPass {
+			delete(m.InactiveServices, name)
+			delete(m.seenPasses, name)
+		}
+	}
+	return m.deferRemovals(m.dropStaleServices(rs, services))
 }
 
-func (m *Service) NotifyServicesCreate(services []swarm.Service, retries, interval int) error {
-	errs := []error{}
+func (m *Service) addRemoval(rs []string, name, reason string) []string {
+	m.RemovalReasons[name] = reason
+	// A flapping service stays tracked so that only its final state is notified once it stabilizes
+	if reason != "removed" && m.isFlapping(name) {
+		return rs
+	}
+	return append(rs, name)
+}
+
+func (m *Service) hasNotifyLabel(s swarm.Service) bool {
+	labels := getServiceLabels(s)
+	if _, ok := labels[m.NotifyLabel]; ok {
+		return true
+	}
+	for _, label := range m.NotifyLabelsAny {
+		if _, ok := labels[label]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Service) getInactiveReason(s swarm.Service) string {
+	if !m.hasNotifyLabel(s) {
+		return "labelDropped"
+	}
+	if s.Spec.Mode.Replicated != nil && s.Spec.Mode.Replicated.Replicas != nil && *s.Spec.Mode.Replicated.Replicas == 0 {
+		return "scaledToZero"
+	}
+	return ""
+}
+
+func (m *Service) GetUpdatedServices(services []swarm.Service) []swarm.Service {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	updatedServices := []swarm.Service{}
 	for _, s := range services {
-		fullUrl := fmt.Sprintf("%s?serviceName=%s", m.NotifCreateServiceUrl, s.Spec.Name)
-		if _, ok := s.Spec.Labels["com.df.notify"]; ok {
-			for k, v := range s.Spec.Labels {
-				if strings.HasPrefix(k, "com.df") && k != "com.df.notify" {
-					fullUrl = fmt.Sprintf("%s&%s=%s", fullUrl, strings.TrimPrefix(k, "com.df."), v)
+		if !m.hasNotifyLabel(s) {
+			continue
+		}
+		if previous, renamed := m.renamedServices[s.Spec.Name]; renamed {
+			delete(m.renamedServices, s.Spec.Name)
+			updatedServices = append(updatedServices, s)
+			m.PreviousServices[s.Spec.Name] = previous
+			continue
+		}
+		cached, ok := m.ServicesCache[s.Spec.Name]
+		if !ok {
+			continue
+		}
+		modeChanged := isModeChange(cached, s)
+		// Swarm increments the version index on every change so services with the same index can be skipped
+		if !modeChanged && s.Version.Index > 0 && s.Version.Index == cached.Version.Index {
+			continue
+		}
+		digest := getSpecDigest(s, m.UpdateWatchFields, m.LabelPrefix)
+		if !modeChanged && digest == m.SpecDigests[s.Spec.Name] {
+			m.ServicesCache[s.Spec.Name] = s
+			continue
+		}
+		if modeChanged || len(getChangedFields(cached, s, m.UpdateWatchFields, m.LabelPrefix)) > 0 {
+			updatedServices = append(updatedServices, s)
+			m.PreviousServices[s.Spec.Name] = cached
+			m.ServicesCache[s.Spec.Name] = s
+			m.SpecDigests[s.Spec.Name] = digest
+		}
+	}
+	return updatedServices
+}
+
+func (m *Service) NotifyServicesCreate(services []swarm.Service, retries, interval int) error {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	return m.notifyServicesCreate(services, retries, interval)
+}
+
+func (m *Service) notifyServicesCreate(services []swarm.Service, retries, interval int) error {
+	labeled := []swarm.Service{}
+	for _, s := range m.sortByWeight(services) {
+		if m.hasNotifyLabel(s) {
+			labeled = append(labeled, s)
+		}
+	}
+	if m.NotifyMethod == "stdout" {
+		if err := m.writeServiceEvents("create", labeled); err != nil {
+			return err
+		}
+		m.settleWritten("create", labeled)
+		return nil
+	}
+	extraParams := m.getExtraParamsUnlocked(labeled)
+	notifications := []notification{}
+	for _, s := range labeled {
+		m.rememberTraceId(s)
+		for _, baseUrl := range getUrls(m.getTarget(s).CreateUrl) {
+			fullUrl := m.getCreateUrl(baseUrl, s) + extraParams[s.Spec.Name]
+			logPrintf("Sending service created notification to %s", m.redact(s.Spec.Name, fullUrl))
+			notifications = append(notifications, notification{s.Spec.Name, "create", fullUrl, ""})
+		}
+	}
+	errs := m.sendUnlocked(notifications, retries, interval)
+	m.settleNotified("create", services, getNotifiedServiceNames(notifications), errs)
+	if len(errs) > 0 {
+		return fmt.Errorf("At least one request produced errors. Please consult logs for more details.")
+	}
+	return nil
+}
+
+func (m *Service) NotifyServicesUpdate(services []swarm.Service, retries, interval int) error {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	if m.NotifyMethod == "stdout" {
+		if err := m.writeServiceEvents("update", m.sortByWeight(services)); err != nil {
+			return err
+		}
+		m.settleWritten("update", services)
+		return nil
+	}
+	sorted := m.sortByWeight(services)
+	extraParams := m.getExtraParamsUnlocked(sorted)
+	notifications := []notification{}
+	for _, s := range sorted {
+		m.rememberTraceId(s)
+		for _, baseUrl := range getUrls(m.getTarget(s).UpdateUrl) {
+			fullUrl := m.getCreateUrl(baseUrl, s) + extraParams[s.Spec.Name]
+			if previous, ok := m.PreviousServices[s.Spec.Name]; ok {
+				changedFields := getChangedFields(previous, s, m.UpdateWatchFields, m.LabelPrefix)
+				if isModeChange(previous, s) {
+					changedFields = append([]string{"mode"}, changedFields...)
 				}
-			}
-			logPrintf("Sending service created notification to %s", fullUrl)
-			for i := 1; i <= retries; i++ {
-				resp, err := http.Get(fullUrl)
-				if err == nil && resp.StatusCode == http.StatusOK {
-					break
-				} else if i < retries {
-					if interval > 0 {
-						t := time.NewTicker(time.Second * time.Duration(interval))
-						<-t.C
-					}
-				} else {
-					if err != nil {
-						logPrintf("ERROR: %s", err.Error())
-						errs = append(errs, err)
-					} else if resp.StatusCode != http.StatusOK {
-						body, _ := ioutil.ReadAll(resp.Body)
-						msg := fmt.Errorf("Request %s returned status code %d\n%s", fullUrl, resp.StatusCode, string(body[:]))
-						logPrintf("ERROR: %s", msg)
-						errs = append(errs, msg)
-					}
+				if isRename(previous, s) {
+					changedFields = append([]string{"name"}, changedFields...)
+				}
+				fullUrl = fmt.Sprintf("%s&changedFields=%s", fullUrl, strings.Join(changedFields, ","))
+				if isRename(previous, s) {
+					fullUrl = fmt.Sprintf("%s&previousServiceName=%s", fullUrl, previous.Spec.Name)
+				}
+				if isModeChange(previous, s) {
+					fullUrl = fmt.Sprintf("%s&mode=%s", fullUrl, getServiceMode(s))
+				}
+				if envNames := getChangedEnvNames(previous, s); len(envNames) > 0 {
+					fullUrl = fmt.Sprintf("%s&changedEnv=%s", fullUrl, strings.Join(envNames, ","))
+				}
+				if labelChanges := getLabelChangesParam(previous, s, m.LabelPrefix); len(labelChanges) > 0 {
+					fullUrl = fmt.Sprintf("%s&labelChanges=%s", fullUrl, labelChanges)
 				}
 			}
+			logPrintf("Sending service updated notification to %s", m.redact(s.Spec.Name, fullUrl))
+			notifications = append(notifications, notification{s.Spec.Name, "update", fullUrl, ""})
 		}
 	}
+	errs := m.sendUnlocked(notifications, retries, interval)
+	m.settleNotified("update", services, getNotifiedServiceNames(notifications), errs)
 	if len(errs) > 0 {
 		return fmt.Errorf("At least one request produced errors. Please consult logs for more details.")
 	}
 	return nil
 }
 
+func (m *Service) settleNotified(eventType string, services []swarm.Service, names []string, errs map[string]error) {
+	m.writeBackStatus(names, errs)
+	m.rememberSpecs(services, errs)
+	m.markProcessed(eventType, names, errs)
+	if eventType != "update" {
+		return
+	}
+	for _, s := range services {
+		if _, failed := errs[s.Spec.Name]; !failed {
+			delete(m.PreviousServices, s.Spec.Name)
+		}
+	}
+}
+
 func (m *Service) NotifyServicesRemove(services []string, retries, interval int) error {
-	errs := []error{}
-	for _, v := range services {
-		fullUrl := fmt.Sprintf("%s?serviceName=%s", m.NotifRemoveServiceUrl, v)
-		logPrintf("Sending service removed notification to %s", fullUrl)
-		for i := 1; i <= retries; i++ {
-			resp, err := http.Get(fullUrl)
-			if err == nil && resp.StatusCode == http.StatusOK {
-				delete(m.Services, v)
-				break
-			} else if i < retries {
-				if interval > 0 {
-					t := time.NewTicker(time.Second * time.Duration(interval))
-					<-t.C
-				}
-			} else {
-				if err != nil {
-					logPrintf("ERROR: %s", err.Error())
-					errs = append(errs, err)
-				} else if resp.StatusCode != http.StatusOK {
-					msg := fmt.Errorf("Request %s returned status code %d", fullUrl, resp.StatusCode)
-					logPrintf("ERROR: %s", msg)
-					errs = append(errs, msg)
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	errs := map[string]error{}
+	for _, group := range m.getRemoveGroups(services) {
+		if m.NotifyMethod == "stdout" {
+			events := []Event{}
+			for _, v := range group {
+				events = append(events, Event{Type: "remove", ServiceName: v, Labels: getServiceLabels(m.ServicesCache[v])})
+			}
+			if err := m.writeEvents(events); err != nil {
+				for _, v := range group {
+					errs[v] = err
 				}
 			}
+			continue
+		}
+		m.notifyShutdown(group, retries, interval)
+		notifications := []notification{}
+		for _, v := range group {
+			m.rememberTraceId(m.ServicesCache[v])
+			removals, err := m.getRemoveNotifications(v)
+			if err != nil {
+				logPrintf("ERROR: %s", err.Error())
+				errs[v] = err
+				continue
+			}
+			for _, n := range removals {
+				logPrintf("Sending service removed notification to %s", m.redact(v, n.fullUrl))
+				notifications = append(notifications, n)
+			}
+		}
+		for k, err := range m.sendUnlocked(notifications, retries, interval) {
+			errs[k] = err
 		}
 	}
+	m.settleRemovals(services, errs)
+	m.markProcessed("remove", services, errs)
 	if len(errs) > 0 {
 		return fmt.Errorf("At least one request produced errors. Please consult logs for more details.")
 	}
 	return nil
 }
 
+func (m *Service) ForgetRemovedServices(services []string) {
+	// Followers do not notify but still stop tracking removed services so that a new leader does not send stale removals
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	m.settleRemovals(services, map[string]error{})
+}
+
+func (m *Service) settleRemovals(services []string, errs map[string]error) {
+	for _, v := range services {
+		if _, failed := errs[v]; failed {
+			continue
+		}
+		if reason, ok := m.RemovalReasons[v]; ok && reason != "removed" {
+			m.InactiveServices[v] = true
+		}
+		m.RemovalHistory.Add(v, m.RemovalReasons[v], m.ServicesCache[v])
+		// Removals are published once they are committed so that failed removals are not streamed again on every retry
+		eventStream.Publish(Event{Type: "remove", ServiceName: v, Labels: getServiceLabels(m.ServicesCache[v])})
+		m.forgetService(v)
+	}
+	m.resetBaselineWhenEmpty()
+}
+
+func (m *Service) forgetService(name string) {
+	delete(m.Services, name)
+	delete(m.ServicesCache, name)
+	delete(m.PreviousServices, name)
+	delete(m.RemovalReasons, name)
+	delete(m.SpecDigests, name)
+	delete(m.StuckServices, name)
+	delete(m.HealthStates, name)
+	delete(m.CreatedServices, name)
+	delete(m.PendingRemovals, name)
+	m.Receipts.Forget(name)
+	m.forgetTraceId(name)
+	if !m.InactiveServices[name] {
+		delete(m.seenPasses, name)
+	}
+}
+
+func (m *Service) getRemoveGroups(services []string) [][]string {
+	orders := []int{}
+	groups := map[int][]string{}
+	for _, v := range services {
+		order := 0
+		if s, ok := m.ServicesCache[v]; ok {
+			if value, ok := getServiceLabels(s)[m.LabelPrefix+"removeOrder"]; ok {
+				if i, err := strconv.Atoi(value); err == nil {
+					order = i
+				} else {
+					logPrintf("WARNING: The removeOrder label of the service %s is not a number", v)
+				}
+			}
+		}
+		if _, ok := groups[order]; !ok {
+			orders = append(orders, order)
+		}
+		groups[order] = append(groups[order], v)
+	}
+	sort.Ints(orders)
+	sorted := [][]string{}
+	for _, order := range orders {
+		sorted = append(sorted, m.sortNamesByWeight(groups[order]))
+	}
+	return sorted
+}
+
+func (m *Service) getCreateUrl(baseUrl string, s swarm.Service) string {
+	fullUrl := fmt.Sprintf("%s?serviceName=%s", baseUrl, s.Spec.Name)
+	labels := m.getNotificationLabels(s)
+	for k, v := range m.getProvenance(s) {
+		if _, ok := labels[k]; !ok {
+			labels[k] = v
+		}
+	}
+	domains := m.getServiceDomains(s, labels)
+	keys := []string{}
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	m.sortLabelKeys(keys)
+	for _, k := range keys {
+		fullUrl = fmt.Sprintf("%s&%s=%s", fullUrl, k, escapeQueryValue(labels[k]))
+	}
+	for _, d := range domains {
+		fullUrl = fmt.Sprintf("%s&serviceDomain=%s", fullUrl, escapeQueryValue(d))
+	}
+	return fullUrl
+}
+
+func escapeQueryValue(value string) string {
+	// Only the characters that change how the query is parsed are escaped so that receivers keep getting readable paths
+	var buf bytes.Buffer
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c <= ' ' || c == 0x7f || strings.IndexByte("%&#+;", c) >= 0 {
+			fmt.Fprintf(&buf, "%%%02X", c)
+			continue
+		}
+		buf.WriteByte(c)
+	}
+	return buf.String()
+}
+
+func (m *Service) sortLabelKeys(keys []string) {
+	priorities := map[string]int{}
+	for i, k := range m.LabelOrder {
+		priorities[k] = i - len(m.LabelOrder)
+	}
+	// Keys without a priority get zero so they follow the ordered ones alphabetically
+	sort.Slice(keys, func(i, j int) bool {
+		if priorities[keys[i]] != priorities[keys[j]] {
+			return priorities[keys[i]] < priorities[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+}
+
+func (m *Service) getNotificationLabels(s swarm.Service) map[string]string {
+	if m.LabelMapper != nil {
+		return m.truncateLabels(s.Spec.Name, m.LabelMapper(s))
+	}
+	labels := map[string]string{}
+	for k, v := range getServiceLabels(s) {
+		if strings.HasPrefix(k, m.LabelPrefix) && k != m.NotifyLabel && k != m.LabelPrefix+notifyStatusKey {
+			labels[strings.TrimPrefix(k, m.LabelPrefix)] = m.decodeLabel(s.Spec.Name, k, v)
+		}
+	}
+	return m.truncateLabels(s.Spec.Name, labels)
+}
+
+func (m *Service) GetReceipts() []Receipt {
+	return m.Receipts.GetAll()
+}
+
+func (m *Service) GetNotificationCounts() []NotificationCount {
+	return m.NotificationCounts.GetAll()
+}
+
+func (m *Service) ValidateTemplates() error {
+	if len(m.NotifRemoveTemplate) > 0 {
+		if _, err := template.New("remove").Parse(m.NotifRemoveTemplate); err != nil {
+			return fmt.Errorf("DF_REMOVE_TEMPLATE could not be parsed\n%s", err.Error())
+		}
+	}
+	if len(m.RemoveBodyTemplate) > 0 {
+		if _, err := template.New("removeBody").Parse(m.RemoveBodyTemplate); err != nil {
+			return fmt.Errorf("DF_REMOVE_BODY_TEMPLATE could not be parsed\n%s", err.Error())
+		}
+	}
+	if err := m.validateRemoveWindow(); err != nil {
+		return err
+	}
+	if err := m.validateEndpointMethods(); err != nil {
+		return err
+	}
+	return m.validateEndpointTemplates()
+}
+
+func (m *Service) getRemoveNotifications(serviceName string) ([]notification, error) {
+	return m.getRemoveNotificationsFor(serviceName, m.RemovalReasons[serviceName], m.ServicesCache[serviceName])
+}
+
+func (m *Service) getRemoveNotificationsFor(serviceName, reason string, s swarm.Service) ([]notification, error) {
+	baseUrls := getUrls(m.getTarget(s).RemoveUrl)
+	// A URL template does not need a remove URL so it is rendered once even when none is configured
+	if len(baseUrls) == 0 && len(m.NotifRemoveTemplate) > 0 {
+		baseUrls = []string{""}
+	}
+	data := TemplateData{
+		ServiceName: serviceName,
+		Labels:      getServiceLabels(s),
+		Reason:      reason,
+	}
+	data.Spec, _ = m.LastSpecs.Get(serviceName)
+	notifications := []notification{}
+	found := map[notification]bool{}
+	for _, baseUrl := range baseUrls {
+		data.Url = baseUrl
+		fullUrl := fmt.Sprintf("%s?serviceName=%s", baseUrl, serviceName)
+		if len(reason) > 0 {
+			fullUrl = fmt.Sprintf("%s&reason=%s", fullUrl, reason)
+		}
+		fullUrl = m.addLastSpec(fullUrl, serviceName)
+		body := ""
+		var err error
+		if len(m.NotifRemoveTemplate) > 0 {
+			fullUrl, err = renderRemoveTemplate("remove", m.NotifRemoveTemplate, data)
+		}
+		if err == nil && len(m.RemoveBodyTemplate) > 0 {
+			body, err = renderRemoveTemplate("removeBody", m.RemoveBodyTemplate, data)
+		}
+		if err != nil {
+			return []notification{}, err
+		}
+		// Templates that do not use the URL of the target render the same request for every target
+		n := notification{serviceName, "remove", fullUrl, body}
+		if !found[n] {
+			found[n] = true
+			notifications = append(notifications, n)
+		}
+	}
+	return notifications, nil
+}
+
+func renderRemoveTemplate(name, text string, data TemplateData) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 func NewService(host, notifCreateServiceUrl, notifRemoveServiceUrl string) *Service {
 	return &Service{
-		Host: host,
+		Host:                  host,
 		NotifCreateServiceUrl: notifCreateServiceUrl,
 		NotifRemoveServiceUrl: notifRemoveServiceUrl,
+		NotifUpdateServiceUrl: notifCreateServiceUrl,
 		Services:              make(map[string]bool),
+		ServicesCache:         make(map[string]swarm.Service),
+		PreviousServices:      make(map[string]swarm.Service),
+		InactiveServices:      make(map[string]bool),
+		seenPasses:            make(map[string]uint64),
+		traceIds:              make(map[string]string),
+		TraceLabel:            "com.df.traceId",
+		RemovalReasons:        make(map[string]string),
+		SpecDigests:           make(map[string]string),
+		UpdateWatchFields:     defaultUpdateWatchFields,
+		TransformTimeout:      5 * time.Second,
+		TransformFailOpen:     true,
+		NotifyAutoThreshold:   2048,
+		EndpointMethods:       make(map[string]string),
+		StuckServices:         make(map[string]bool),
+		HealthStates:          make(map[string]bool),
+		CreatedServices:       make(map[string]time.Time),
+		PendingRemovals:       make(map[string]time.Time),
+		deferredRemovals:      make(map[string]bool),
+		settlingServices:      make(map[string]settlingService),
+		flapStates:            make(map[string]*flapState),
+		FlapWindow:            5 * time.Minute,
+		ResyncScope:           "creates",
+		loadedServices:        make(map[string]bool),
+		sourcedServices:       make(map[string]bool),
+		renamedServices:       make(map[string]swarm.Service),
+		Targets:               make(map[string]Target),
+		EndpointTemplates:     make(map[string]EndpointTemplate),
+		skipLogs:              make(map[string]skipLog),
+		CreateFailureWindow:   60 * time.Second,
+		LabelPrefix:           "com.df.",
+		NotifyLabel:           "com.df.notify",
+		EnrichTimeout:         5 * time.Second,
+		Receipts:              NewReceipts(1000),
+		NotificationCounts:    NewNotificationCounts(1000),
+		RemovalHistory:        NewRemovalHistory(1000, 24*time.Hour),
+		LastSpecs:             NewSpecCache(1000),
+		RetryIntervalRefused:  -1,
+		RetryIntervalTimeout:  -1,
 	}
 }
 
@@ -177,10 +889,93 @@ func NewServiceFromEnv() *Service {
 	if len(notifRemoveServiceUrl) == 0 {
 		notifRemoveServiceUrl = os.Getenv("DF_NOTIFICATION_URL")
 	}
-	return &Service{
-		Host: host,
-		NotifCreateServiceUrl: notifCreateServiceUrl,
-		NotifRemoveServiceUrl: notifRemoveServiceUrl,
-		Services:              make(map[string]bool),
+	service := NewService(host, notifCreateServiceUrl, notifRemoveServiceUrl)
+	if len(os.Getenv("DF_NOTIF_UPDATE_SERVICE_URL")) > 0 {
+		service.NotifUpdateServiceUrl = os.Getenv("DF_NOTIF_UPDATE_SERVICE_URL")
+	}
+	service.Targets = getTargetsFromEnv()
+	service.EndpointTemplates = getEndpointTemplatesFromEnv()
+	service.NotifRemoveTemplate = os.Getenv("DF_REMOVE_TEMPLATE")
+	service.RemoveBodyTemplate = os.Getenv("DF_REMOVE_BODY_TEMPLATE")
+	service.RejectDuplicateKeys = getBoolValue(false, "DF_REJECT_DUPLICATE_KEYS")
+	service.ManagedByLabel = os.Getenv("DF_MANAGED_BY_LABEL")
+	service.RequireSecret = os.Getenv("DF_REQUIRE_SECRET")
+	service.ShardIndex = getValue(0, "DF_SHARD_INDEX")
+	service.ShardTotal = getValue(0, "DF_SHARD_TOTAL")
+	if service.ShardTotal > 1 && (service.ShardIndex < 0 || service.ShardIndex >= service.ShardTotal) {
+		logPrintf("WARNING: DF_SHARD_INDEX must be between 0 and %d. No services will be handled by this instance", service.ShardTotal-1)
+	}
+	service.LabelPrefix = getStringValue(service.LabelPrefix, "DF_LABEL_PREFIX")
+	service.NotifyLabel = getStringValue(service.LabelPrefix+"notify", "DF_NOTIFY_LABEL")
+	if len(os.Getenv("DF_NOTIFY_LABELS_ANY")) > 0 {
+		service.NotifyLabelsAny = strings.Split(os.Getenv("DF_NOTIFY_LABELS_ANY"), ",")
+	}
+	service.MaxLabelValueLength = getValue(0, "DF_MAX_LABEL_VALUE_LENGTH")
+	service.OversizedLabelAction = getStringValue("truncate", "DF_OVERSIZED_LABEL_ACTION")
+	service.ValidatePorts = os.Getenv("DF_VALIDATE_PORTS")
+	service.TraceHeader = os.Getenv("DF_TRACE_HEADER")
+	service.TraceLabel = getStringValue(service.LabelPrefix+"traceId", "DF_TRACE_LABEL")
+	service.NotifyTimeout = time.Second * time.Duration(getValue(0, "DF_NOTIFY_TIMEOUT"))
+	service.CycleRetryBudget = time.Second * time.Duration(getValue(0, "DF_CYCLE_RETRY_BUDGET"))
+	service.NotifyCycleTimeout = time.Second * time.Duration(getValue(0, "DF_NOTIFY_CYCLE_TIMEOUT"))
+	service.RetryIntervalRefused = getValue(-1, "DF_RETRY_INTERVAL_REFUSED")
+	service.RetryIntervalTimeout = getValue(-1, "DF_RETRY_INTERVAL_TIMEOUT")
+	service.EnrichUrl = os.Getenv("DF_ENRICH_URL")
+	service.EnrichTimeout = time.Second * time.Duration(getValue(5, "DF_ENRICH_TIMEOUT"))
+	service.NotifStuckServiceUrl = os.Getenv("DF_NOTIF_STUCK_SERVICE_URL")
+	service.StuckTaskTimeout = time.Second * time.Duration(getValue(0, "DF_STUCK_TASK_TIMEOUT"))
+	service.NotifHealthServiceUrl = os.Getenv("DF_NOTIF_HEALTH_URL")
+	service.IncludeNodes = getBoolValue(false, "DF_INCLUDE_NODES")
+	service.NotifCreateFailureUrl = os.Getenv("DF_NOTIF_CREATE_FAILURE_URL")
+	service.CreateFailureWindow = time.Second * time.Duration(getValue(60, "DF_CREATE_FAILURE_WINDOW"))
+	service.EndpointConcurrency = getValue(1, "DF_ENDPOINT_CONCURRENCY")
+	service.NotifyStagger = time.Millisecond * time.Duration(getValue(0, "DF_NOTIFY_STAGGER"))
+	service.NotifyHttp2 = getBoolValue(false, "DF_NOTIFY_HTTP2")
+	service.NotifyFormat = os.Getenv("DF_NOTIFY_FORMAT")
+	service.NotifyMethod = os.Getenv("DF_NOTIFY_METHOD")
+	service.NotifyAutoThreshold = getValue(2048, "DF_NOTIFY_AUTO_THRESHOLD")
+	service.EndpointMethods = getEndpointMethods(os.Getenv("DF_ENDPOINT_METHODS"))
+	service.NotifyInsecure = getBoolValue(false, "DF_NOTIFY_INSECURE")
+	if service.NotifyInsecure {
+		logPrintf("WARNING: DF_NOTIFY_INSECURE is enabled. TLS certificates of notification receivers will NOT be verified. Do not use it in production!")
+	}
+	service.LogLevel = os.Getenv("DF_LOG_LEVEL")
+	service.WriteBackStatus = os.Getenv("DF_WRITE_BACK_STATUS")
+	service.StateFile = os.Getenv("DF_STATE_FILE")
+	service.StateSourceUrl = os.Getenv("DF_STATE_SOURCE_URL")
+	service.ReconcileSourceUrl = os.Getenv("DF_RECONCILE_SOURCE_URL")
+	service.TrackBy = getStringValue("name", "DF_TRACK_BY")
+	service.ResyncScope = getStringValue("creates", "DF_RESYNC_SCOPE")
+	service.StartupGrace = time.Second * time.Duration(getValue(0, "DF_STARTUP_GRACE"))
+	service.LastSpecs = NewSpecCache(getValue(1000, "DF_SPEC_CACHE_SIZE"))
+	service.RemovalHistory = NewRemovalHistory(
+		getValue(1000, "DF_REMOVAL_HISTORY_MAX"),
+		time.Second*time.Duration(getValue(86400, "DF_REMOVAL_HISTORY_TTL")),
+	)
+	service.ReplaceWindow = time.Second * time.Duration(getValue(0, "DF_REPLACE_WINDOW"))
+	service.RemoveWindow = os.Getenv("DF_REMOVE_WINDOW")
+	service.NotificationCounts = NewNotificationCounts(getValue(1000, "DF_NOTIFICATION_COUNTS_MAX"))
+	service.Receipts = NewReceipts(getValue(1000, "DF_RECEIPTS_MAX"))
+	service.SettleDelay = time.Second * time.Duration(getValue(0, "DF_SETTLE_DELAY"))
+	service.FlapThreshold = getValue(0, "DF_FLAP_THRESHOLD")
+	service.FlapWindow = time.Second * time.Duration(getValue(300, "DF_FLAP_WINDOW"))
+	service.TransformUrl = os.Getenv("DF_TRANSFORM_URL")
+	service.TransformTimeout = time.Second * time.Duration(getValue(5, "DF_TRANSFORM_TIMEOUT"))
+	service.TransformFailOpen = getBoolValue(true, "DF_TRANSFORM_FAIL_OPEN")
+	if len(os.Getenv("DF_LABEL_ORDER")) > 0 {
+		service.LabelOrder = strings.Split(os.Getenv("DF_LABEL_ORDER"), ",")
+	}
+	if len(os.Getenv("DF_UPDATE_WATCH_FIELDS")) > 0 {
+		service.UpdateWatchFields = strings.Split(os.Getenv("DF_UPDATE_WATCH_FIELDS"), ",")
+	}
+	if len(os.Getenv("DF_PROVENANCE_LABELS")) > 0 {
+		service.ProvenanceLabels = strings.Split(os.Getenv("DF_PROVENANCE_LABELS"), ",")
+	}
+	if len(os.Getenv("DF_LOG_REDACT_LABELS")) > 0 {
+		service.RedactLabels = strings.Split(os.Getenv("DF_LOG_REDACT_LABELS"), ",")
+	}
+	if len(os.Getenv("DF_DECODE_LABELS")) > 0 {
+		service.DecodeLabels = strings.Split(os.Getenv("DF_DECODE_LABELS"), ",")
 	}
+	return service
 }
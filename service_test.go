@@ -1,16 +1,21 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/swarm"
 	"github.com/docker/docker/client"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -71,6 +76,17 @@ func (s *ServiceTestSuite) Test_GetServices_ReturnsError_WhenServiceListFails()
 	s.Error(err)
 }
 
+func (s *ServiceTestSuite) Test_GetServices_StoresDaemonId() {
+	dockerSrv := s.newFakeDockerServer([]swarm.Service{}, []swarm.Task{}, "cluster-1")
+	defer func() { dockerSrv.Close() }()
+	service := NewService(s.getDockerHost(dockerSrv), "", "")
+
+	_, err := service.GetServices()
+
+	s.NoError(err)
+	s.Equal("cluster-1", service.DaemonId)
+}
+
 // GetNewServices
 
 func (s *ServiceTestSuite) Test_GetNewServices_ReturnsAllServices_WhenExecutedForTheFirstTime() {
@@ -104,6 +120,278 @@ func (s *ServiceTestSuite) Test_GetNewServices_AddsServices() {
 	s.Contains(service.Services, "util-1")
 }
 
+func (s *ServiceTestSuite) Test_GetNewServices_ReturnsServicesWithDuplicateKeys() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	serviceLastCreatedAt = time.Time{}
+	services := []swarm.Service{
+		s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true", "com.df.serviceName": "demo"}),
+		s.getSwarmService("go-demo-2", map[string]string{"com.df.notify": "true", "com.df.serviceName": "demo"}),
+	}
+	msg := ""
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {
+		msg += fmt.Sprintf(format, v...)
+	}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(2, len(actual))
+	s.Contains(msg, "Services go-demo and go-demo-2 produce the same notification key demo")
+}
+
+func (s *ServiceTestSuite) Test_GetNewServices_RejectsServicesWithDuplicateKeys_WhenRejectDuplicateKeysIsTrue() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.RejectDuplicateKeys = true
+	serviceLastCreatedAt = time.Time{}
+	services := []swarm.Service{
+		s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true", "com.df.serviceName": "demo"}),
+		s.getSwarmService("go-demo-2", map[string]string{"com.df.notify": "true", "com.df.serviceName": "demo"}),
+	}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(1, len(actual))
+	s.Equal("go-demo", actual[0].Spec.Name)
+	s.Contains(service.Services, "go-demo")
+	s.NotContains(service.Services, "go-demo-2")
+}
+
+func (s *ServiceTestSuite) Test_GetNewServices_UsesKeyMapperToDetectDuplicateKeys() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.RejectDuplicateKeys = true
+	service.KeyMapper = func(s swarm.Service) string {
+		return s.Spec.Labels["com.acme.route"]
+	}
+	serviceLastCreatedAt = time.Time{}
+	services := []swarm.Service{
+		s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true", "com.df.serviceName": "demo", "com.acme.route": "blue"}),
+		s.getSwarmService("go-demo-2", map[string]string{"com.df.notify": "true", "com.df.serviceName": "demo", "com.acme.route": "green"}),
+		s.getSwarmService("go-demo-3", map[string]string{"com.df.notify": "true", "com.df.serviceName": "demo-3", "com.acme.route": "blue"}),
+	}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(2, len(actual))
+	s.Contains(service.Services, "go-demo")
+	s.Contains(service.Services, "go-demo-2")
+	s.NotContains(service.Services, "go-demo-3")
+}
+
+func (s *ServiceTestSuite) Test_GetNewServices_ReturnsManagedServices_WhenManagedByLabelIsSet() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.ManagedByLabel = "com.acme.managed-by=terraform"
+	serviceLastCreatedAt = time.Time{}
+	services := []swarm.Service{
+		s.getSwarmService("managed", map[string]string{"com.df.notify": "true", "com.acme.managed-by": "terraform"}),
+		s.getSwarmService("other-manager", map[string]string{"com.df.notify": "true", "com.acme.managed-by": "ansible"}),
+		s.getSwarmService("ad-hoc", map[string]string{"com.df.notify": "true"}),
+	}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(1, len(actual))
+	s.Equal("managed", actual[0].Spec.Name)
+	s.NotContains(service.Services, "other-manager")
+	s.NotContains(service.Services, "ad-hoc")
+}
+
+func (s *ServiceTestSuite) Test_GetNewServices_ReturnsServicesWithManagedByKey_WhenManagedByLabelHasNoValue() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.ManagedByLabel = "com.acme.managed-by"
+	serviceLastCreatedAt = time.Time{}
+	services := []swarm.Service{
+		s.getSwarmService("managed", map[string]string{"com.df.notify": "true", "com.acme.managed-by": "terraform"}),
+		s.getSwarmService("ad-hoc", map[string]string{"com.df.notify": "true"}),
+	}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(1, len(actual))
+	s.Equal("managed", actual[0].Spec.Name)
+}
+
+func (s *ServiceTestSuite) Test_GetNewServices_UsesNotifyLabel_WhenCustomPrefixIsSet() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.LabelPrefix = "com.acme."
+	service.NotifyLabel = "com.acme.route"
+	serviceLastCreatedAt = time.Time{}
+	services := []swarm.Service{
+		s.getSwarmService("go-demo", map[string]string{"com.acme.route": "true"}),
+		s.getSwarmService("go-demo-2", map[string]string{"com.df.notify": "true"}),
+	}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(1, len(actual))
+	s.Equal("go-demo", actual[0].Spec.Name)
+	s.NotContains(service.Services, "go-demo-2")
+}
+
+func (s *ServiceTestSuite) Test_GetNewServices_ReturnsServices_WhenNotifyLabelIsOnlyInTaskTemplate() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{})
+	srv.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{Labels: map[string]string{"com.df.notify": "true"}}
+
+	actual, _ := service.GetNewServices([]swarm.Service{srv})
+
+	s.Equal(1, len(actual))
+	s.Equal("go-demo", actual[0].Spec.Name)
+}
+
+func (s *ServiceTestSuite) Test_GetNewServices_RejectsDuplicateKeys_WhenKeyIsOnlyInTaskTemplate() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.RejectDuplicateKeys = true
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true", "com.df.serviceName": "demo"})
+	other := s.getSwarmService("go-demo-2", map[string]string{"com.df.notify": "true"})
+	other.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{Labels: map[string]string{"com.df.serviceName": "demo"}}
+
+	actual, _ := service.GetNewServices([]swarm.Service{srv, other})
+
+	s.Equal(1, len(actual))
+	s.Equal("go-demo", actual[0].Spec.Name)
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesCreate_UsesTargetFromTaskTemplate() {
+	actualPath := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualPath = r.URL.Path
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL+"/default", "")
+	service.Targets = map[string]Target{"internal": {CreateUrl: httpSrv.URL + "/internal"}}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	srv.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{Labels: map[string]string{"com.df.target": "internal"}}
+
+	service.NotifyServicesCreate([]swarm.Service{srv}, 1, 0)
+
+	s.Equal("/internal", actualPath)
+}
+
+func (s *ServiceTestSuite) Test_GetNewServices_ReturnsServicesWithAnyOfTheNotifyLabels() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyLabelsAny = []string{"com.df.notify", "com.acme.route"}
+	serviceLastCreatedAt = time.Time{}
+	services := []swarm.Service{
+		s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"}),
+		s.getSwarmService("go-demo-2", map[string]string{"com.acme.route": "true"}),
+		s.getSwarmService("go-demo-3", map[string]string{"com.acme.other": "true"}),
+	}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(2, len(actual))
+	s.Equal("go-demo", actual[0].Spec.Name)
+	s.Equal("go-demo-2", actual[1].Spec.Name)
+	s.NotContains(service.Services, "go-demo-3")
+}
+
+func (s *ServiceTestSuite) Test_GetNewServices_ReturnsServicesWithNotifyLabel_WhenNotifyLabelsAnyDoesNotContainIt() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyLabelsAny = []string{"com.acme.route"}
+	serviceLastCreatedAt = time.Time{}
+	services := []swarm.Service{
+		s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"}),
+		s.getSwarmService("go-demo-2", map[string]string{"com.acme.route": "true"}),
+	}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(2, len(actual))
+}
+
+func (s *ServiceTestSuite) Test_GetNewServices_TracksRecentlyCreatedServices_WhenCreateFailureUrlIsSet() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifCreateFailureUrl = "http://alerts/failed"
+	serviceLastCreatedAt = time.Time{}
+	old := s.getSwarmService("old", map[string]string{"com.df.notify": "true"})
+	old.Meta.CreatedAt = time.Now().Add(-time.Hour)
+	services := []swarm.Service{
+		s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"}),
+		old,
+	}
+
+	service.GetNewServices(services)
+
+	s.Contains(service.CreatedServices, "go-demo")
+	s.NotContains(service.CreatedServices, "old")
+}
+
+func (s *ServiceTestSuite) Test_GetNewServices_ReturnsOnlyServicesWithRequiredSecret_WhenRequireSecretIsSet() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.RequireSecret = "tls-cert"
+	serviceLastCreatedAt = time.Time{}
+	withSecret := s.getSwarmService("with-secret", map[string]string{"com.df.notify": "true"})
+	withSecret.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{
+		Secrets: []*swarm.SecretReference{{SecretName: "db-password"}, {SecretName: "tls-cert"}},
+	}
+	otherSecret := s.getSwarmService("other-secret", map[string]string{"com.df.notify": "true"})
+	otherSecret.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{
+		Secrets: []*swarm.SecretReference{{SecretName: "db-password"}},
+	}
+	withoutSecret := s.getSwarmService("without-secret", map[string]string{"com.df.notify": "true"})
+
+	actual, _ := service.GetNewServices([]swarm.Service{withSecret, otherSecret, withoutSecret})
+
+	s.Equal(1, len(actual))
+	s.Equal("with-secret", actual[0].Spec.Name)
+}
+
+func (s *ServiceTestSuite) Test_GetNewServices_ReturnsServicesWithoutSecrets_WhenRequireSecretIsNotSet() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	serviceLastCreatedAt = time.Time{}
+
+	actual, _ := service.GetNewServices([]swarm.Service{s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})})
+
+	s.Equal(1, len(actual))
+}
+
+func (s *ServiceTestSuite) Test_GetNewServices_ObservesLabelCardinality() {
+	metricsOrig := metrics
+	defer func() { metrics = metricsOrig }()
+	metrics = NewMetrics()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	serviceLastCreatedAt = time.Time{}
+	services := []swarm.Service{
+		s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true", "com.df.servicePath": "/demo"}),
+		s.getSwarmService("go-demo-2", map[string]string{"com.df.notify": "true", "com.df.port": "8080 "}),
+		s.getSwarmService("util", map[string]string{"com.df.distribute": "true"}),
+	}
+
+	service.GetNewServices(services)
+
+	s.Equal(4, metrics.GetLabelKeys())
+	s.Equal(1, metrics.GetMalformedLabelValues())
+}
+
+func (s *ServiceTestSuite) Test_GetNewServices_ReconcilesServices_WhenDaemonIdChanges() {
+	srv1 := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	srv2 := s.getSwarmService("go-demo-2", map[string]string{"com.df.notify": "true"})
+	dockerSrv := s.newFakeDockerServer([]swarm.Service{srv1}, []swarm.Task{}, "cluster-1")
+	service := NewService(s.getDockerHost(dockerSrv), "", "")
+	serviceLastCreatedAt = time.Time{}
+	services, _ := service.GetServices()
+	service.GetNewServices(services)
+	dockerSrv.Close()
+	// The restarted daemon reports the same services with new creation timestamps
+	srv1.Meta.CreatedAt = time.Now().Add(time.Hour)
+	srv2.Meta.CreatedAt = time.Now().Add(time.Hour)
+	dockerSrv = s.newFakeDockerServer([]swarm.Service{srv1, srv2}, []swarm.Task{}, "cluster-2")
+	defer func() { dockerSrv.Close() }()
+	service.Host = s.getDockerHost(dockerSrv)
+
+	services, _ = service.GetServices()
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(1, len(actual))
+	s.Equal("go-demo-2", actual[0].Spec.Name)
+	s.Equal("cluster-2", service.DaemonId)
+	s.Equal(srv2.Meta.CreatedAt.Unix(), serviceLastCreatedAt.Unix())
+	s.False(service.daemonChanged)
+}
+
 // GetRemovedServices
 
 func (s *ServiceTestSuite) Test_GetRemovedServices_ReturnsNamesOfRemovedServices() {
@@ -119,6 +407,263 @@ func (s *ServiceTestSuite) Test_GetRemovedServices_ReturnsNamesOfRemovedServices
 	s.Contains(actual, "removed-service-2")
 }
 
+func (s *ServiceTestSuite) Test_GetRemovedServices_ReturnsServices_WhenNotifyLabelIsDropped() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.Services["go-demo"] = true
+	services := []swarm.Service{s.getSwarmService("go-demo", map[string]string{})}
+
+	actual := service.GetRemovedServices(services)
+
+	s.Equal([]string{"go-demo"}, actual)
+	s.Equal("labelDropped", service.RemovalReasons["go-demo"])
+}
+
+func (s *ServiceTestSuite) Test_GetRemovedServices_ReturnsServices_WhenScaledToZero() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.Services["go-demo"] = true
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	replicas := uint64(0)
+	srv.Spec.Mode.Replicated = &swarm.ReplicatedService{Replicas: &replicas}
+
+	actual := service.GetRemovedServices([]swarm.Service{srv})
+
+	s.Equal([]string{"go-demo"}, actual)
+	s.Equal("scaledToZero", service.RemovalReasons["go-demo"])
+}
+
+func (s *ServiceTestSuite) Test_GetRemovedServices_SetsReasonToRemoved_WhenServiceDoesNotExist() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.Services["go-demo"] = true
+
+	actual := service.GetRemovedServices([]swarm.Service{})
+
+	s.Equal([]string{"go-demo"}, actual)
+	s.Equal("removed", service.RemovalReasons["go-demo"])
+}
+
+func (s *ServiceTestSuite) Test_GetNewServices_ReturnsServices_WhenInactiveServicesAreScaledUp() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL)
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	replicas := uint64(0)
+	srv.Spec.Mode.Replicated = &swarm.ReplicatedService{Replicas: &replicas}
+	service.GetNewServices([]swarm.Service{srv})
+	service.NotifyServicesRemove(service.GetRemovedServices([]swarm.Service{srv}), 1, 0)
+	s.Equal(0, len(service.Services))
+
+	replicas = 2
+	actual, _ := service.GetNewServices([]swarm.Service{srv})
+
+	s.Equal(1, len(actual))
+	s.Contains(service.Services, "go-demo")
+}
+
+// GetUpdatedServices
+
+func (s *ServiceTestSuite) Test_GetUpdatedServices_ReturnsServices_WhenForceUpdateChanges() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	service.GetNewServices([]swarm.Service{srv})
+	srv.Spec.TaskTemplate.ForceUpdate = srv.Spec.TaskTemplate.ForceUpdate + 1
+
+	actual := service.GetUpdatedServices([]swarm.Service{srv})
+
+	s.Equal(1, len(actual))
+	s.Equal(uint64(1), service.ServicesCache["go-demo"].Spec.TaskTemplate.ForceUpdate)
+	s.Equal(0, len(service.GetUpdatedServices([]swarm.Service{srv})))
+}
+
+func (s *ServiceTestSuite) Test_GetUpdatedServices_ReturnsServices_WhenRestartPolicyChanges() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	service.GetNewServices([]swarm.Service{srv})
+	srv.Spec.TaskTemplate.RestartPolicy = &swarm.RestartPolicy{Condition: swarm.RestartPolicyConditionOnFailure}
+
+	actual := service.GetUpdatedServices([]swarm.Service{srv})
+
+	s.Equal(1, len(actual))
+}
+
+func (s *ServiceTestSuite) Test_GetUpdatedServices_ReturnsServices_WhenPlacementPreferencesChange() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	srv.Spec.TaskTemplate.Placement = &swarm.Placement{
+		Preferences: []swarm.PlacementPreference{{Spread: &swarm.SpreadOver{SpreadDescriptor: "node.labels.az"}}},
+	}
+	service.GetNewServices([]swarm.Service{srv})
+	updated := srv
+	updated.Spec.TaskTemplate.Placement = &swarm.Placement{
+		Preferences: []swarm.PlacementPreference{{Spread: &swarm.SpreadOver{SpreadDescriptor: "node.labels.rack"}}},
+	}
+
+	actual := service.GetUpdatedServices([]swarm.Service{updated})
+
+	s.Equal(1, len(actual))
+}
+
+func (s *ServiceTestSuite) Test_GetUpdatedServices_DoesNotReturnServices_WhenPlacementChangesFromNilToEmpty() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	service.GetNewServices([]swarm.Service{srv})
+	srv.Spec.TaskTemplate.Placement = &swarm.Placement{}
+
+	actual := service.GetUpdatedServices([]swarm.Service{srv})
+
+	s.Equal(0, len(actual))
+}
+
+func (s *ServiceTestSuite) Test_GetUpdatedServices_ReturnsServices_WhenSecretReferencesChange() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	srv.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{}
+	service.GetNewServices([]swarm.Service{srv})
+	added := srv
+	added.Version.Index = 2
+	added.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{
+		Secrets: []*swarm.SecretReference{{SecretID: "cert-v1", SecretName: "cert"}},
+	}
+
+	actual := service.GetUpdatedServices([]swarm.Service{added})
+
+	s.Equal(1, len(actual))
+	s.Contains(service.PreviousServices, "go-demo")
+
+	delete(service.PreviousServices, "go-demo")
+	removed := srv
+	removed.Version.Index = 3
+
+	actual = service.GetUpdatedServices([]swarm.Service{removed})
+
+	s.Equal(1, len(actual))
+}
+
+func (s *ServiceTestSuite) Test_GetUpdatedServices_DoesNotReturnServices_WhenNothingChanged() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	service.GetNewServices([]swarm.Service{srv})
+
+	actual := service.GetUpdatedServices([]swarm.Service{srv})
+
+	s.Equal(0, len(actual))
+}
+
+func (s *ServiceTestSuite) Test_GetUpdatedServices_DoesNotReturnUntrackedServices() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	srv.Spec.TaskTemplate.ForceUpdate = 1
+
+	actual := service.GetUpdatedServices([]swarm.Service{srv})
+
+	s.Equal(0, len(actual))
+}
+
+func (s *ServiceTestSuite) Test_GetUpdatedServices_SkipsServices_WhenVersionIndexDidNotIncrease() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	srv.Version.Index = 10
+	service.GetNewServices([]swarm.Service{srv})
+	srv.Spec.TaskTemplate.ForceUpdate = 1
+
+	actual := service.GetUpdatedServices([]swarm.Service{srv})
+
+	s.Equal(0, len(actual))
+}
+
+func (s *ServiceTestSuite) Test_GetUpdatedServices_ProcessesServices_WhenVersionIndexIncreased() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	srv.Version.Index = 10
+	service.GetNewServices([]swarm.Service{srv})
+	srv.Version.Index = 11
+	srv.Spec.TaskTemplate.ForceUpdate = 1
+
+	actual := service.GetUpdatedServices([]swarm.Service{srv})
+
+	s.Equal(1, len(actual))
+	s.Equal(uint64(11), service.ServicesCache["go-demo"].Version.Index)
+}
+
+func (s *ServiceTestSuite) Test_GetUpdatedServices_DoesNotReturnServices_WhenSpecDigestDidNotChange() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	srv.Version.Index = 10
+	srv.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{Env: []string{"A=1", "B=2"}}
+	service.GetNewServices([]swarm.Service{srv})
+	srv.Version.Index = 11
+	srv.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{Env: []string{"B=2", "A=1"}}
+
+	actual := service.GetUpdatedServices([]swarm.Service{srv})
+
+	s.Equal(0, len(actual))
+	s.Equal(uint64(11), service.ServicesCache["go-demo"].Version.Index)
+}
+
+func (s *ServiceTestSuite) Test_GetUpdatedServices_StoresSpecDigest_WhenServiceIsUpdated() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	service.GetNewServices([]swarm.Service{srv})
+	srv.Spec.TaskTemplate.ForceUpdate = 1
+
+	service.GetUpdatedServices([]swarm.Service{srv})
+
+	s.Equal(getSpecDigest(srv, defaultUpdateWatchFields, "com.df."), service.SpecDigests["go-demo"])
+}
+
+func (s *ServiceTestSuite) Test_GetUpdatedServices_ReturnsServices_WhenWatchedFieldChanges() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.UpdateWatchFields = []string{"image", "replicas"}
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	srv.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{Image: "go-demo:1.0"}
+	service.GetNewServices([]swarm.Service{srv})
+	srv.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{Image: "go-demo:2.0"}
+
+	actual := service.GetUpdatedServices([]swarm.Service{srv})
+
+	s.Equal(1, len(actual))
+}
+
+func (s *ServiceTestSuite) Test_GetUpdatedServices_DoesNotReturnServices_WhenOnlyUnwatchedFieldsChange() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.UpdateWatchFields = []string{"image"}
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	srv.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{Image: "go-demo:1.0", Env: []string{"A=1"}}
+	service.GetNewServices([]swarm.Service{srv})
+	srv.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{Image: "go-demo:1.0", Env: []string{"A=2"}}
+	srv.Spec.TaskTemplate.ForceUpdate = 1
+
+	actual := service.GetUpdatedServices([]swarm.Service{srv})
+
+	s.Equal(0, len(actual))
+}
+
+func (s *ServiceTestSuite) Test_GetUpdatedServices_DoesNotPanic_WhenSubStructsAreNil() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	service.GetNewServices([]swarm.Service{srv})
+	srv.Spec.TaskTemplate = swarm.TaskSpec{}
+	srv.Spec.Mode = swarm.ServiceMode{}
+	srv.Spec.EndpointSpec = nil
+
+	s.NotPanics(func() { service.GetUpdatedServices([]swarm.Service{srv}) })
+}
+
 // NotifyServicesCreate
 
 func (s *ServiceTestSuite) Test_NotifyServicesCreate_SendsRequests() {
@@ -137,6 +682,127 @@ func (s *ServiceTestSuite) Test_NotifyServicesCreate_DoesNotSendRequest_WhenDfNo
 	s.verifyNotifyServiceCreate(labels, false, "")
 }
 
+func (s *ServiceTestSuite) Test_NotifyServicesCreate_SendsRequests_WhenCustomPrefixIsSet() {
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	service.LabelPrefix = "com.acme."
+	service.NotifyLabel = "com.acme.route"
+	labels := map[string]string{
+		"com.acme.route":       "true",
+		"com.acme.servicePath": "/demo",
+		"com.df.distribute":    "true",
+	}
+
+	err := service.NotifyServicesCreate(s.getSwarmServices(labels), 1, 0)
+
+	s.NoError(err)
+	s.Equal(fmt.Sprintf("serviceName=%s&servicePath=/demo", s.serviceName), actualQuery)
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesCreate_DoesNotSendRequest_WhenCustomNotifyLabelIsNotDefined() {
+	actualSent := false
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualSent = true
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	service.LabelPrefix = "com.acme."
+	service.NotifyLabel = "com.acme.route"
+
+	service.NotifyServicesCreate(s.getSwarmServices(map[string]string{"com.df.notify": "true"}), 1, 0)
+
+	s.False(actualSent)
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesCreate_SendsLabelsInLabelOrder() {
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	service.LabelOrder = []string{"servicePath", "port"}
+	services := s.getSwarmServices(map[string]string{
+		"com.df.notify":      "true",
+		"com.df.distribute":  "true",
+		"com.df.port":        "8080",
+		"com.df.servicePath": "/demo",
+		"com.df.aclName":     "demo",
+	})
+
+	err := service.NotifyServicesCreate(services, 1, 0)
+
+	s.NoError(err)
+	s.Equal(fmt.Sprintf("serviceName=%s&servicePath=/demo&port=8080&aclName=demo&distribute=true", s.serviceName), actualQuery)
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesCreate_SendsTaskTemplateLabels() {
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	services := s.getSwarmServices(map[string]string{"com.df.servicePath": "/service"})
+	services[0].Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{
+		Labels: map[string]string{"com.df.notify": "true", "com.df.port": "8080", "com.df.servicePath": "/task"},
+	}
+
+	err := service.NotifyServicesCreate(services, 1, 0)
+
+	s.NoError(err)
+	s.Equal(fmt.Sprintf("serviceName=%s&port=8080&servicePath=/service", s.serviceName), actualQuery)
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesCreate_SendsLabelsProducedByLabelMapper() {
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	service.LabelMapper = func(srv swarm.Service) map[string]string {
+		return map[string]string{
+			"routingKey": "tenant-" + srv.Spec.Labels["tenant"],
+			"port":       "8080",
+		}
+	}
+	labels := map[string]string{
+		"com.df.notify":     "true",
+		"com.df.distribute": "true",
+		"tenant":            "acme",
+	}
+
+	err := service.NotifyServicesCreate(s.getSwarmServices(labels), 1, 0)
+
+	s.NoError(err)
+	s.Equal(fmt.Sprintf("serviceName=%s&port=8080&routingKey=tenant-acme", s.serviceName), actualQuery)
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesCreate_SendsRequestsToAllEndpoints() {
+	mu := sync.Mutex{}
+	actualHosts := map[string]bool{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		actualHosts[r.Host] = true
+	})
+	srvA := httptest.NewServer(handler)
+	defer srvA.Close()
+	srvB := httptest.NewServer(handler)
+	defer srvB.Close()
+	service := NewService("unix:///var/run/docker.sock", srvA.URL+","+srvB.URL, "")
+
+	err := service.NotifyServicesCreate(s.getSwarmServices(map[string]string{"com.df.notify": "true"}), 1, 0)
+
+	s.NoError(err)
+	s.Equal(2, len(actualHosts))
+}
+
 func (s *ServiceTestSuite) Test_NotifyServicesCreate_ReturnsError_WhenHttpStatusIsNot200() {
 	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
@@ -174,10 +840,166 @@ func (s *ServiceTestSuite) Test_NotifyServicesCreate_RetriesRequests() {
 		attempt = attempt + 1
 	}))
 
-	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
-	err := service.NotifyServicesCreate(s.getSwarmServices(labels), 3, 0)
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	err := service.NotifyServicesCreate(s.getSwarmServices(labels), 3, 0)
+
+	s.NoError(err)
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesCreate_StoresReceipt_WhenResponseContainsReceiptId() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Receipt-Id", "receipt-123")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer func() { httpSrv.Close() }()
+	labels := map[string]string{"com.df.notify": "true"}
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+
+	service.NotifyServicesCreate(s.getSwarmServices(labels), 1, 0)
+
+	actual := service.GetReceipts()
+	s.Equal(1, len(actual))
+	s.Equal(s.serviceName, actual[0].ServiceName)
+	s.Equal("create", actual[0].Event)
+	s.Equal("receipt-123", actual[0].ReceiptId)
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesCreate_DoesNotStoreReceipt_WhenResponseDoesNotContainReceiptId() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer func() { httpSrv.Close() }()
+	labels := map[string]string{"com.df.notify": "true"}
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+
+	service.NotifyServicesCreate(s.getSwarmServices(labels), 1, 0)
+
+	s.Equal(0, len(service.GetReceipts()))
+}
+
+// NotifyServicesUpdate
+
+func (s *ServiceTestSuite) Test_NotifyServicesUpdate_SendsRequests() {
+	actualPath := ""
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualPath = r.URL.Path
+		actualQuery = r.URL.RawQuery
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer func() { httpSrv.Close() }()
+	labels := map[string]string{"com.df.notify": "true", "com.df.distribute": "true"}
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifUpdateServiceUrl = httpSrv.URL + "/v1/docker-flow-proxy/reconfigure"
+
+	err := service.NotifyServicesUpdate(s.getSwarmServices(labels), 1, 0)
+
+	s.NoError(err)
+	s.Equal("/v1/docker-flow-proxy/reconfigure", actualPath)
+	s.Equal(fmt.Sprintf("serviceName=%s&distribute=true", s.serviceName), actualQuery)
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesUpdate_SendsChangedEnvNamesWithoutValues_WhenEnvChanges() {
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifUpdateServiceUrl = httpSrv.URL
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	srv.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{Env: []string{"DB=go-demo-db", "TOKEN=old-secret"}}
+	service.GetNewServices([]swarm.Service{srv})
+	srv.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{Env: []string{"DB=go-demo-db", "TOKEN=new-secret"}}
+
+	updated := service.GetUpdatedServices([]swarm.Service{srv})
+	err := service.NotifyServicesUpdate(updated, 1, 0)
+
+	s.NoError(err)
+	s.Equal(1, len(updated))
+	s.Equal("serviceName=go-demo&changedFields=env&changedEnv=TOKEN", actualQuery)
+	s.NotContains(actualQuery, "secret")
+	s.NotContains(service.PreviousServices, "go-demo")
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesUpdate_SendsLabelChanges_WhenLabelsChange() {
+	actualLabelChanges := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualLabelChanges = r.URL.Query().Get("labelChanges")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifUpdateServiceUrl = httpSrv.URL
+	service.UpdateWatchFields = []string{"labels"}
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true", "com.df.port": "8080", "com.df.distribute": "true"})
+	service.GetNewServices([]swarm.Service{srv})
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true", "com.df.port": "9090", "com.df.servicePath": "/demo", "other": "label"}
+
+	updated := service.GetUpdatedServices([]swarm.Service{srv})
+	err := service.NotifyServicesUpdate(updated, 1, 0)
+
+	s.NoError(err)
+	s.JSONEq(`{"added":{"com.df.servicePath":"/demo"},"removed":["com.df.distribute"],"changed":{"com.df.port":"9090"}}`, actualLabelChanges)
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesUpdate_SendsLabelChanges_WhenUpdateWatchFieldsAreDefault() {
+	actualQuery := url.Values{}
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.Query()
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifUpdateServiceUrl = httpSrv.URL
+	serviceLastCreatedAt = time.Time{}
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true", "com.df.port": "8080"})
+	service.GetNewServices([]swarm.Service{srv})
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true", "com.df.port": "9090"}
+
+	updated := service.GetUpdatedServices([]swarm.Service{srv})
+	err := service.NotifyServicesUpdate(updated, 1, 0)
+
+	s.NoError(err)
+	s.Equal(1, len(updated))
+	s.Equal("labels", actualQuery.Get("changedFields"))
+	s.JSONEq(`{"changed":{"com.df.port":"9090"}}`, actualQuery.Get("labelChanges"))
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesUpdate_ReturnsError_WhenHttpStatusIsNot200() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer func() { httpSrv.Close() }()
+	labels := map[string]string{"com.df.notify": "true"}
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifUpdateServiceUrl = httpSrv.URL
+
+	err := service.NotifyServicesUpdate(s.getSwarmServices(labels), 1, 0)
+
+	s.Error(err)
+}
+
+// ForgetRemovedServices
+
+func (s *ServiceTestSuite) Test_ForgetRemovedServices_StopsTrackingServicesWithoutSendingRequests() {
+	requests := 0
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL)
+	service.Services["go-demo"] = true
+	service.ServicesCache["go-demo"] = s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+
+	service.ForgetRemovedServices(service.GetRemovedServices([]swarm.Service{}))
 
-	s.NoError(err)
+	s.Equal(0, requests)
+	s.NotContains(service.Services, "go-demo")
+	s.NotContains(service.ServicesCache, "go-demo")
+	s.Empty(service.GetRemovedServices([]swarm.Service{}))
 }
 
 // NotifyServicesRemove
@@ -186,6 +1008,48 @@ func (s *ServiceTestSuite) Test_NotifyServicesRemove_SendsRequests() {
 	s.verifyNotifyServiceRemove(true, fmt.Sprintf("serviceName=%s", s.removedServices[0]))
 }
 
+func (s *ServiceTestSuite) Test_NotifyServicesRemove_SendsReason() {
+	for _, reason := range []string{"removed", "labelDropped", "scaledToZero"} {
+		actualQuery := ""
+		httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actualQuery = r.URL.RawQuery
+			w.WriteHeader(http.StatusOK)
+		}))
+		service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL)
+		service.Services["go-demo"] = true
+		service.RemovalReasons["go-demo"] = reason
+
+		err := service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+		s.NoError(err)
+		s.Equal("serviceName=go-demo&reason="+reason, actualQuery)
+		s.NotContains(service.RemovalReasons, "go-demo")
+		httpSrv.Close()
+	}
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesRemove_SendsRequestsInRemoveOrder() {
+	mu := sync.Mutex{}
+	actualOrder := []string{}
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		actualOrder = append(actualOrder, r.URL.Query().Get("serviceName"))
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL)
+	service.EndpointConcurrency = 10
+	for name, order := range map[string]string{"db": "3", "backend": "2", "frontend": "1"} {
+		service.Services[name] = true
+		service.ServicesCache[name] = s.getSwarmService(name, map[string]string{"com.df.notify": "true", "com.df.removeOrder": order})
+	}
+
+	err := service.NotifyServicesRemove([]string{"db", "frontend", "backend"}, 1, 0)
+
+	s.NoError(err)
+	s.Equal([]string{"frontend", "backend", "db"}, actualOrder)
+}
+
 func (s *ServiceTestSuite) Test_NotifyServicesRemove_ReturnsError_WhenHttpStatusIsNot200() {
 	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
@@ -224,6 +1088,145 @@ func (s *ServiceTestSuite) Test_NotifyServicesRemove_RetriesRequests() {
 	s.NoError(err)
 }
 
+func (s *ServiceTestSuite) Test_NotifyServicesRemove_SendsRequestsUsingRemoveTemplate() {
+	actualPath := ""
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualPath = r.URL.Path
+		actualQuery = r.URL.RawQuery
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifRemoveTemplate = httpSrv.URL + `/v1/remove/{{.ServiceName}}?path={{index .Labels "com.df.servicePath"}}`
+	service.Services[s.removedServices[0]] = true
+	service.ServicesCache[s.removedServices[0]] = swarm.Service{
+		Spec: swarm.ServiceSpec{
+			Annotations: swarm.Annotations{
+				Name:   s.removedServices[0],
+				Labels: map[string]string{"com.df.servicePath": "/demo"},
+			},
+		},
+	}
+
+	err := service.NotifyServicesRemove(s.removedServices, 1, 0)
+
+	s.NoError(err)
+	s.Equal("/v1/remove/"+s.removedServices[0], actualPath)
+	s.Equal("path=/demo", actualQuery)
+	s.NotContains(service.ServicesCache, s.removedServices[0])
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesRemove_AppliesRemoveTemplateToEveryRemoveUrl() {
+	actualUris := []string{}
+	mu := sync.Mutex{}
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		actualUris = append(actualUris, r.URL.RequestURI())
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL+"/a,"+httpSrv.URL+"/b")
+	service.NotifRemoveTemplate = `{{.Url}}/{{.ServiceName}}`
+	service.Services["go-demo"] = true
+
+	err := service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	s.NoError(err)
+	s.ElementsMatch([]string{"/a/go-demo", "/b/go-demo"}, actualUris)
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesRemove_AppliesRemoveTemplateToTargetOfService() {
+	actualPath := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualPath = r.URL.Path
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL+"/default")
+	service.Targets = map[string]Target{"internal": {RemoveUrl: httpSrv.URL + "/internal"}}
+	service.NotifRemoveTemplate = `{{.Url}}/{{.ServiceName}}`
+	service.Services["go-demo"] = true
+	service.ServicesCache["go-demo"] = s.getSwarmService("go-demo", map[string]string{"com.df.target": "internal"})
+
+	err := service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	s.NoError(err)
+	s.Equal("/internal/go-demo", actualPath)
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesRemove_SendsRemoveBodyTemplateToEveryRemoveUrl() {
+	actualRequests := map[string]string{}
+	actualContentType := ""
+	mu := sync.Mutex{}
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := ioutil.ReadAll(r.Body)
+		actualRequests[r.Method+" "+r.URL.RequestURI()] = string(body)
+		actualContentType = r.Header.Get("Content-Type")
+	}))
+	defer func() { httpSrv.Close() }()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL+"/a,"+httpSrv.URL+"/b")
+	service.RemoveBodyTemplate = `{"service":"{{.ServiceName}}","path":"{{index .Labels "com.df.servicePath"}}","url":"{{.Url}}"}`
+	service.Services["go-demo"] = true
+	service.ServicesCache["go-demo"] = s.getSwarmService("go-demo", map[string]string{"com.df.servicePath": "/demo"})
+
+	err := service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	s.NoError(err)
+	s.Equal(map[string]string{
+		"POST /a?serviceName=go-demo": `{"service":"go-demo","path":"/demo","url":"` + httpSrv.URL + `/a"}`,
+		"POST /b?serviceName=go-demo": `{"service":"go-demo","path":"/demo","url":"` + httpSrv.URL + `/b"}`,
+	}, actualRequests)
+	s.Equal("application/json", actualContentType)
+}
+
+func (s *ServiceTestSuite) Test_NotifyServicesRemove_ReturnsError_WhenRemoveTemplateIsInvalid() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifRemoveTemplate = "{{.ServiceName"
+
+	err := service.NotifyServicesRemove(s.removedServices, 1, 0)
+
+	s.Error(err)
+}
+
+// getRemoveGroups
+
+func (s *ServiceTestSuite) Test_GetRemoveGroups_GroupsServicesByRemoveOrder() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.ServicesCache["frontend"] = s.getSwarmService("frontend", map[string]string{"com.df.removeOrder": "1"})
+	service.ServicesCache["web"] = s.getSwarmService("web", map[string]string{"com.df.removeOrder": "1"})
+	service.ServicesCache["backend"] = s.getSwarmService("backend", map[string]string{"com.df.removeOrder": "2"})
+	service.ServicesCache["invalid"] = s.getSwarmService("invalid", map[string]string{"com.df.removeOrder": "first"})
+
+	actual := service.getRemoveGroups([]string{"backend", "web", "unlabeled", "frontend", "invalid"})
+
+	s.Equal([][]string{{"unlabeled", "invalid"}, {"web", "frontend"}, {"backend"}}, actual)
+}
+
+// ValidateTemplates
+
+func (s *ServiceTestSuite) Test_ValidateTemplates_ReturnsNil_WhenTemplatesAreValid() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifRemoveTemplate = "http://proxy/remove?serviceName={{.ServiceName}}"
+
+	s.NoError(service.ValidateTemplates())
+}
+
+func (s *ServiceTestSuite) Test_ValidateTemplates_ReturnsError_WhenRemoveTemplateIsInvalid() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifRemoveTemplate = "{{.ServiceName"
+
+	s.Error(service.ValidateTemplates())
+}
+
+func (s *ServiceTestSuite) Test_ValidateTemplates_ReturnsError_WhenRemoveBodyTemplateIsInvalid() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.RemoveBodyTemplate = "{{.ServiceName"
+
+	s.Error(service.ValidateTemplates())
+}
+
 // NewService
 
 func (s *ServiceTestSuite) Test_NewService_SetsHost() {
@@ -298,6 +1301,251 @@ func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsNotifRemoveServiceUrl() {
 	s.Equal(expected, service.NotifRemoveServiceUrl)
 }
 
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsNotifUpdateServiceUrl() {
+	url := os.Getenv("DF_NOTIF_UPDATE_SERVICE_URL")
+	defer func() { os.Setenv("DF_NOTIF_UPDATE_SERVICE_URL", url) }()
+	expected := "this-is-a-notification-url"
+	os.Setenv("DF_NOTIF_UPDATE_SERVICE_URL", expected)
+
+	service := NewServiceFromEnv()
+
+	s.Equal(expected, service.NotifUpdateServiceUrl)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsNotifUpdateServiceUrlToCreateUrl_WhenEnvIsNotPresent() {
+	url := os.Getenv("DF_NOTIF_UPDATE_SERVICE_URL")
+	createUrl := os.Getenv("DF_NOTIF_CREATE_SERVICE_URL")
+	defer func() {
+		os.Setenv("DF_NOTIF_UPDATE_SERVICE_URL", url)
+		os.Setenv("DF_NOTIF_CREATE_SERVICE_URL", createUrl)
+	}()
+	expected := "this-is-a-notification-url"
+	os.Unsetenv("DF_NOTIF_UPDATE_SERVICE_URL")
+	os.Setenv("DF_NOTIF_CREATE_SERVICE_URL", expected)
+
+	service := NewServiceFromEnv()
+
+	s.Equal(expected, service.NotifUpdateServiceUrl)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsNotifRemoveTemplate() {
+	tmpl := os.Getenv("DF_REMOVE_TEMPLATE")
+	defer func() { os.Setenv("DF_REMOVE_TEMPLATE", tmpl) }()
+	expected := "http://proxy/remove?serviceName={{.ServiceName}}"
+	os.Setenv("DF_REMOVE_TEMPLATE", expected)
+
+	service := NewServiceFromEnv()
+
+	s.Equal(expected, service.NotifRemoveTemplate)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsRemoveBodyTemplate() {
+	tmpl := os.Getenv("DF_REMOVE_BODY_TEMPLATE")
+	defer func() { os.Setenv("DF_REMOVE_BODY_TEMPLATE", tmpl) }()
+	expected := `{"serviceName":"{{.ServiceName}}"}`
+	os.Setenv("DF_REMOVE_BODY_TEMPLATE", expected)
+
+	service := NewServiceFromEnv()
+
+	s.Equal(expected, service.RemoveBodyTemplate)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsRejectDuplicateKeys() {
+	reject := os.Getenv("DF_REJECT_DUPLICATE_KEYS")
+	defer func() { os.Setenv("DF_REJECT_DUPLICATE_KEYS", reject) }()
+	os.Setenv("DF_REJECT_DUPLICATE_KEYS", "true")
+
+	service := NewServiceFromEnv()
+
+	s.True(service.RejectDuplicateKeys)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsManagedByLabel() {
+	label := os.Getenv("DF_MANAGED_BY_LABEL")
+	defer func() { os.Setenv("DF_MANAGED_BY_LABEL", label) }()
+	expected := "com.acme.managed-by=terraform"
+	os.Setenv("DF_MANAGED_BY_LABEL", expected)
+
+	service := NewServiceFromEnv()
+
+	s.Equal(expected, service.ManagedByLabel)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsLabelPrefixAndNotifyLabel() {
+	prefix := os.Getenv("DF_LABEL_PREFIX")
+	notifyLabel := os.Getenv("DF_NOTIFY_LABEL")
+	defer func() {
+		os.Setenv("DF_LABEL_PREFIX", prefix)
+		os.Setenv("DF_NOTIFY_LABEL", notifyLabel)
+	}()
+	os.Setenv("DF_LABEL_PREFIX", "com.acme.")
+	os.Setenv("DF_NOTIFY_LABEL", "com.acme.route")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("com.acme.", service.LabelPrefix)
+	s.Equal("com.acme.route", service.NotifyLabel)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsNotifyLabelFromPrefix_WhenNotifyLabelIsNotPresent() {
+	prefix := os.Getenv("DF_LABEL_PREFIX")
+	notifyLabel := os.Getenv("DF_NOTIFY_LABEL")
+	defer func() {
+		os.Setenv("DF_LABEL_PREFIX", prefix)
+		os.Setenv("DF_NOTIFY_LABEL", notifyLabel)
+	}()
+	os.Setenv("DF_LABEL_PREFIX", "com.acme.")
+	os.Unsetenv("DF_NOTIFY_LABEL")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("com.acme.notify", service.NotifyLabel)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsDefaultLabelPrefix_WhenEnvIsNotPresent() {
+	prefix := os.Getenv("DF_LABEL_PREFIX")
+	notifyLabel := os.Getenv("DF_NOTIFY_LABEL")
+	defer func() {
+		os.Setenv("DF_LABEL_PREFIX", prefix)
+		os.Setenv("DF_NOTIFY_LABEL", notifyLabel)
+	}()
+	os.Unsetenv("DF_LABEL_PREFIX")
+	os.Unsetenv("DF_NOTIFY_LABEL")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("com.df.", service.LabelPrefix)
+	s.Equal("com.df.notify", service.NotifyLabel)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsNotifyLabelsAny() {
+	labelsOrig := os.Getenv("DF_NOTIFY_LABELS_ANY")
+	defer func() { os.Setenv("DF_NOTIFY_LABELS_ANY", labelsOrig) }()
+	os.Setenv("DF_NOTIFY_LABELS_ANY", "com.df.notify,com.acme.route")
+
+	service := NewServiceFromEnv()
+
+	s.Equal([]string{"com.df.notify", "com.acme.route"}, service.NotifyLabelsAny)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsEnrichUrlAndTimeout() {
+	enrichUrl := os.Getenv("DF_ENRICH_URL")
+	enrichTimeout := os.Getenv("DF_ENRICH_TIMEOUT")
+	defer func() {
+		os.Setenv("DF_ENRICH_URL", enrichUrl)
+		os.Setenv("DF_ENRICH_TIMEOUT", enrichTimeout)
+	}()
+	os.Setenv("DF_ENRICH_URL", "http://metadata/services")
+	os.Setenv("DF_ENRICH_TIMEOUT", "2")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("http://metadata/services", service.EnrichUrl)
+	s.Equal(2*time.Second, service.EnrichTimeout)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsStuckTaskDetection() {
+	stuckUrl := os.Getenv("DF_NOTIF_STUCK_SERVICE_URL")
+	stuckTimeout := os.Getenv("DF_STUCK_TASK_TIMEOUT")
+	defer func() {
+		os.Setenv("DF_NOTIF_STUCK_SERVICE_URL", stuckUrl)
+		os.Setenv("DF_STUCK_TASK_TIMEOUT", stuckTimeout)
+	}()
+	os.Setenv("DF_NOTIF_STUCK_SERVICE_URL", "http://alerts/stuck")
+	os.Setenv("DF_STUCK_TASK_TIMEOUT", "120")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("http://alerts/stuck", service.NotifStuckServiceUrl)
+	s.Equal(120*time.Second, service.StuckTaskTimeout)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsIncludeNodes() {
+	includeNodes := os.Getenv("DF_INCLUDE_NODES")
+	defer func() { os.Setenv("DF_INCLUDE_NODES", includeNodes) }()
+	os.Setenv("DF_INCLUDE_NODES", "true")
+
+	service := NewServiceFromEnv()
+
+	s.True(service.IncludeNodes)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsCreateFailureDetection() {
+	failureUrl := os.Getenv("DF_NOTIF_CREATE_FAILURE_URL")
+	failureWindow := os.Getenv("DF_CREATE_FAILURE_WINDOW")
+	defer func() {
+		os.Setenv("DF_NOTIF_CREATE_FAILURE_URL", failureUrl)
+		os.Setenv("DF_CREATE_FAILURE_WINDOW", failureWindow)
+	}()
+	os.Setenv("DF_NOTIF_CREATE_FAILURE_URL", "http://alerts/failed")
+	os.Setenv("DF_CREATE_FAILURE_WINDOW", "300")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("http://alerts/failed", service.NotifCreateFailureUrl)
+	s.Equal(300*time.Second, service.CreateFailureWindow)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsLabelOrder() {
+	order := os.Getenv("DF_LABEL_ORDER")
+	defer func() { os.Setenv("DF_LABEL_ORDER", order) }()
+	os.Setenv("DF_LABEL_ORDER", "port,servicePath")
+
+	service := NewServiceFromEnv()
+
+	s.Equal([]string{"port", "servicePath"}, service.LabelOrder)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsUpdateWatchFields() {
+	fields := os.Getenv("DF_UPDATE_WATCH_FIELDS")
+	defer func() { os.Setenv("DF_UPDATE_WATCH_FIELDS", fields) }()
+	os.Setenv("DF_UPDATE_WATCH_FIELDS", "image,labels,replicas")
+
+	service := NewServiceFromEnv()
+
+	s.Equal([]string{"image", "labels", "replicas"}, service.UpdateWatchFields)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsDefaultUpdateWatchFields_WhenEnvIsNotPresent() {
+	fields := os.Getenv("DF_UPDATE_WATCH_FIELDS")
+	defer func() { os.Setenv("DF_UPDATE_WATCH_FIELDS", fields) }()
+	os.Unsetenv("DF_UPDATE_WATCH_FIELDS")
+
+	service := NewServiceFromEnv()
+
+	s.Equal([]string{"forceUpdate", "restartPolicy", "env", "labels", "placement", "secrets", "configs"}, service.UpdateWatchFields)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsRequireSecret() {
+	secret := os.Getenv("DF_REQUIRE_SECRET")
+	defer func() { os.Setenv("DF_REQUIRE_SECRET", secret) }()
+	os.Setenv("DF_REQUIRE_SECRET", "tls-cert")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("tls-cert", service.RequireSecret)
+}
+
+func (s *ServiceTestSuite) Test_NewServiceFromEnv_SetsRetryIntervalsPerErrorClass() {
+	refused := os.Getenv("DF_RETRY_INTERVAL_REFUSED")
+	timeout := os.Getenv("DF_RETRY_INTERVAL_TIMEOUT")
+	notifyTimeout := os.Getenv("DF_NOTIFY_TIMEOUT")
+	defer func() {
+		os.Setenv("DF_RETRY_INTERVAL_REFUSED", refused)
+		os.Setenv("DF_RETRY_INTERVAL_TIMEOUT", timeout)
+		os.Setenv("DF_NOTIFY_TIMEOUT", notifyTimeout)
+	}()
+	os.Setenv("DF_RETRY_INTERVAL_REFUSED", "1")
+	os.Setenv("DF_RETRY_INTERVAL_TIMEOUT", "30")
+	os.Setenv("DF_NOTIFY_TIMEOUT", "10")
+
+	service := NewServiceFromEnv()
+
+	s.Equal(1, service.RetryIntervalRefused)
+	s.Equal(30, service.RetryIntervalTimeout)
+	s.Equal(10*time.Second, service.NotifyTimeout)
+}
+
 // Util
 
 func (s *ServiceTestSuite) verifyNotifyServiceCreate(labels map[string]string, expectSent bool, expectQuery string) {
@@ -362,6 +1610,42 @@ func (s *ServiceTestSuite) verifyNotifyServiceRemove(expectSent bool, expectQuer
 	}
 }
 
+func (s *ServiceTestSuite) getSwarmService(name string, labels map[string]string) swarm.Service {
+	return swarm.Service{
+		Meta: swarm.Meta{
+			CreatedAt: time.Now(),
+		},
+		Spec: swarm.ServiceSpec{
+			Annotations: swarm.Annotations{
+				Name:   name,
+				Labels: labels,
+			},
+		},
+	}
+}
+
+func (s *ServiceTestSuite) newFakeDockerServer(services []swarm.Service, tasks []swarm.Task, clusterId string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/services"):
+			json.NewEncoder(w).Encode(services)
+		case strings.HasSuffix(r.URL.Path, "/tasks"):
+			json.NewEncoder(w).Encode(tasks)
+		case strings.HasSuffix(r.URL.Path, "/info"):
+			info := types.Info{ID: "daemon-id"}
+			info.Swarm.Cluster = &swarm.ClusterInfo{ID: clusterId}
+			json.NewEncoder(w).Encode(info)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func (s *ServiceTestSuite) getDockerHost(srv *httptest.Server) string {
+	return strings.Replace(srv.URL, "http://", "tcp://", 1)
+}
+
 func (s *ServiceTestSuite) getSwarmServices(labels map[string]string) []swarm.Service {
 	ann := swarm.Annotations{
 		Name:   s.serviceName,
@@ -424,16 +1708,89 @@ func (m *ServicerMock) GetNewServices(services []swarm.Service) ([]swarm.Service
 	return args.Get(0).([]swarm.Service), args.Error(1)
 }
 
+func (m *ServicerMock) GetUpdatedServices(services []swarm.Service) []swarm.Service {
+	args := m.Called(services)
+	return args.Get(0).([]swarm.Service)
+}
+
+func (m *ServicerMock) GetRemovedServices(services []swarm.Service) []string {
+	args := m.Called(services)
+	return args.Get(0).([]string)
+}
+
 func (m *ServicerMock) NotifyServicesCreate(services []swarm.Service, retries, interval int) error {
 	args := m.Called(services, retries, interval)
 	return args.Error(0)
 }
 
+func (m *ServicerMock) NotifyServicesUpdate(services []swarm.Service, retries, interval int) error {
+	args := m.Called(services, retries, interval)
+	return args.Error(0)
+}
+
 func (m *ServicerMock) NotifyServicesRemove(services []string, retries, interval int) error {
 	args := m.Called(services, retries, interval)
 	return args.Error(0)
 }
 
+func (m *ServicerMock) NotifyServicesStuck(services []swarm.Service, retries, interval int) error {
+	args := m.Called(services, retries, interval)
+	return args.Error(0)
+}
+
+func (m *ServicerMock) NotifyServicesHealth(services []swarm.Service, retries, interval int) error {
+	args := m.Called(services, retries, interval)
+	return args.Error(0)
+}
+
+func (m *ServicerMock) NotifyServicesCreateFailure(services []swarm.Service, retries, interval int) error {
+	args := m.Called(services, retries, interval)
+	return args.Error(0)
+}
+
+func (m *ServicerMock) CorrelateReplacements(newServices, updatedServices []swarm.Service, removedServices []string) ([]swarm.Service, []swarm.Service, []string) {
+	m.Called(newServices, updatedServices, removedServices)
+	return newServices, updatedServices, removedServices
+}
+
+func (m *ServicerMock) SettleChanges(services, newServices, updatedServices []swarm.Service) ([]swarm.Service, []swarm.Service) {
+	m.Called(services, newServices, updatedServices)
+	return newServices, updatedServices
+}
+
+func (m *ServicerMock) GetReceipts() []Receipt {
+	args := m.Called()
+	return args.Get(0).([]Receipt)
+}
+
+func (m *ServicerMock) GetNotificationCounts() []NotificationCount {
+	args := m.Called()
+	return args.Get(0).([]NotificationCount)
+}
+
+func (m *ServicerMock) ResendRemovals(retries, interval int) ([]string, error) {
+	args := m.Called(retries, interval)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *ServicerMock) StartCycle(retries, interval int) error {
+	args := m.Called(retries, interval)
+	return args.Error(0)
+}
+
+func (m *ServicerMock) ForgetRemovedServices(services []string) {
+	m.Called(services)
+}
+
+func (m *ServicerMock) EndCycle() {
+	m.Called()
+}
+
+func (m *ServicerMock) Reconcile(retries, interval int) (ReconcileResult, error) {
+	args := m.Called(retries, interval)
+	return args.Get(0).(ReconcileResult), args.Error(1)
+}
+
 func getServicerMock(skipMethod string) *ServicerMock {
 	mockObj := new(ServicerMock)
 	if !strings.EqualFold("GetServices", skipMethod) {
@@ -442,11 +1799,78 @@ func getServicerMock(skipMethod string) *ServicerMock {
 	if !strings.EqualFold("GetNewServices", skipMethod) {
 		mockObj.On("GetNewServices", mock.Anything).Return([]swarm.Service{}, nil)
 	}
+	if !strings.EqualFold("GetUpdatedServices", skipMethod) {
+		mockObj.On("GetUpdatedServices", mock.Anything).Return([]swarm.Service{})
+	}
+	if !strings.EqualFold("GetRemovedServices", skipMethod) {
+		mockObj.On("GetRemovedServices", mock.Anything).Return([]string{})
+	}
 	if !strings.EqualFold("NotifyServicesCreate", skipMethod) {
 		mockObj.On("NotifyServicesCreate", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	}
+	if !strings.EqualFold("NotifyServicesUpdate", skipMethod) {
+		mockObj.On("NotifyServicesUpdate", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	}
 	if !strings.EqualFold("NotifyServicesRemove", skipMethod) {
 		mockObj.On("NotifyServicesRemove", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	}
+	if !strings.EqualFold("NotifyServicesStuck", skipMethod) {
+		mockObj.On("NotifyServicesStuck", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	}
+	if !strings.EqualFold("NotifyServicesHealth", skipMethod) {
+		mockObj.On("NotifyServicesHealth", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	}
+	if !strings.EqualFold("NotifyServicesCreateFailure", skipMethod) {
+		mockObj.On("NotifyServicesCreateFailure", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	}
+	if !strings.EqualFold("CorrelateReplacements", skipMethod) {
+		mockObj.On("CorrelateReplacements", mock.Anything, mock.Anything, mock.Anything).Return()
+	}
+	if !strings.EqualFold("SettleChanges", skipMethod) {
+		mockObj.On("SettleChanges", mock.Anything, mock.Anything, mock.Anything).Return()
+	}
+	if !strings.EqualFold("GetReceipts", skipMethod) {
+		mockObj.On("GetReceipts").Return([]Receipt{})
+	}
+	if !strings.EqualFold("GetNotificationCounts", skipMethod) {
+		mockObj.On("GetNotificationCounts").Return([]NotificationCount{})
+	}
+	if !strings.EqualFold("StartCycle", skipMethod) {
+		mockObj.On("StartCycle", mock.Anything, mock.Anything).Return(nil)
+	}
+	if !strings.EqualFold("ForgetRemovedServices", skipMethod) {
+		mockObj.On("ForgetRemovedServices", mock.Anything).Return()
+	}
+	if !strings.EqualFold("EndCycle", skipMethod) {
+		mockObj.On("EndCycle").Return()
+	}
+	if !strings.EqualFold("Reconcile", skipMethod) {
+		mockObj.On("Reconcile", mock.Anything, mock.Anything).Return(ReconcileResult{}, nil)
+	}
+	if !strings.EqualFold("ResendRemovals", skipMethod) {
+		mockObj.On("ResendRemovals", mock.Anything, mock.Anything).Return([]string{}, nil)
+	}
 	return mockObj
 }
+
+// Benchmarks
+
+func BenchmarkGetRemovedServices(b *testing.B) {
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	services := []swarm.Service{}
+	for i := 0; i < 10000; i++ {
+		srv := swarm.Service{}
+		srv.Spec.Name = fmt.Sprintf("service-%d", i)
+		srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+		services = append(services, srv)
+		service.Services[srv.Spec.Name] = true
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		service.GetRemovedServices(services)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"sort"
+	"time"
+)
+
+type settlingService struct {
+	event       string
+	previous    swarm.Service
+	hasPrevious bool
+	changedAt   time.Time
+}
+
+func (m *Service) SettleChanges(services, newServices, updatedServices []swarm.Service) ([]swarm.Service, []swarm.Service) {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	if m.SettleDelay <= 0 {
+		return newServices, updatedServices
+	}
+	for _, s := range newServices {
+		m.holdChange(s, "create")
+	}
+	for _, s := range updatedServices {
+		m.holdChange(s, "update")
+	}
+	current := map[string]swarm.Service{}
+	for _, s := range services {
+		current[s.Spec.Name] = s
+	}
+	names := []string{}
+	for name := range m.settlingServices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	created := []swarm.Service{}
+	updated := []swarm.Service{}
+	for _, name := range names {
+		pending := m.settlingServices[name]
+		s, ok := current[name]
+		if !ok || !m.hasNotifyLabel(s) {
+			delete(m.settlingServices, name)
+			continue
+		}
+		if time.Since(pending.changedAt) < m.SettleDelay {
+			continue
+		}
+		delete(m.settlingServices, name)
+		// The latest spec is sent since it is the one that did not change during the delay
+		if pending.event == "create" {
+			created = append(created, s)
+			continue
+		}
+		if pending.hasPrevious {
+			m.PreviousServices[name] = pending.previous
+		}
+		updated = append(updated, s)
+	}
+	return created, updated
+}
+
+func (m *Service) holdChange(s swarm.Service, event string) {
+	pending, ok := m.settlingServices[s.Spec.Name]
+	if !ok {
+		pending = settlingService{event: event}
+		if previous, found := m.PreviousServices[s.Spec.Name]; found && event == "update" {
+			pending.previous = previous
+			pending.hasPrevious = true
+		}
+		logPrintf("Waiting %s for the service %s to settle before sending the %s notification", m.SettleDelay, s.Spec.Name, event)
+	}
+	pending.changedAt = time.Now()
+	m.settlingServices[s.Spec.Name] = pending
+}
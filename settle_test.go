@@ -0,0 +1,145 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+	"time"
+)
+
+type SettleTestSuite struct {
+	suite.Suite
+}
+
+func TestSettleUnitTestSuite(t *testing.T) {
+	s := new(SettleTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// SettleChanges
+
+func (s *SettleTestSuite) Test_SettleChanges_ReturnsInputs_WhenSettleDelayIsNotSet() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	created := []swarm.Service{s.getService("go-demo", "1")}
+	updated := []swarm.Service{s.getService("other", "1")}
+
+	actualCreated, actualUpdated := service.SettleChanges(append(created, updated...), created, updated)
+
+	s.Equal(created, actualCreated)
+	s.Equal(updated, actualUpdated)
+}
+
+func (s *SettleTestSuite) Test_SettleChanges_HoldsChanges_WhileSettleDelayIsOpen() {
+	service := s.getSettlingService()
+	srv := s.getService("go-demo", "1")
+
+	created, updated := service.SettleChanges([]swarm.Service{srv}, []swarm.Service{srv}, []swarm.Service{})
+
+	s.Empty(created)
+	s.Empty(updated)
+}
+
+func (s *SettleTestSuite) Test_SettleChanges_ReturnsService_WhenItDidNotChangeDuringSettleDelay() {
+	service := s.getSettlingService()
+	srv := s.getService("go-demo", "1")
+	service.SettleChanges([]swarm.Service{srv}, []swarm.Service{srv}, []swarm.Service{})
+	s.expireSettleDelay(service, "go-demo")
+
+	created, updated := service.SettleChanges([]swarm.Service{srv}, []swarm.Service{}, []swarm.Service{})
+
+	s.Equal([]swarm.Service{srv}, created)
+	s.Empty(updated)
+	s.NotContains(service.settlingServices, "go-demo")
+}
+
+func (s *SettleTestSuite) Test_SettleChanges_SendsSingleNotification_WhenServiceChangesAgainDuringSettleDelay() {
+	service := s.getSettlingService()
+	first := s.getService("go-demo", "1")
+	second := s.getService("go-demo", "2")
+	service.SettleChanges([]swarm.Service{first}, []swarm.Service{first}, []swarm.Service{})
+	s.expireSettleDelay(service, "go-demo")
+
+	created, updated := service.SettleChanges([]swarm.Service{second}, []swarm.Service{}, []swarm.Service{second})
+
+	s.Empty(created)
+	s.Empty(updated)
+
+	s.expireSettleDelay(service, "go-demo")
+	created, updated = service.SettleChanges([]swarm.Service{second}, []swarm.Service{}, []swarm.Service{})
+
+	s.Equal([]swarm.Service{second}, created)
+	s.Empty(updated)
+
+	created, updated = service.SettleChanges([]swarm.Service{second}, []swarm.Service{}, []swarm.Service{})
+
+	s.Empty(created)
+	s.Empty(updated)
+}
+
+func (s *SettleTestSuite) Test_SettleChanges_KeepsPreviousServiceOfTheFirstChange() {
+	service := s.getSettlingService()
+	original := s.getService("go-demo", "1")
+	first := s.getService("go-demo", "2")
+	second := s.getService("go-demo", "3")
+	service.PreviousServices["go-demo"] = original
+	service.SettleChanges([]swarm.Service{first}, []swarm.Service{}, []swarm.Service{first})
+	service.PreviousServices["go-demo"] = first
+	service.SettleChanges([]swarm.Service{second}, []swarm.Service{}, []swarm.Service{second})
+	s.expireSettleDelay(service, "go-demo")
+
+	_, updated := service.SettleChanges([]swarm.Service{second}, []swarm.Service{}, []swarm.Service{})
+
+	s.Equal([]swarm.Service{second}, updated)
+	s.Equal(original, service.PreviousServices["go-demo"])
+}
+
+func (s *SettleTestSuite) Test_SettleChanges_DropsService_WhenItIsRemovedDuringSettleDelay() {
+	service := s.getSettlingService()
+	srv := s.getService("go-demo", "1")
+	service.SettleChanges([]swarm.Service{srv}, []swarm.Service{srv}, []swarm.Service{})
+	s.expireSettleDelay(service, "go-demo")
+
+	created, _ := service.SettleChanges([]swarm.Service{}, []swarm.Service{}, []swarm.Service{})
+
+	s.Empty(created)
+	s.NotContains(service.settlingServices, "go-demo")
+}
+
+// NewServiceFromEnv
+
+func (s *SettleTestSuite) Test_NewServiceFromEnv_SetsSettleDelay() {
+	delayOrig := os.Getenv("DF_SETTLE_DELAY")
+	defer func() { os.Setenv("DF_SETTLE_DELAY", delayOrig) }()
+	os.Setenv("DF_SETTLE_DELAY", "10")
+
+	service := NewServiceFromEnv()
+
+	s.Equal(10*time.Second, service.SettleDelay)
+}
+
+// Util
+
+func (s *SettleTestSuite) getSettlingService() *Service {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.SettleDelay = time.Minute
+	return service
+}
+
+func (s *SettleTestSuite) expireSettleDelay(service *Service, name string) {
+	pending := service.settlingServices[name]
+	pending.changedAt = time.Now().Add(-2 * time.Minute)
+	service.settlingServices[name] = pending
+}
+
+func (s *SettleTestSuite) getService(name, port string) swarm.Service {
+	srv := swarm.Service{}
+	srv.Spec.Name = name
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true", "com.df.port": port}
+	return srv
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"hash/fnv"
+)
+
+func getShard(serviceName string, total int) int {
+	h := fnv.New32a()
+	h.Write([]byte(serviceName))
+	return int(h.Sum32() % uint32(total))
+}
+
+func (m *Service) isInShard(s swarm.Service) bool {
+	if m.ShardTotal <= 1 {
+		return true
+	}
+	return getShard(s.Spec.Name, m.ShardTotal) == m.ShardIndex
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+	"time"
+)
+
+type ShardTestSuite struct {
+	suite.Suite
+}
+
+func TestShardUnitTestSuite(t *testing.T) {
+	s := new(ShardTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+func (s *ShardTestSuite) SetupTest() {
+	serviceLastCreatedAt = time.Time{}
+}
+
+// GetNewServices
+
+func (s *ShardTestSuite) Test_GetNewServices_ReturnsEachServiceFromExactlyOneShard() {
+	services := []swarm.Service{}
+	for i := 0; i < 20; i++ {
+		services = append(services, s.getService(fmt.Sprintf("service-%d", i)))
+	}
+	owners := map[string]int{}
+	for index := 0; index < 3; index++ {
+		service := NewService("unix:///var/run/docker.sock", "", "")
+		service.ShardIndex = index
+		service.ShardTotal = 3
+
+		actual, _ := service.GetNewServices(services)
+
+		for _, srv := range actual {
+			owners[srv.Spec.Name]++
+		}
+		serviceLastCreatedAt = time.Time{}
+	}
+
+	s.Equal(20, len(owners))
+	for name, count := range owners {
+		s.Equal(1, count, name)
+	}
+}
+
+func (s *ShardTestSuite) Test_GetNewServices_ReturnsAllServices_WhenShardTotalIsNotSet() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	services := []swarm.Service{s.getService("go-demo"), s.getService("other")}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(2, len(actual))
+}
+
+// getShard
+
+func (s *ShardTestSuite) Test_GetShard_ReturnsTheSameShardForTheSameName() {
+	expected := getShard("go-demo", 5)
+
+	for i := 0; i < 10; i++ {
+		s.Equal(expected, getShard("go-demo", 5))
+	}
+	s.True(expected >= 0 && expected < 5)
+}
+
+// NewServiceFromEnv
+
+func (s *ShardTestSuite) Test_NewServiceFromEnv_SetsShard() {
+	indexOrig := os.Getenv("DF_SHARD_INDEX")
+	totalOrig := os.Getenv("DF_SHARD_TOTAL")
+	defer func() {
+		os.Setenv("DF_SHARD_INDEX", indexOrig)
+		os.Setenv("DF_SHARD_TOTAL", totalOrig)
+	}()
+	os.Setenv("DF_SHARD_INDEX", "2")
+	os.Setenv("DF_SHARD_TOTAL", "4")
+
+	service := NewServiceFromEnv()
+
+	s.Equal(2, service.ShardIndex)
+	s.Equal(4, service.ShardTotal)
+}
+
+// Util
+
+func (s *ShardTestSuite) getService(name string) swarm.Service {
+	srv := swarm.Service{}
+	srv.Spec.Name = name
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	return srv
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"strings"
+	"time"
+)
+
+var skipLogInterval = 5 * time.Minute
+
+type skipLog struct {
+	reason   string
+	loggedAt time.Time
+}
+
+func (m *Service) getSkipReason(s swarm.Service) string {
+	if !m.hasNotifyLabel(s) {
+		if len(m.NotifyLabelsAny) > 0 {
+			return fmt.Sprintf("none of the labels %s is set", strings.Join(append([]string{m.NotifyLabel}, m.NotifyLabelsAny...), ","))
+		}
+		return fmt.Sprintf("the label %s is not set", m.NotifyLabel)
+	}
+	if !m.isInShard(s) {
+		return fmt.Sprintf("the service belongs to another shard than %d", m.ShardIndex)
+	}
+	if m.isFlapping(s.Spec.Name) {
+		return "the service is flapping"
+	}
+	if !m.isManaged(s) {
+		return fmt.Sprintf("the service is filtered out by the managed by label %s", m.ManagedByLabel)
+	}
+	if !m.hasRequiredSecret(s) {
+		return fmt.Sprintf("the service does not use the secret %s", m.RequireSecret)
+	}
+	if keys := m.getOversizedLabels(s); len(keys) > 0 && m.OversizedLabelAction == "reject" {
+		return fmt.Sprintf("the values of the labels %s are longer than %d characters", strings.Join(keys, ","), m.MaxLabelValueLength)
+	}
+	if ports := m.getUnexposedPorts(s); len(ports) > 0 && m.ValidatePorts == "skip" {
+		return fmt.Sprintf("the ports %s are not exposed", strings.Join(ports, ","))
+	}
+	return ""
+}
+
+func (m *Service) logSkip(serviceName, reason string) {
+	if m.LogLevel != "debug" {
+		return
+	}
+	// The same reason is repeated at most once per interval so that large clusters do not flood the log
+	if last, ok := m.skipLogs[serviceName]; ok && last.reason == reason && time.Since(last.loggedAt) < skipLogInterval {
+		return
+	}
+	for name, l := range m.skipLogs {
+		if time.Since(l.loggedAt) >= skipLogInterval {
+			delete(m.skipLogs, name)
+		}
+	}
+	m.skipLogs[serviceName] = skipLog{reason: reason, loggedAt: time.Now()}
+	logPrintf("DEBUG: Skipping the service %s because %s", serviceName, reason)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type SkipTestSuite struct {
+	suite.Suite
+	logs []string
+}
+
+func TestSkipUnitTestSuite(t *testing.T) {
+	s := new(SkipTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {
+		s.logs = append(s.logs, fmt.Sprintf(format, v...))
+	}
+
+	suite.Run(t, s)
+}
+
+func (s *SkipTestSuite) SetupTest() {
+	s.logs = []string{}
+	serviceLastCreatedAt = time.Time{}
+}
+
+// GetNewServices
+
+func (s *SkipTestSuite) Test_GetNewServices_LogsSkipReasonsAtDebug() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.LogLevel = "debug"
+	service.ManagedByLabel = "com.df.managedBy=ci"
+	service.RequireSecret = "cert"
+	services := []swarm.Service{
+		s.getService("no-label", map[string]string{}),
+		s.getService("not-managed", map[string]string{"com.df.notify": "true", "com.df.managedBy": "manual"}),
+		s.getService("no-secret", map[string]string{"com.df.notify": "true", "com.df.managedBy": "ci"}),
+	}
+
+	service.GetNewServices(services)
+
+	s.Equal([]string{
+		"DEBUG: Skipping the service no-label because the label com.df.notify is not set",
+		"DEBUG: Skipping the service not-managed because the service is filtered out by the managed by label com.df.managedBy=ci",
+		"DEBUG: Skipping the service no-secret because the service does not use the secret cert",
+	}, s.logs)
+}
+
+func (s *SkipTestSuite) Test_GetNewServices_LogsDuplicateKeysAtDebug() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.LogLevel = "debug"
+	service.RejectDuplicateKeys = true
+	services := []swarm.Service{
+		s.getService("go-demo", map[string]string{"com.df.notify": "true", "com.df.serviceName": "demo"}),
+		s.getService("go-demo-2", map[string]string{"com.df.notify": "true", "com.df.serviceName": "demo"}),
+	}
+
+	service.GetNewServices(services)
+
+	s.Contains(s.logs, "DEBUG: Skipping the service go-demo-2 because the notification key is a duplicate")
+}
+
+func (s *SkipTestSuite) Test_GetNewServices_LogsRejectedDuplicateKeysOnce() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.RejectDuplicateKeys = true
+	services := []swarm.Service{
+		s.getService("go-demo", map[string]string{"com.df.notify": "true", "com.df.serviceName": "demo"}),
+		s.getService("go-demo-2", map[string]string{"com.df.notify": "true", "com.df.serviceName": "demo"}),
+	}
+
+	for i := 0; i < 3; i++ {
+		service.GetNewServices(services)
+	}
+
+	s.Equal([]string{"WARNING: Services go-demo and go-demo-2 produce the same notification key demo"}, s.logs)
+	s.NotContains(service.Services, "go-demo-2")
+}
+
+func (s *SkipTestSuite) Test_GetNewServices_DoesNotLogSkips_WhenLogLevelIsNotDebug() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	service.GetNewServices([]swarm.Service{s.getService("no-label", map[string]string{})})
+
+	s.Empty(s.logs)
+}
+
+// logSkip
+
+func (s *SkipTestSuite) Test_LogSkip_LogsTheSameReasonOncePerInterval() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.LogLevel = "debug"
+
+	service.logSkip("go-demo", "the label com.df.notify is not set")
+	service.logSkip("go-demo", "the label com.df.notify is not set")
+
+	s.Len(s.logs, 1)
+}
+
+func (s *SkipTestSuite) Test_LogSkip_LogsAgain_WhenReasonChanges() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.LogLevel = "debug"
+
+	service.logSkip("go-demo", "the label com.df.notify is not set")
+	service.logSkip("go-demo", "the service does not use the secret cert")
+
+	s.Len(s.logs, 2)
+}
+
+func (s *SkipTestSuite) Test_LogSkip_LogsAgain_WhenIntervalPasses() {
+	skipLogIntervalOrig := skipLogInterval
+	defer func() { skipLogInterval = skipLogIntervalOrig }()
+	skipLogInterval = 0
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.LogLevel = "debug"
+
+	service.logSkip("go-demo", "the label com.df.notify is not set")
+	service.logSkip("go-demo", "the label com.df.notify is not set")
+
+	s.Len(s.logs, 2)
+	s.True(strings.HasPrefix(s.logs[1], "DEBUG: "))
+}
+
+// NewServiceFromEnv
+
+func (s *SkipTestSuite) Test_NewServiceFromEnv_SetsLogLevel() {
+	logLevelOrig := os.Getenv("DF_LOG_LEVEL")
+	defer func() { os.Setenv("DF_LOG_LEVEL", logLevelOrig) }()
+	os.Setenv("DF_LOG_LEVEL", "debug")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("debug", service.LogLevel)
+}
+
+// Util
+
+func (s *SkipTestSuite) getService(name string, labels map[string]string) swarm.Service {
+	return swarm.Service{
+		Spec: swarm.ServiceSpec{
+			Annotations: swarm.Annotations{Name: name, Labels: labels},
+		},
+		Meta: swarm.Meta{CreatedAt: time.Now()},
+	}
+}
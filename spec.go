@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type LastSpec struct {
+	Image    string
+	Ports    []string
+	Labels   map[string]string
+	Replicas uint64
+}
+
+type SpecCache struct {
+	mu    sync.Mutex
+	specs map[string]LastSpec
+	order []string
+	max   int
+}
+
+func (m *SpecCache) Put(serviceName string, spec LastSpec) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.specs[serviceName]; ok {
+		m.delete(serviceName)
+	}
+	m.specs[serviceName] = spec
+	m.order = append(m.order, serviceName)
+	// The least recently notified services are dropped first
+	for m.max > 0 && len(m.order) > m.max {
+		delete(m.specs, m.order[0])
+		m.order = m.order[1:]
+	}
+}
+
+func (m *SpecCache) Get(serviceName string) (LastSpec, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	spec, ok := m.specs[serviceName]
+	return spec, ok
+}
+
+func (m *SpecCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.specs)
+}
+
+func (m *SpecCache) delete(serviceName string) {
+	delete(m.specs, serviceName)
+	for i, name := range m.order {
+		if name == serviceName {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			return
+		}
+	}
+}
+
+func NewSpecCache(max int) *SpecCache {
+	return &SpecCache{
+		specs: map[string]LastSpec{},
+		order: []string{},
+		max:   max,
+	}
+}
+
+func (m *Service) getLastSpec(s swarm.Service) LastSpec {
+	ports := []string{}
+	for _, p := range getPorts(s) {
+		ports = append(ports, fmt.Sprintf("%d:%d/%s", p.PublishedPort, p.TargetPort, p.Protocol))
+	}
+	return LastSpec{
+		Image:    getImage(s),
+		Ports:    ports,
+		Labels:   m.getNotificationLabels(s),
+		Replicas: getReplicas(s),
+	}
+}
+
+func (m *Service) rememberSpecs(services []swarm.Service, errs map[string]error) {
+	for _, s := range services {
+		if _, failed := errs[s.Spec.Name]; !failed {
+			m.LastSpecs.Put(s.Spec.Name, m.getLastSpec(s))
+		}
+	}
+}
+
+func (m *Service) addLastSpec(fullUrl, serviceName string) string {
+	spec, ok := m.LastSpecs.Get(serviceName)
+	if !ok {
+		return fullUrl
+	}
+	if len(spec.Image) > 0 {
+		fullUrl = fmt.Sprintf("%s&image=%s", fullUrl, spec.Image)
+	}
+	if len(spec.Ports) > 0 {
+		fullUrl = fmt.Sprintf("%s&ports=%s", fullUrl, strings.Join(spec.Ports, ","))
+	}
+	if spec.Replicas > 0 {
+		fullUrl = fmt.Sprintf("%s&replicas=%d", fullUrl, spec.Replicas)
+	}
+	keys := []string{}
+	for k := range spec.Labels {
+		switch k {
+		case "serviceName", "reason", "image", "ports", "replicas":
+		default:
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fullUrl = fmt.Sprintf("%s&%s=%s", fullUrl, k, spec.Labels[k])
+	}
+	return fullUrl
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+type SpecTestSuite struct {
+	suite.Suite
+}
+
+func TestSpecUnitTestSuite(t *testing.T) {
+	s := new(SpecTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// SpecCache
+
+func (s *SpecTestSuite) Test_Put_EvictsLeastRecentlyStoredSpecs_WhenMaxIsReached() {
+	cache := NewSpecCache(2)
+
+	cache.Put("first", LastSpec{Image: "first"})
+	cache.Put("second", LastSpec{Image: "second"})
+	cache.Put("first", LastSpec{Image: "first:2"})
+	cache.Put("third", LastSpec{Image: "third"})
+
+	s.Equal(2, cache.Len())
+	_, ok := cache.Get("second")
+	s.False(ok)
+	actual, ok := cache.Get("first")
+	s.True(ok)
+	s.Equal("first:2", actual.Image)
+}
+
+// NotifyServicesRemove
+
+func (s *SpecTestSuite) Test_NotifyServicesRemove_SendsLastKnownSpec() {
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, httpSrv.URL)
+	srv := s.getService()
+	service.Services["go-demo"] = true
+	service.ServicesCache["go-demo"] = srv
+	service.NotifyServicesCreate([]swarm.Service{srv}, 1, 0)
+
+	err := service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	s.NoError(err)
+	s.Equal("serviceName=go-demo&image=vfarcic/go-demo:1.0&ports=80:8080/tcp&replicas=3&servicePath=/demo", actualQuery)
+}
+
+func (s *SpecTestSuite) Test_NotifyServicesRemove_SendsLastKnownSpec_WhenServiceIsNoLongerCached() {
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, httpSrv.URL)
+	service.NotifyServicesCreate([]swarm.Service{s.getService()}, 1, 0)
+
+	service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	s.Contains(actualQuery, "image=vfarcic/go-demo:1.0")
+}
+
+func (s *SpecTestSuite) Test_NotifyServicesRemove_DoesNotSendSpec_WhenServiceWasNotNotified() {
+	actualQuery := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, httpSrv.URL)
+	service.Services["go-demo"] = true
+	service.ServicesCache["go-demo"] = s.getService()
+
+	service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	s.Equal("serviceName=go-demo", actualQuery)
+}
+
+func (s *SpecTestSuite) Test_NotifyServicesRemove_ExposesLastKnownSpecToTemplate() {
+	actualPath := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualPath = r.URL.Path
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, httpSrv.URL)
+	service.NotifRemoveTemplate = httpSrv.URL + "/{{.ServiceName}}/{{.Spec.Image}}"
+	service.NotifyServicesCreate([]swarm.Service{s.getService()}, 1, 0)
+
+	service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	s.Equal("/go-demo/vfarcic/go-demo:1.0", actualPath)
+}
+
+// NewServiceFromEnv
+
+func (s *SpecTestSuite) Test_NewServiceFromEnv_SetsSpecCacheSize() {
+	sizeOrig := os.Getenv("DF_SPEC_CACHE_SIZE")
+	defer func() { os.Setenv("DF_SPEC_CACHE_SIZE", sizeOrig) }()
+	os.Setenv("DF_SPEC_CACHE_SIZE", "10")
+
+	service := NewServiceFromEnv()
+
+	s.Equal(10, service.LastSpecs.max)
+}
+
+// Util
+
+func (s *SpecTestSuite) getService() swarm.Service {
+	replicas := uint64(3)
+	srv := swarm.Service{}
+	srv.Spec.Name = "go-demo"
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true", "com.df.servicePath": "/demo"}
+	srv.Spec.Mode.Replicated = &swarm.ReplicatedService{Replicas: &replicas}
+	srv.Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{Image: "vfarcic/go-demo:1.0"}
+	srv.Spec.EndpointSpec = &swarm.EndpointSpec{
+		Ports: []swarm.PortConfig{{PublishedPort: 80, TargetPort: 8080, Protocol: swarm.PortConfigProtocolTCP}},
+	}
+	return srv
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+)
+
+type State struct {
+	Services map[string]map[string]string `json:"services"`
+}
+
+func (m *Service) LoadState() error {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	if len(m.StateFile) == 0 {
+		return nil
+	}
+	data, err := ioutil.ReadFile(m.StateFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	state := State{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	for name, labels := range state.Services {
+		s := swarm.Service{}
+		s.Spec.Name = name
+		s.Spec.Labels = labels
+		m.Services[name] = true
+		m.ServicesCache[name] = s
+		m.loadedServices[name] = true
+	}
+	m.stateLoadedAt = time.Now()
+	logPrintf("Loaded %d services from the state file %s", len(state.Services), m.StateFile)
+	return nil
+}
+
+func (m *Service) LoadStateSource() error {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	if len(m.StateSourceUrl) == 0 {
+		return nil
+	}
+	names, err := m.getKnownServices(m.StateSourceUrl)
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, name := range names {
+		known[name] = true
+	}
+	// The receiver is the authority so the services from the state file it does not know about are notified again
+	for name := range m.loadedServices {
+		if !known[name] {
+			delete(m.loadedServices, name)
+			m.forgetService(name)
+		}
+	}
+	for _, name := range names {
+		if _, ok := m.ServicesCache[name]; !ok {
+			s := swarm.Service{}
+			s.Spec.Name = name
+			m.ServicesCache[name] = s
+		}
+		m.Services[name] = true
+		m.sourcedServices[name] = true
+	}
+	logPrintf("Loaded %d services known to %s", len(names), m.StateSourceUrl)
+	return nil
+}
+
+func (m *Service) getKnownServices(sourceUrl string) ([]string, error) {
+	resp, err := m.getHttpClient().Get(sourceUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Request %s returned status code %d", sourceUrl, resp.StatusCode)
+	}
+	names := []string{}
+	if err := json.NewDecoder(resp.Body).Decode(&names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func (m *Service) SaveState() error {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	if len(m.StateFile) == 0 {
+		return nil
+	}
+	state := State{Services: map[string]map[string]string{}}
+	for name := range m.Services {
+		state.Services[name] = m.ServicesCache[name].Spec.Labels
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmpFile := m.StateFile + ".tmp"
+	if err := ioutil.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, m.StateFile)
+}
+
+func (m *Service) dropStaleServices(removed []string, services []swarm.Service) []string {
+	if len(m.loadedServices) == 0 {
+		return removed
+	}
+	if time.Since(m.stateLoadedAt) < m.StartupGrace {
+		// Services that are missing during the grace period might still be starting and are re-checked once it passes
+		rs := []string{}
+		for _, name := range removed {
+			if !m.isMissingLoadedService(name) {
+				rs = append(rs, name)
+			}
+		}
+		return rs
+	}
+	loaded := m.loadedServices
+	m.loadedServices = map[string]bool{}
+	if m.ResyncScope == "full" {
+		return removed
+	}
+	if !m.hasLabeledServices(services) {
+		// Nothing in an empty cluster indicates that the receiver dropped the services so they are removed explicitly
+		logPrintf("The cluster has no labeled services. Services loaded from the state file will be notified as removed")
+		return removed
+	}
+	rs := []string{}
+	stale := []string{}
+	for _, name := range removed {
+		if loaded[name] && m.RemovalReasons[name] == "removed" {
+			stale = append(stale, name)
+			m.forgetService(name)
+		} else {
+			rs = append(rs, name)
+		}
+	}
+	if len(stale) > 0 {
+		sort.Strings(stale)
+		logPrintf("Services %v loaded from the state file do not exist any more and will not be notified as removed", stale)
+	}
+	return rs
+}
+
+func (m *Service) hasLabeledServices(services []swarm.Service) bool {
+	for _, s := range services {
+		if m.hasNotifyLabel(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Service) resetBaselineWhenEmpty() {
+	if len(m.Services) == 0 && !serviceLastCreatedAt.IsZero() {
+		logPrintf("No services are tracked any more. The last service creation time is reset")
+		serviceLastCreatedAt = time.Time{}
+	}
+}
+
+func (m *Service) isMissingLoadedService(name string) bool {
+	return m.loadedServices[name] && m.RemovalReasons[name] == "removed"
+}
+
+func (m *Service) isKnownToReceiver(s swarm.Service) bool {
+	if m.sourcedServices[s.Spec.Name] {
+		return true
+	}
+	// Services loaded from the state file were already notified unless their labels changed in the meantime
+	return m.loadedServices[s.Spec.Name] && reflect.DeepEqual(getLabels(m.ServicesCache[s.Spec.Name], m.LabelPrefix), getLabels(s, m.LabelPrefix))
+}
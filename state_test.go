@@ -0,0 +1,376 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+type StateTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestStateUnitTestSuite(t *testing.T) {
+	s := new(StateTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+func (s *StateTestSuite) SetupTest() {
+	s.dir, _ = ioutil.TempDir("", "swarm-listener-state")
+	serviceLastCreatedAt = time.Time{}
+}
+
+func (s *StateTestSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+// LoadState
+
+func (s *StateTestSuite) Test_LoadState_AddsServicesFromStateFile() {
+	service := s.getService()
+	ioutil.WriteFile(service.StateFile, []byte(`{"services":{"go-demo":{"com.df.notify":"true"}}}`), 0644)
+
+	err := service.LoadState()
+
+	s.NoError(err)
+	s.Contains(service.Services, "go-demo")
+	s.Equal("true", service.ServicesCache["go-demo"].Spec.Labels["com.df.notify"])
+}
+
+func (s *StateTestSuite) Test_LoadState_DoesNothing_WhenStateFileDoesNotExist() {
+	service := s.getService()
+
+	err := service.LoadState()
+
+	s.NoError(err)
+	s.Empty(service.Services)
+}
+
+func (s *StateTestSuite) Test_LoadState_ReturnsError_WhenStateFileIsInvalid() {
+	service := s.getService()
+	ioutil.WriteFile(service.StateFile, []byte("not json"), 0644)
+
+	err := service.LoadState()
+
+	s.Error(err)
+}
+
+// LoadStateSource
+
+func (s *StateTestSuite) Test_LoadStateSource_SeedsServicesFromSource() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`["go-demo","other"]`))
+	}))
+	defer httpSrv.Close()
+	service := s.getService()
+	service.StateSourceUrl = httpSrv.URL
+
+	err := service.LoadStateSource()
+
+	s.NoError(err)
+	s.Equal(map[string]bool{"go-demo": true, "other": true}, service.Services)
+	s.Equal("go-demo", service.ServicesCache["go-demo"].Spec.Name)
+}
+
+func (s *StateTestSuite) Test_LoadStateSource_ReturnsError_WhenSourceFails() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer httpSrv.Close()
+	service := s.getService()
+	service.StateSourceUrl = httpSrv.URL
+
+	err := service.LoadStateSource()
+
+	s.Error(err)
+	s.Empty(service.Services)
+}
+
+func (s *StateTestSuite) Test_LoadStateSource_ReturnsError_WhenResponseIsNotAList() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"services":"go-demo"}`))
+	}))
+	defer httpSrv.Close()
+	service := s.getService()
+	service.StateSourceUrl = httpSrv.URL
+
+	s.Error(service.LoadStateSource())
+}
+
+func (s *StateTestSuite) Test_GetNewServices_DoesNotReturnServicesKnownToStateSource() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`["go-demo","gone"]`))
+	}))
+	defer httpSrv.Close()
+	service := s.getService()
+	service.StateSourceUrl = httpSrv.URL
+	service.LoadStateSource()
+	services := []swarm.Service{s.getSwarmService("go-demo"), s.getSwarmService("new")}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(1, len(actual))
+	s.Equal("new", actual[0].Spec.Name)
+	s.Equal(services[0], service.ServicesCache["go-demo"])
+	s.Equal([]string{"gone"}, service.GetRemovedServices(services))
+}
+
+func (s *StateTestSuite) Test_GetNewServices_ReturnsOnlyServicesUnknownToReceiver_WhenStateFileAndSourceAreSet() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`["go-demo"]`))
+	}))
+	defer httpSrv.Close()
+	service := s.getService()
+	service.StateSourceUrl = httpSrv.URL
+	ioutil.WriteFile(service.StateFile, []byte(`{"services":{"go-demo":{"com.df.notify":"true"},"forgotten":{"com.df.notify":"true"},"gone":{"com.df.notify":"true"}}}`), 0644)
+	service.LoadState()
+	service.LoadStateSource()
+	services := []swarm.Service{s.getSwarmService("go-demo"), s.getSwarmService("forgotten"), s.getSwarmService("new")}
+
+	actual, _ := service.GetNewServices(services)
+
+	s.Equal(2, len(actual))
+	s.Equal("forgotten", actual[0].Spec.Name)
+	s.Equal("new", actual[1].Spec.Name)
+	s.Empty(service.GetRemovedServices(services))
+}
+
+// SaveState
+
+func (s *StateTestSuite) Test_SaveState_WritesServicesThatCanBeLoaded() {
+	service := s.getService()
+	service.GetNewServices([]swarm.Service{s.getSwarmService("go-demo")})
+
+	err := service.SaveState()
+	loaded := s.getService()
+	loaded.LoadState()
+
+	s.NoError(err)
+	s.Equal(map[string]bool{"go-demo": true}, loaded.Services)
+}
+
+// GetRemovedServices
+
+func (s *StateTestSuite) Test_GetRemovedServices_DropsStaleServices_WhenResyncScopeIsCreates() {
+	service := s.getLoadedService("creates")
+
+	actual := service.GetRemovedServices([]swarm.Service{s.getSwarmService("go-demo")})
+
+	s.Empty(actual)
+	s.NotContains(service.Services, "stale")
+}
+
+func (s *StateTestSuite) Test_GetRemovedServices_ReturnsStaleServices_WhenClusterIsEmpty() {
+	service := s.getLoadedService("creates")
+
+	actual := service.GetRemovedServices([]swarm.Service{})
+
+	sort.Strings(actual)
+	s.Equal([]string{"go-demo", "stale"}, actual)
+}
+
+func (s *StateTestSuite) Test_NotifyServicesRemove_ResetsLastCreatedAt_WhenStateFileIsReconciledWithEmptyCluster() {
+	removed := []string{}
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		removed = append(removed, r.URL.Query().Get("serviceName"))
+	}))
+	defer httpSrv.Close()
+	service := s.getLoadedService("creates")
+	service.NotifRemoveServiceUrl = httpSrv.URL
+	serviceLastCreatedAt = time.Now().Add(-time.Hour)
+	empty := []swarm.Service{}
+
+	err := service.NotifyServicesRemove(service.GetRemovedServices(empty), 1, 0)
+
+	s.NoError(err)
+	sort.Strings(removed)
+	s.Equal([]string{"go-demo", "stale"}, removed)
+	s.Empty(service.Services)
+	s.True(serviceLastCreatedAt.IsZero())
+	service.SaveState()
+	loaded := s.getService()
+	loaded.LoadState()
+	s.Empty(loaded.Services)
+}
+
+func (s *StateTestSuite) Test_GetRemovedServices_ReturnsStaleServices_WhenResyncScopeIsFull() {
+	service := s.getLoadedService("full")
+
+	actual := service.GetRemovedServices([]swarm.Service{s.getSwarmService("go-demo")})
+
+	s.Equal([]string{"stale"}, actual)
+}
+
+func (s *StateTestSuite) Test_GetRemovedServices_ReturnsServicesRemovedAfterStartup_WhenResyncScopeIsCreates() {
+	service := s.getLoadedService("creates")
+	service.GetRemovedServices([]swarm.Service{s.getSwarmService("go-demo"), s.getSwarmService("stale")})
+
+	actual := service.GetRemovedServices([]swarm.Service{s.getSwarmService("go-demo")})
+
+	s.Equal([]string{"stale"}, actual)
+}
+
+func (s *StateTestSuite) Test_GetRemovedServices_HoldsStaleServices_WhenStartupGraceDidNotPass() {
+	service := s.getLoadedService("full")
+	service.StartupGrace = time.Minute
+
+	actual := service.GetRemovedServices([]swarm.Service{s.getSwarmService("go-demo")})
+
+	s.Empty(actual)
+	s.Contains(service.Services, "stale")
+}
+
+func (s *StateTestSuite) Test_GetRemovedServices_DoesNotReturnServices_WhenTheyReappearWithinStartupGrace() {
+	service := s.getLoadedService("full")
+	service.StartupGrace = time.Minute
+	service.GetRemovedServices([]swarm.Service{s.getSwarmService("go-demo")})
+	service.GetRemovedServices([]swarm.Service{s.getSwarmService("go-demo"), s.getSwarmService("stale")})
+	service.stateLoadedAt = time.Now().Add(-2 * time.Minute)
+
+	actual := service.GetRemovedServices([]swarm.Service{s.getSwarmService("go-demo"), s.getSwarmService("stale")})
+
+	s.Empty(actual)
+	s.Contains(service.Services, "stale")
+}
+
+func (s *StateTestSuite) Test_GetRemovedServices_ReturnsStaleServices_WhenTheyAreStillMissingAfterStartupGrace() {
+	service := s.getLoadedService("full")
+	service.StartupGrace = time.Minute
+	service.GetRemovedServices([]swarm.Service{s.getSwarmService("go-demo")})
+	service.stateLoadedAt = time.Now().Add(-2 * time.Minute)
+
+	actual := service.GetRemovedServices([]swarm.Service{s.getSwarmService("go-demo")})
+
+	s.Equal([]string{"stale"}, actual)
+}
+
+func (s *StateTestSuite) Test_GetNewServices_ResyncsOnlyServicesUnknownToStateFile() {
+	service := s.getLoadedService("creates")
+
+	actual, _ := service.GetNewServices([]swarm.Service{s.getSwarmService("go-demo"), s.getSwarmService("new")})
+
+	s.Equal(1, len(actual))
+	s.Equal("new", actual[0].Spec.Name)
+	s.Contains(service.SpecDigests, "go-demo")
+}
+
+func (s *StateTestSuite) Test_GetNewServices_ResyncsServices_WhenLabelsChangedSinceStateWasSaved() {
+	service := s.getLoadedService("creates")
+	srv := s.getSwarmService("go-demo")
+	srv.Spec.Labels["com.df.port"] = "8080"
+
+	actual, _ := service.GetNewServices([]swarm.Service{srv})
+
+	s.Equal([]swarm.Service{srv}, actual)
+}
+
+func (s *StateTestSuite) Test_GetNewServices_ReturnsUntrackedServices_WhenTheyAreOlderThanLastCreatedAt() {
+	service := s.getLoadedService("creates")
+	service.GetNewServices([]swarm.Service{s.getSwarmService("go-demo")})
+	labeled := s.getSwarmService("labeled-later")
+	labeled.Meta.CreatedAt = time.Now().Add(-time.Hour)
+
+	actual, _ := service.GetNewServices([]swarm.Service{s.getSwarmService("go-demo"), labeled})
+
+	s.Equal([]swarm.Service{labeled}, actual)
+	s.Contains(service.Services, "labeled-later")
+}
+
+func (s *StateTestSuite) Test_GetNewServices_DoesNotReturnOlderServices_WhenTheyAreInactive() {
+	service := s.getLoadedService("creates")
+	service.GetNewServices([]swarm.Service{s.getSwarmService("go-demo")})
+	inactive := s.getSwarmService("inactive")
+	inactive.Meta.CreatedAt = time.Now().Add(-time.Hour)
+	replicas := uint64(0)
+	inactive.Spec.Mode.Replicated = &swarm.ReplicatedService{Replicas: &replicas}
+	service.InactiveServices["inactive"] = true
+
+	actual, _ := service.GetNewServices([]swarm.Service{s.getSwarmService("go-demo"), inactive})
+
+	s.Empty(actual)
+}
+
+// NewServiceFromEnv
+
+func (s *StateTestSuite) Test_NewServiceFromEnv_SetsStateFileAndResyncScope() {
+	stateFileOrig := os.Getenv("DF_STATE_FILE")
+	scopeOrig := os.Getenv("DF_RESYNC_SCOPE")
+	defer func() {
+		os.Setenv("DF_STATE_FILE", stateFileOrig)
+		os.Setenv("DF_RESYNC_SCOPE", scopeOrig)
+	}()
+	os.Setenv("DF_STATE_FILE", "/data/state.json")
+	os.Setenv("DF_RESYNC_SCOPE", "full")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("/data/state.json", service.StateFile)
+	s.Equal("full", service.ResyncScope)
+}
+
+func (s *StateTestSuite) Test_NewServiceFromEnv_SetsResyncScopeToCreates_WhenEnvIsNotPresent() {
+	scopeOrig := os.Getenv("DF_RESYNC_SCOPE")
+	defer func() { os.Setenv("DF_RESYNC_SCOPE", scopeOrig) }()
+	os.Unsetenv("DF_RESYNC_SCOPE")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("creates", service.ResyncScope)
+}
+
+func (s *StateTestSuite) Test_NewServiceFromEnv_SetsStateSourceUrl() {
+	sourceOrig := os.Getenv("DF_STATE_SOURCE_URL")
+	defer func() { os.Setenv("DF_STATE_SOURCE_URL", sourceOrig) }()
+	os.Setenv("DF_STATE_SOURCE_URL", "http://proxy:8080/v1/docker-flow-proxy/services")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("http://proxy:8080/v1/docker-flow-proxy/services", service.StateSourceUrl)
+}
+
+func (s *StateTestSuite) Test_NewServiceFromEnv_SetsStartupGrace() {
+	graceOrig := os.Getenv("DF_STARTUP_GRACE")
+	defer func() { os.Setenv("DF_STARTUP_GRACE", graceOrig) }()
+	os.Setenv("DF_STARTUP_GRACE", "30")
+
+	service := NewServiceFromEnv()
+
+	s.Equal(30*time.Second, service.StartupGrace)
+}
+
+// Util
+
+func (s *StateTestSuite) getService() *Service {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.StateFile = filepath.Join(s.dir, "state.json")
+	return service
+}
+
+func (s *StateTestSuite) getLoadedService(scope string) *Service {
+	service := s.getService()
+	service.ResyncScope = scope
+	ioutil.WriteFile(service.StateFile, []byte(`{"services":{"go-demo":{"com.df.notify":"true"},"stale":{"com.df.notify":"true"}}}`), 0644)
+	service.LoadState()
+	return service
+}
+
+func (s *StateTestSuite) getSwarmService(name string) swarm.Service {
+	srv := swarm.Service{}
+	srv.Spec.Name = name
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	srv.Meta.CreatedAt = time.Now()
+	return srv
+}
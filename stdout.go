@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/docker/docker/api/types/swarm"
+	"io"
+	"os"
+	"sync"
+)
+
+var stdoutWriter = NewEventWriter(os.Stdout)
+
+type EventWriter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func (m *EventWriter) Write(events []Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range events {
+		// The encoder terminates each event with a new line which makes the output NDJSON
+		if err := m.encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{encoder: json.NewEncoder(w)}
+}
+
+func (m *Service) writeServiceEvents(eventType string, services []swarm.Service) error {
+	events := []Event{}
+	for _, s := range services {
+		events = append(events, Event{Type: eventType, ServiceName: s.Spec.Name, Labels: getServiceLabels(s)})
+	}
+	return m.writeEvents(events)
+}
+
+func (m *Service) settleWritten(eventType string, services []swarm.Service) {
+	names := []string{}
+	for _, s := range services {
+		names = append(names, s.Spec.Name)
+	}
+	m.settleNotified(eventType, services, names, map[string]error{})
+}
+
+func (m *Service) writeEvents(events []Event) error {
+	if err := stdoutWriter.Write(events); err != nil {
+		logPrintf("ERROR: Could not write events to stdout\n%s", err.Error())
+		return err
+	}
+	return nil
+}
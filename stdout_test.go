@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+)
+
+type StdoutTestSuite struct {
+	suite.Suite
+	out *bytes.Buffer
+}
+
+func TestStdoutUnitTestSuite(t *testing.T) {
+	s := new(StdoutTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+func (s *StdoutTestSuite) SetupTest() {
+	s.out = &bytes.Buffer{}
+	stdoutWriter = NewEventWriter(s.out)
+}
+
+func (s *StdoutTestSuite) TearDownTest() {
+	stdoutWriter = NewEventWriter(os.Stdout)
+}
+
+// NotifyServicesCreate
+
+func (s *StdoutTestSuite) Test_NotifyServicesCreate_WritesNDJSONEvents() {
+	service := s.getService()
+	services := []swarm.Service{
+		s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true", "com.df.port": "8080"}),
+		s.getSwarmService("other", map[string]string{"com.df.notify": "true"}),
+		s.getSwarmService("not-labeled", map[string]string{}),
+	}
+
+	err := service.NotifyServicesCreate(services, 1, 0)
+
+	s.NoError(err)
+	s.Equal([]Event{
+		{Type: "create", ServiceName: "go-demo", Labels: map[string]string{"com.df.notify": "true", "com.df.port": "8080"}},
+		{Type: "create", ServiceName: "other", Labels: map[string]string{"com.df.notify": "true"}},
+	}, s.getEvents())
+}
+
+func (s *StdoutTestSuite) Test_NotifyServicesCreate_CountsWrittenEvents() {
+	service := s.getService()
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+
+	service.NotifyServicesCreate([]swarm.Service{srv}, 1, 0)
+
+	s.Equal([]NotificationCount{{ServiceName: "go-demo", Create: 1}}, service.GetNotificationCounts())
+	_, ok := service.LastSpecs.Get("go-demo")
+	s.True(ok)
+}
+
+// NotifyServicesUpdate
+
+func (s *StdoutTestSuite) Test_NotifyServicesUpdate_WritesNDJSONEvents() {
+	service := s.getService()
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	service.PreviousServices["go-demo"] = srv
+
+	err := service.NotifyServicesUpdate([]swarm.Service{srv}, 1, 0)
+
+	s.NoError(err)
+	s.Equal([]Event{{Type: "update", ServiceName: "go-demo", Labels: srv.Spec.Labels}}, s.getEvents())
+	s.NotContains(service.PreviousServices, "go-demo")
+	s.Equal([]NotificationCount{{ServiceName: "go-demo", Update: 1}}, service.GetNotificationCounts())
+}
+
+// NotifyServicesRemove
+
+func (s *StdoutTestSuite) Test_NotifyServicesRemove_WritesNDJSONEvents() {
+	service := s.getService()
+	srv := s.getSwarmService("go-demo", map[string]string{"com.df.notify": "true"})
+	service.Services["go-demo"] = true
+	service.ServicesCache["go-demo"] = srv
+
+	err := service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	s.NoError(err)
+	s.Equal([]Event{{Type: "remove", ServiceName: "go-demo", Labels: srv.Spec.Labels}}, s.getEvents())
+	s.NotContains(service.Services, "go-demo")
+	s.Equal([]NotificationCount{{ServiceName: "go-demo", Remove: 1}}, service.GetNotificationCounts())
+}
+
+// NewServiceFromEnv
+
+func (s *StdoutTestSuite) Test_NewServiceFromEnv_SetsNotifyMethod() {
+	methodOrig := os.Getenv("DF_NOTIFY_METHOD")
+	defer func() { os.Setenv("DF_NOTIFY_METHOD", methodOrig) }()
+	os.Setenv("DF_NOTIFY_METHOD", "stdout")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("stdout", service.NotifyMethod)
+}
+
+// Util
+
+func (s *StdoutTestSuite) getService() *Service {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyMethod = "stdout"
+	return service
+}
+
+func (s *StdoutTestSuite) getSwarmService(name string, labels map[string]string) swarm.Service {
+	srv := swarm.Service{}
+	srv.Spec.Name = name
+	srv.Spec.Labels = labels
+	return srv
+}
+
+func (s *StdoutTestSuite) getEvents() []Event {
+	events := []Event{}
+	scanner := bufio.NewScanner(bytes.NewReader(s.out.Bytes()))
+	for scanner.Scan() {
+		event := Event{}
+		s.NoError(json.Unmarshal(scanner.Bytes(), &event), "Each line must be a valid JSON object")
+		events = append(events, event)
+	}
+	return events
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"os"
+	"strings"
+)
+
+type Target struct {
+	CreateUrl string
+	UpdateUrl string
+	RemoveUrl string
+}
+
+func getTargetsFromEnv() map[string]Target {
+	targets := map[string]Target{}
+	for _, e := range os.Environ() {
+		kv := strings.SplitN(e, "=", 2)
+		for prefix, field := range map[string]string{
+			"DF_NOTIF_CREATE_SERVICE_URL_": "create",
+			"DF_NOTIF_UPDATE_SERVICE_URL_": "update",
+			"DF_NOTIF_REMOVE_SERVICE_URL_": "remove",
+		} {
+			if !strings.HasPrefix(kv[0], prefix) || len(kv[0]) == len(prefix) {
+				continue
+			}
+			name := strings.ToLower(strings.TrimPrefix(kv[0], prefix))
+			target := targets[name]
+			switch field {
+			case "create":
+				target.CreateUrl = kv[1]
+			case "update":
+				target.UpdateUrl = kv[1]
+			case "remove":
+				target.RemoveUrl = kv[1]
+			}
+			targets[name] = target
+		}
+	}
+	for name, target := range targets {
+		if len(target.UpdateUrl) == 0 {
+			target.UpdateUrl = target.CreateUrl
+			targets[name] = target
+		}
+	}
+	return targets
+}
+
+func (m *Service) getTarget(s swarm.Service) Target {
+	name, ok := getServiceLabels(s)[m.LabelPrefix+"target"]
+	if !ok || len(name) == 0 {
+		return Target{
+			CreateUrl: m.NotifCreateServiceUrl,
+			UpdateUrl: m.NotifUpdateServiceUrl,
+			RemoveUrl: m.NotifRemoveServiceUrl,
+		}
+	}
+	if target, ok := m.Targets[strings.ToLower(name)]; ok {
+		return target
+	}
+	logPrintf("WARNING: The target %s of the service %s is not configured. The service will not be notified", name, s.Spec.Name)
+	return Target{}
+}
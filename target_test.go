@@ -0,0 +1,140 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+type TargetTestSuite struct {
+	suite.Suite
+	mu       sync.Mutex
+	requests map[string][]string
+}
+
+func TestTargetUnitTestSuite(t *testing.T) {
+	s := new(TargetTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+func (s *TargetTestSuite) SetupTest() {
+	s.requests = map[string][]string{}
+}
+
+// NotifyServicesCreate
+
+func (s *TargetTestSuite) Test_NotifyServicesCreate_RoutesServicesToTargetGroups() {
+	defaultSrv := s.newReceiver("default")
+	defer defaultSrv.Close()
+	prodSrv := s.newReceiver("prod")
+	defer prodSrv.Close()
+	stagingSrv := s.newReceiver("staging")
+	defer stagingSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", defaultSrv.URL, defaultSrv.URL)
+	service.Targets = map[string]Target{
+		"prod":    {CreateUrl: prodSrv.URL},
+		"staging": {CreateUrl: stagingSrv.URL},
+	}
+	services := []swarm.Service{
+		s.getService("go-demo-prod", "prod"),
+		s.getService("go-demo-staging", "staging"),
+		s.getService("go-demo", ""),
+	}
+
+	err := service.NotifyServicesCreate(services, 1, 0)
+
+	s.NoError(err)
+	s.Equal([]string{"go-demo-prod"}, s.requests["prod"])
+	s.Equal([]string{"go-demo-staging"}, s.requests["staging"])
+	s.Equal([]string{"go-demo"}, s.requests["default"])
+}
+
+func (s *TargetTestSuite) Test_NotifyServicesCreate_DoesNotNotify_WhenTargetIsNotConfigured() {
+	defaultSrv := s.newReceiver("default")
+	defer defaultSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", defaultSrv.URL, defaultSrv.URL)
+
+	err := service.NotifyServicesCreate([]swarm.Service{s.getService("go-demo", "qa")}, 1, 0)
+
+	s.NoError(err)
+	s.Empty(s.requests)
+}
+
+// NotifyServicesRemove
+
+func (s *TargetTestSuite) Test_NotifyServicesRemove_RoutesServicesToTargetGroups() {
+	defaultSrv := s.newReceiver("default")
+	defer defaultSrv.Close()
+	prodSrv := s.newReceiver("prod")
+	defer prodSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", defaultSrv.URL, defaultSrv.URL)
+	service.Targets = map[string]Target{"prod": {RemoveUrl: prodSrv.URL}}
+	for _, srv := range []swarm.Service{s.getService("go-demo-prod", "prod"), s.getService("go-demo", "")} {
+		service.Services[srv.Spec.Name] = true
+		service.ServicesCache[srv.Spec.Name] = srv
+	}
+
+	err := service.NotifyServicesRemove([]string{"go-demo-prod", "go-demo"}, 1, 0)
+
+	s.NoError(err)
+	s.Equal([]string{"go-demo-prod"}, s.requests["prod"])
+	s.Equal([]string{"go-demo"}, s.requests["default"])
+}
+
+// getTargetsFromEnv
+
+func (s *TargetTestSuite) Test_GetTargetsFromEnv_ReturnsTargetGroups() {
+	defer func() {
+		os.Unsetenv("DF_NOTIF_CREATE_SERVICE_URL_PROD")
+		os.Unsetenv("DF_NOTIF_REMOVE_SERVICE_URL_PROD")
+		os.Unsetenv("DF_NOTIF_CREATE_SERVICE_URL_STAGING")
+		os.Unsetenv("DF_NOTIF_UPDATE_SERVICE_URL_STAGING")
+	}()
+	os.Setenv("DF_NOTIF_CREATE_SERVICE_URL_PROD", "http://prod-proxy/reconfigure")
+	os.Setenv("DF_NOTIF_REMOVE_SERVICE_URL_PROD", "http://prod-proxy/remove")
+	os.Setenv("DF_NOTIF_CREATE_SERVICE_URL_STAGING", "http://staging-proxy/reconfigure")
+	os.Setenv("DF_NOTIF_UPDATE_SERVICE_URL_STAGING", "http://staging-proxy/update")
+
+	actual := getTargetsFromEnv()
+
+	s.Equal(map[string]Target{
+		"prod": {
+			CreateUrl: "http://prod-proxy/reconfigure",
+			UpdateUrl: "http://prod-proxy/reconfigure",
+			RemoveUrl: "http://prod-proxy/remove",
+		},
+		"staging": {
+			CreateUrl: "http://staging-proxy/reconfigure",
+			UpdateUrl: "http://staging-proxy/update",
+		},
+	}, actual)
+}
+
+// Util
+
+func (s *TargetTestSuite) newReceiver(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests[name] = append(s.requests[name], r.URL.Query().Get("serviceName"))
+	}))
+}
+
+func (s *TargetTestSuite) getService(name, target string) swarm.Service {
+	srv := swarm.Service{}
+	srv.Spec.Name = name
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	if len(target) > 0 {
+		srv.Spec.Labels["com.df.target"] = target
+	}
+	return srv
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"golang.org/x/net/context"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (m *Service) getServiceTasks(serviceId string) ([]swarm.Task, error) {
+	dc, err := newDockerClient(m.Host)
+	if err != nil {
+		return []swarm.Task{}, err
+	}
+	args := filters.NewArgs()
+	args.Add("service", serviceId)
+	return dc.TaskList(context.Background(), types.TaskListOptions{Filters: args})
+}
+
+func getRunningNodeIds(tasks []swarm.Task) []string {
+	nodes := map[string]bool{}
+	for _, t := range tasks {
+		if t.Status.State == swarm.TaskStateRunning && len(t.NodeID) > 0 {
+			nodes[t.NodeID] = true
+		}
+	}
+	ids := []string{}
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (m *Service) getNodesParam(s swarm.Service) string {
+	if !m.IncludeNodes {
+		return ""
+	}
+	tasks, err := m.getServiceTasks(s.ID)
+	if err != nil {
+		logPrintf("WARNING: Could not list tasks of the service %s. The nodes parameter will not be sent\n%s", s.Spec.Name, err.Error())
+		return ""
+	}
+	return fmt.Sprintf("&nodes=%s", strings.Join(getRunningNodeIds(tasks), ","))
+}
+
+func getStuckTasks(tasks []swarm.Task, timeout time.Duration) []swarm.Task {
+	stuck := []swarm.Task{}
+	for _, t := range tasks {
+		if t.DesiredState != swarm.TaskStateRunning {
+			continue
+		}
+		switch t.Status.State {
+		case swarm.TaskStateNew, swarm.TaskStateAllocated, swarm.TaskStatePending:
+			if time.Since(t.Meta.CreatedAt) > timeout {
+				stuck = append(stuck, t)
+			}
+		}
+	}
+	return stuck
+}
+
+func (m *Service) NotifyServicesStuck(services []swarm.Service, retries, interval int) error {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	if m.StuckTaskTimeout <= 0 || len(m.NotifStuckServiceUrl) == 0 {
+		return nil
+	}
+	errs := []error{}
+	for _, s := range services {
+		if _, ok := m.Services[s.Spec.Name]; !ok {
+			continue
+		}
+		tasks, err := m.getServiceTasks(s.ID)
+		if err != nil {
+			logPrintf("WARNING: Could not list tasks of the service %s\n%s", s.Spec.Name, err.Error())
+			continue
+		}
+		stuck := getStuckTasks(tasks, m.StuckTaskTimeout)
+		if len(stuck) == 0 {
+			delete(m.StuckServices, s.Spec.Name)
+			continue
+		}
+		if m.StuckServices[s.Spec.Name] {
+			continue
+		}
+		logPrintf("WARNING: %d tasks of the service %s did not reach the running state", len(stuck), s.Spec.Name)
+		fullUrl := fmt.Sprintf("%s?serviceName=%s&stuckTasks=%d", m.NotifStuckServiceUrl, s.Spec.Name, len(stuck))
+		logPrintf("Sending service stuck notification to %s", fullUrl)
+		if err := m.sendNotificationUnlocked(s.Spec.Name, "stuck", fullUrl, retries, interval); err != nil {
+			errs = append(errs, err)
+		} else {
+			m.StuckServices[s.Spec.Name] = true
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("At least one request produced errors. Please consult logs for more details.")
+	}
+	return nil
+}
+
+func getFailedTasks(tasks []swarm.Task) []swarm.Task {
+	failed := []swarm.Task{}
+	for _, t := range tasks {
+		if t.Status.State == swarm.TaskStateFailed || t.Status.State == swarm.TaskStateRejected {
+			failed = append(failed, t)
+		}
+	}
+	return failed
+}
+
+func (m *Service) NotifyServicesCreateFailure(services []swarm.Service, retries, interval int) error {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	if len(m.NotifCreateFailureUrl) == 0 {
+		return nil
+	}
+	errs := []error{}
+	for _, s := range services {
+		createdAt, ok := m.CreatedServices[s.Spec.Name]
+		if !ok {
+			continue
+		}
+		if time.Since(createdAt) > m.CreateFailureWindow {
+			delete(m.CreatedServices, s.Spec.Name)
+			continue
+		}
+		tasks, err := m.getServiceTasks(s.ID)
+		if err != nil {
+			logPrintf("WARNING: Could not list tasks of the service %s\n%s", s.Spec.Name, err.Error())
+			continue
+		}
+		failed := getFailedTasks(tasks)
+		if len(failed) == 0 {
+			continue
+		}
+		logPrintf("WARNING: %d tasks of the newly created service %s failed", len(failed), s.Spec.Name)
+		fullUrl := fmt.Sprintf(
+			"%s?serviceName=%s&failedTasks=%d&error=%s",
+			m.NotifCreateFailureUrl,
+			s.Spec.Name,
+			len(failed),
+			url.QueryEscape(failed[0].Status.Err),
+		)
+		logPrintf("Sending service create failure notification to %s", fullUrl)
+		if err := m.sendNotificationUnlocked(s.Spec.Name, "createFailure", fullUrl, retries, interval); err != nil {
+			errs = append(errs, err)
+		} else {
+			delete(m.CreatedServices, s.Spec.Name)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("At least one request produced errors. Please consult logs for more details.")
+	}
+	return nil
+}
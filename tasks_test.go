@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TasksTestSuite struct {
+	suite.Suite
+}
+
+func TestTasksUnitTestSuite(t *testing.T) {
+	s := new(TasksTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// getRunningNodeIds
+
+func (s *TasksTestSuite) Test_GetRunningNodeIds_ReturnsSortedDistinctNodesOfRunningTasks() {
+	tasks := []swarm.Task{
+		s.getTaskOnNode(swarm.TaskStateRunning, "node-2"),
+		s.getTaskOnNode(swarm.TaskStateRunning, "node-1"),
+		s.getTaskOnNode(swarm.TaskStateRunning, "node-2"),
+		s.getTaskOnNode(swarm.TaskStateShutdown, "node-3"),
+	}
+
+	actual := getRunningNodeIds(tasks)
+
+	s.Equal([]string{"node-1", "node-2"}, actual)
+}
+
+// NotifyServicesCreate
+
+func (s *TasksTestSuite) Test_NotifyServicesCreate_IncludesNodesOfRunningTasks() {
+	dockerSrv := s.newFakeDockerServer([]swarm.Task{
+		s.getTaskOnNode(swarm.TaskStateRunning, "node-1"),
+		s.getTaskOnNode(swarm.TaskStateRunning, "node-2"),
+	})
+	defer dockerSrv.Close()
+	actualQuery := ""
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, notifSrv.URL)
+	service.NotifCreateServiceUrl = notifSrv.URL
+	service.IncludeNodes = true
+
+	err := service.NotifyServicesCreate(s.getServices(), 1, 0)
+
+	s.NoError(err)
+	s.Equal("serviceName=go-demo&nodes=node-1,node-2", actualQuery)
+}
+
+func (s *TasksTestSuite) Test_NotifyServicesCreate_SendsEmptyNodes_WhenServiceHasNoRunningTasks() {
+	dockerSrv := s.newFakeDockerServer([]swarm.Task{s.getTask(swarm.TaskStateNew, time.Second)})
+	defer dockerSrv.Close()
+	actualQuery := ""
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, notifSrv.URL)
+	service.NotifCreateServiceUrl = notifSrv.URL
+	service.IncludeNodes = true
+
+	service.NotifyServicesCreate(s.getServices(), 1, 0)
+
+	s.Equal("serviceName=go-demo&nodes=", actualQuery)
+}
+
+func (s *TasksTestSuite) Test_NotifyServicesCreate_SendsNotificationWithoutNodes_WhenTasksCannotBeListed() {
+	dockerSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer dockerSrv.Close()
+	actualQuery := ""
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, notifSrv.URL)
+	service.NotifCreateServiceUrl = notifSrv.URL
+	service.IncludeNodes = true
+	msgs := []string{}
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {
+		msgs = append(msgs, fmt.Sprintf(format, v...))
+	}
+
+	err := service.NotifyServicesCreate(s.getServices(), 1, 0)
+
+	s.NoError(err)
+	s.Equal("serviceName=go-demo", actualQuery)
+	s.Contains(msgs[0], "WARNING: Could not list tasks of the service go-demo. The nodes parameter will not be sent")
+}
+
+func (s *TasksTestSuite) Test_NotifyServicesUpdate_SendsNotificationWithoutNodes_WhenTasksCannotBeListed() {
+	dockerSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer dockerSrv.Close()
+	actualQuery := ""
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, notifSrv.URL)
+	service.NotifUpdateServiceUrl = notifSrv.URL
+	service.IncludeNodes = true
+
+	err := service.NotifyServicesUpdate(s.getServices(), 1, 0)
+
+	s.NoError(err)
+	s.Equal("serviceName=go-demo", actualQuery)
+}
+
+func (s *TasksTestSuite) Test_NotifyServicesUpdate_ListsTasksOnce_WhenThereAreMultipleUrls() {
+	tasksCount := 0
+	dockerSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/tasks") {
+			tasksCount++
+			json.NewEncoder(w).Encode([]swarm.Task{s.getTaskOnNode(swarm.TaskStateRunning, "node-1")})
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer dockerSrv.Close()
+	actualQueries := []string{}
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQueries = append(actualQueries, r.URL.RawQuery)
+	}))
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, notifSrv.URL)
+	service.NotifUpdateServiceUrl = notifSrv.URL + "/a," + notifSrv.URL + "/b"
+	service.IncludeNodes = true
+
+	err := service.NotifyServicesUpdate(s.getServices(), 1, 0)
+
+	s.NoError(err)
+	s.Equal(1, tasksCount)
+	s.Equal([]string{"serviceName=go-demo&nodes=node-1", "serviceName=go-demo&nodes=node-1"}, actualQueries)
+}
+
+func (s *TasksTestSuite) Test_NotifyServicesCreate_DoesNotIncludeNodes_WhenIncludeNodesIsFalse() {
+	dockerSrv := s.newFakeDockerServer([]swarm.Task{s.getTaskOnNode(swarm.TaskStateRunning, "node-1")})
+	defer dockerSrv.Close()
+	actualQuery := ""
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, notifSrv.URL)
+	service.NotifCreateServiceUrl = notifSrv.URL
+
+	service.NotifyServicesCreate(s.getServices(), 1, 0)
+
+	s.Equal("serviceName=go-demo", actualQuery)
+}
+
+// getStuckTasks
+
+func (s *TasksTestSuite) Test_GetStuckTasks_ReturnsTasksThatDidNotReachRunning() {
+	tasks := []swarm.Task{
+		s.getTask(swarm.TaskStateNew, time.Minute),
+		s.getTask(swarm.TaskStateAllocated, time.Minute),
+		s.getTask(swarm.TaskStateRunning, time.Minute),
+		s.getTask(swarm.TaskStateNew, time.Second),
+	}
+
+	actual := getStuckTasks(tasks, 30*time.Second)
+
+	s.Equal(2, len(actual))
+}
+
+func (s *TasksTestSuite) Test_GetStuckTasks_IgnoresTasksThatShouldNotRun() {
+	task := s.getTask(swarm.TaskStateNew, time.Minute)
+	task.DesiredState = swarm.TaskStateShutdown
+
+	actual := getStuckTasks([]swarm.Task{task}, 30*time.Second)
+
+	s.Empty(actual)
+}
+
+// NotifyServicesStuck
+
+func (s *TasksTestSuite) Test_NotifyServicesStuck_SendsNotification_WhenTasksAreStuckInNew() {
+	dockerSrv := s.newFakeDockerServer([]swarm.Task{s.getTask(swarm.TaskStateNew, time.Minute)})
+	defer dockerSrv.Close()
+	actualQueries := []string{}
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQueries = append(actualQueries, r.URL.RawQuery)
+	}))
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, notifSrv.URL)
+
+	err := service.NotifyServicesStuck(s.getServices(), 1, 0)
+	service.NotifyServicesStuck(s.getServices(), 1, 0)
+
+	s.NoError(err)
+	s.Equal([]string{"serviceName=go-demo&stuckTasks=1"}, actualQueries)
+	s.True(service.StuckServices["go-demo"])
+}
+
+func (s *TasksTestSuite) Test_NotifyServicesStuck_DoesNotSendNotification_WhenTasksAreRunning() {
+	dockerSrv := s.newFakeDockerServer([]swarm.Task{s.getTask(swarm.TaskStateRunning, time.Minute)})
+	defer dockerSrv.Close()
+	actualSent := false
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualSent = true
+	}))
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, notifSrv.URL)
+	service.StuckServices["go-demo"] = true
+
+	service.NotifyServicesStuck(s.getServices(), 1, 0)
+
+	s.False(actualSent)
+	s.NotContains(service.StuckServices, "go-demo")
+}
+
+func (s *TasksTestSuite) Test_NotifyServicesStuck_DoesNothing_WhenTimeoutIsNotSet() {
+	actualSent := false
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualSent = true
+	}))
+	defer notifSrv.Close()
+	service := NewService("unix:///this/socket/does/not/exist", "", "")
+	service.NotifStuckServiceUrl = notifSrv.URL
+	service.Services["go-demo"] = true
+
+	err := service.NotifyServicesStuck(s.getServices(), 1, 0)
+
+	s.NoError(err)
+	s.False(actualSent)
+}
+
+// getFailedTasks
+
+func (s *TasksTestSuite) Test_GetFailedTasks_ReturnsFailedAndRejectedTasks() {
+	tasks := []swarm.Task{
+		s.getTask(swarm.TaskStateFailed, time.Second),
+		s.getTask(swarm.TaskStateRejected, time.Second),
+		s.getTask(swarm.TaskStateRunning, time.Second),
+	}
+
+	actual := getFailedTasks(tasks)
+
+	s.Equal(2, len(actual))
+}
+
+// NotifyServicesCreateFailure
+
+func (s *TasksTestSuite) Test_NotifyServicesCreateFailure_SendsNotification_WhenTasksOfNewServiceFail() {
+	task := s.getTask(swarm.TaskStateRejected, time.Second)
+	task.Status.Err = "No such image: go-demo:bad"
+	dockerSrv := s.newFakeDockerServer([]swarm.Task{task})
+	defer dockerSrv.Close()
+	actualQueries := []string{}
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQueries = append(actualQueries, r.URL.RawQuery)
+	}))
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, "")
+	service.NotifCreateFailureUrl = notifSrv.URL
+	service.CreatedServices["go-demo"] = time.Now()
+
+	err := service.NotifyServicesCreateFailure(s.getServices(), 1, 0)
+	service.NotifyServicesCreateFailure(s.getServices(), 1, 0)
+
+	s.NoError(err)
+	s.Equal([]string{"serviceName=go-demo&failedTasks=1&error=No+such+image%3A+go-demo%3Abad"}, actualQueries)
+	s.NotContains(service.CreatedServices, "go-demo")
+}
+
+func (s *TasksTestSuite) Test_NotifyServicesCreateFailure_DoesNotSendNotification_WhenWindowPassed() {
+	dockerSrv := s.newFakeDockerServer([]swarm.Task{s.getTask(swarm.TaskStateFailed, time.Second)})
+	defer dockerSrv.Close()
+	actualSent := false
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualSent = true
+	}))
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, "")
+	service.NotifCreateFailureUrl = notifSrv.URL
+	service.CreatedServices["go-demo"] = time.Now().Add(-time.Hour)
+
+	service.NotifyServicesCreateFailure(s.getServices(), 1, 0)
+
+	s.False(actualSent)
+	s.NotContains(service.CreatedServices, "go-demo")
+}
+
+func (s *TasksTestSuite) Test_NotifyServicesCreateFailure_DoesNotSendNotification_WhenTasksAreRunning() {
+	dockerSrv := s.newFakeDockerServer([]swarm.Task{s.getTask(swarm.TaskStateRunning, time.Second)})
+	defer dockerSrv.Close()
+	actualSent := false
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualSent = true
+	}))
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, "")
+	service.NotifCreateFailureUrl = notifSrv.URL
+	service.CreatedServices["go-demo"] = time.Now()
+
+	service.NotifyServicesCreateFailure(s.getServices(), 1, 0)
+
+	s.False(actualSent)
+	s.Contains(service.CreatedServices, "go-demo")
+}
+
+// Util
+
+func (s *TasksTestSuite) getTask(state swarm.TaskState, age time.Duration) swarm.Task {
+	task := swarm.Task{
+		DesiredState: swarm.TaskStateRunning,
+		Status:       swarm.TaskStatus{State: state},
+	}
+	task.Meta.CreatedAt = time.Now().Add(-age)
+	return task
+}
+
+func (s *TasksTestSuite) getTaskOnNode(state swarm.TaskState, nodeId string) swarm.Task {
+	task := s.getTask(state, time.Second)
+	task.NodeID = nodeId
+	return task
+}
+
+func (s *TasksTestSuite) getServices() []swarm.Service {
+	service := swarm.Service{ID: "go-demo-id"}
+	service.Spec.Name = "go-demo"
+	service.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	return []swarm.Service{service}
+}
+
+func (s *TasksTestSuite) getService(dockerSrv *httptest.Server, notifUrl string) *Service {
+	service := NewService(strings.Replace(dockerSrv.URL, "http://", "tcp://", 1), "", "")
+	service.NotifStuckServiceUrl = notifUrl
+	service.StuckTaskTimeout = 30 * time.Second
+	service.Services["go-demo"] = true
+	return service
+}
+
+func (s *TasksTestSuite) newFakeDockerServer(tasks []swarm.Task) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/tasks") {
+			json.NewEncoder(w).Encode(tasks)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+}
@@ -0,0 +1,16 @@
+package main
+
+func (m *Service) notifyShutdown(services []string, retries, interval int) {
+	notifications := []notification{}
+	for _, name := range services {
+		shutdownUrl := getServiceLabels(m.ServicesCache[name])[m.LabelPrefix+"shutdownNotifyUrl"]
+		if len(shutdownUrl) == 0 {
+			continue
+		}
+		logPrintf("Sending service shutdown notification to %s", m.redact(name, shutdownUrl))
+		notifications = append(notifications, notification{name, "shutdown", shutdownUrl, ""})
+	}
+	for name := range m.sendUnlocked(notifications, retries, interval) {
+		logPrintf("WARNING: The shutdown notification of the service %s failed. The service will be notified as removed", name)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type TeardownTestSuite struct {
+	suite.Suite
+	mu    sync.Mutex
+	paths []string
+}
+
+func TestTeardownUnitTestSuite(t *testing.T) {
+	s := new(TeardownTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+func (s *TeardownTestSuite) SetupTest() {
+	s.paths = []string{}
+}
+
+// NotifyServicesRemove
+
+func (s *TeardownTestSuite) Test_NotifyServicesRemove_CallsShutdownUrlBeforeRemoveNotification() {
+	httpSrv := s.newReceiver(http.StatusOK)
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL+"/remove")
+	s.addService(service, "go-demo", map[string]string{"com.df.shutdownNotifyUrl": httpSrv.URL + "/teardown"})
+
+	err := service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	s.NoError(err)
+	s.Equal([]string{"/teardown", "/remove"}, s.paths)
+}
+
+func (s *TeardownTestSuite) Test_NotifyServicesRemove_DoesNotCallShutdownUrl_WhenLabelIsNotSet() {
+	httpSrv := s.newReceiver(http.StatusOK)
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL+"/remove")
+	s.addService(service, "go-demo", map[string]string{})
+
+	service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	s.Equal([]string{"/remove"}, s.paths)
+}
+
+func (s *TeardownTestSuite) Test_NotifyServicesRemove_SendsRemoveNotification_WhenShutdownFails() {
+	httpSrv := s.newReceiver(http.StatusOK)
+	defer httpSrv.Close()
+	shutdownSrv := s.newReceiver(http.StatusInternalServerError)
+	defer shutdownSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL+"/remove")
+	s.addService(service, "go-demo", map[string]string{"com.df.shutdownNotifyUrl": shutdownSrv.URL + "/teardown"})
+
+	err := service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	s.NoError(err)
+	s.Equal([]string{"/teardown", "/remove"}, s.paths)
+	s.NotContains(service.Services, "go-demo")
+}
+
+// Util
+
+func (s *TeardownTestSuite) newReceiver(status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.paths = append(s.paths, r.URL.Path)
+		s.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+}
+
+func (s *TeardownTestSuite) addService(service *Service, name string, labels map[string]string) {
+	srv := swarm.Service{}
+	srv.Spec.Name = name
+	srv.Spec.Labels = labels
+	service.Services[name] = true
+	service.ServicesCache[name] = srv
+}
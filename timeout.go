@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/net/context"
+	"strings"
+)
+
+var errCycleTimeout = errors.New("The notification phase of the iteration timed out")
+
+func (m *Service) startCycleTimeout() {
+	m.cycleMu.Lock()
+	defer m.cycleMu.Unlock()
+	m.stopCycleTimeout()
+	if m.NotifyCycleTimeout > 0 {
+		m.cycleCtx, m.cycleCancel = context.WithTimeout(context.Background(), m.NotifyCycleTimeout)
+	}
+}
+
+func (m *Service) EndCycle() {
+	// Only the create, update, and remove notifications are bound by the timeout so the phases that follow are not skipped
+	m.cycleMu.Lock()
+	defer m.cycleMu.Unlock()
+	m.stopCycleTimeout()
+}
+
+func (m *Service) stopCycleTimeout() {
+	if m.cycleCancel != nil {
+		m.cycleCancel()
+	}
+	m.cycleCtx, m.cycleCancel = nil, nil
+}
+
+func (m *Service) getCycleContext() context.Context {
+	m.cycleMu.Lock()
+	defer m.cycleMu.Unlock()
+	if m.cycleCtx == nil {
+		return context.Background()
+	}
+	return m.cycleCtx
+}
+
+func (m *Service) isCycleTimedOut() bool {
+	return m.getCycleContext().Err() != nil
+}
+
+func (m *Service) logCycleTimeout(skipped []notification) {
+	if len(skipped) == 0 {
+		return
+	}
+	items := []string{}
+	for _, n := range skipped {
+		items = append(items, fmt.Sprintf("%s %s", n.event, n.serviceName))
+	}
+	logPrintf("WARNING: The notification phase exceeded %s. The following notifications were skipped and will be sent in the next iteration: %s", m.NotifyCycleTimeout, strings.Join(items, ", "))
+}
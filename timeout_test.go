@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type TimeoutTestSuite struct {
+	suite.Suite
+	mu   sync.Mutex
+	logs []string
+}
+
+func TestTimeoutUnitTestSuite(t *testing.T) {
+	s := new(TimeoutTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.logs = append(s.logs, fmt.Sprintf(format, v...))
+	}
+
+	suite.Run(t, s)
+}
+
+func (s *TimeoutTestSuite) SetupTest() {
+	s.logs = []string{}
+}
+
+// sendNotifications
+
+func (s *TimeoutTestSuite) Test_SendNotifications_AbortsWhenNotifyCycleTimeoutIsReached() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyCycleTimeout = 100 * time.Millisecond
+	service.StartCycle(1, 0)
+	notifications := []notification{
+		{"go-demo", "create", httpSrv.URL + "?serviceName=go-demo", ""},
+		{"other", "create", httpSrv.URL + "?serviceName=other", ""},
+	}
+
+	start := time.Now()
+	errs := service.sendNotifications(notifications, 1, 0)
+
+	s.True(time.Since(start) < time.Second)
+	s.Equal(errCycleTimeout, errs["go-demo"])
+	s.Equal(errCycleTimeout, errs["other"])
+	s.Equal(notifications, service.deferred)
+	s.True(s.hasLog("create go-demo, create other"))
+}
+
+func (s *TimeoutTestSuite) Test_SendNotifications_DoesNotAbort_WhenNotifyCycleTimeoutIsNotSet() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.StartCycle(1, 0)
+
+	errs := service.sendNotifications([]notification{{"go-demo", "create", httpSrv.URL, ""}}, 1, 0)
+
+	s.Empty(errs)
+	s.Empty(service.deferred)
+}
+
+// StartCycle
+
+func (s *TimeoutTestSuite) Test_StartCycle_ResetsNotifyCycleTimeout() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyCycleTimeout = 50 * time.Millisecond
+	service.StartCycle(1, 0)
+	time.Sleep(100 * time.Millisecond)
+	s.True(service.isCycleTimedOut())
+
+	service.StartCycle(1, 0)
+
+	s.False(service.isCycleTimedOut())
+}
+
+// EndCycle
+
+func (s *TimeoutTestSuite) Test_EndCycle_DoesNotSkipNotificationsSentAfterIt() {
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.NotifyCycleTimeout = 50 * time.Millisecond
+	service.StartCycle(1, 0)
+	time.Sleep(100 * time.Millisecond)
+
+	service.EndCycle()
+
+	errs := service.sendNotifications([]notification{{"go-demo", "stuck", httpSrv.URL, ""}}, 1, 0)
+	s.Empty(errs)
+	s.Empty(service.deferred)
+}
+
+// NewServiceFromEnv
+
+func (s *TimeoutTestSuite) Test_NewServiceFromEnv_SetsNotifyCycleTimeout() {
+	timeoutOrig := os.Getenv("DF_NOTIFY_CYCLE_TIMEOUT")
+	defer func() { os.Setenv("DF_NOTIFY_CYCLE_TIMEOUT", timeoutOrig) }()
+	os.Setenv("DF_NOTIFY_CYCLE_TIMEOUT", "30")
+
+	service := NewServiceFromEnv()
+
+	s.Equal(30*time.Second, service.NotifyCycleTimeout)
+}
+
+// Util
+
+func (s *TimeoutTestSuite) hasLog(value string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range s.logs {
+		if strings.Contains(l, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"net/http"
+)
+
+func (m *Service) rememberTraceId(s swarm.Service) {
+	if len(m.TraceHeader) == 0 {
+		return
+	}
+	value := getServiceLabels(s)[m.TraceLabel]
+	m.traceMu.Lock()
+	defer m.traceMu.Unlock()
+	if len(value) == 0 {
+		delete(m.traceIds, s.Spec.Name)
+		return
+	}
+	m.traceIds[s.Spec.Name] = value
+}
+
+func (m *Service) forgetTraceId(serviceName string) {
+	m.traceMu.Lock()
+	defer m.traceMu.Unlock()
+	delete(m.traceIds, serviceName)
+}
+
+func (m *Service) setTraceHeader(req *http.Request, serviceName string) {
+	if len(m.TraceHeader) == 0 {
+		return
+	}
+	m.traceMu.Lock()
+	defer m.traceMu.Unlock()
+	if value, ok := m.traceIds[serviceName]; ok {
+		req.Header.Set(m.TraceHeader, value)
+	}
+}
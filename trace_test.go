@@ -0,0 +1,145 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+type TraceTestSuite struct {
+	suite.Suite
+}
+
+func TestTraceUnitTestSuite(t *testing.T) {
+	s := new(TraceTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// NotifyServicesCreate
+
+func (s *TraceTestSuite) Test_NotifyServicesCreate_SetsTraceHeaderFromLabel() {
+	actualHeader := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualHeader = r.Header.Get("X-Trace-Id")
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	service.TraceHeader = "X-Trace-Id"
+
+	err := service.NotifyServicesCreate([]swarm.Service{s.getService("go-demo", "abc-123")}, 1, 0)
+
+	s.NoError(err)
+	s.Equal("abc-123", actualHeader)
+}
+
+func (s *TraceTestSuite) Test_NotifyServicesCreate_DoesNotSetTraceHeader_WhenLabelIsNotPresent() {
+	headers := http.Header{}
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	service.TraceHeader = "X-Trace-Id"
+
+	err := service.NotifyServicesCreate([]swarm.Service{s.getService("go-demo", "")}, 1, 0)
+
+	s.NoError(err)
+	s.NotContains(headers, "X-Trace-Id")
+}
+
+func (s *TraceTestSuite) Test_NotifyServicesCreate_DoesNotSetTraceHeader_WhenTraceHeaderIsNotSet() {
+	headers := http.Header{}
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+
+	err := service.NotifyServicesCreate([]swarm.Service{s.getService("go-demo", "abc-123")}, 1, 0)
+
+	s.NoError(err)
+	s.NotContains(headers, "X-Trace-Id")
+}
+
+// NotifyServicesRemove
+
+func (s *TraceTestSuite) Test_NotifyServicesRemove_SetsTraceHeaderFromLastKnownLabel() {
+	actualHeader := ""
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualHeader = r.Header.Get("X-Trace-Id")
+	}))
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL)
+	service.TraceHeader = "X-Trace-Id"
+	service.Services["go-demo"] = true
+	service.ServicesCache["go-demo"] = s.getService("go-demo", "abc-123")
+
+	err := service.NotifyServicesRemove([]string{"go-demo"}, 1, 0)
+
+	s.NoError(err)
+	s.Equal("abc-123", actualHeader)
+	s.Empty(service.traceIds)
+}
+
+// NewServiceFromEnv
+
+func (s *TraceTestSuite) Test_NewServiceFromEnv_SetsTraceHeaderAndLabel() {
+	headerOrig := os.Getenv("DF_TRACE_HEADER")
+	labelOrig := os.Getenv("DF_TRACE_LABEL")
+	defer func() {
+		os.Setenv("DF_TRACE_HEADER", headerOrig)
+		os.Setenv("DF_TRACE_LABEL", labelOrig)
+	}()
+	os.Setenv("DF_TRACE_HEADER", "X-Request-Id")
+	os.Setenv("DF_TRACE_LABEL", "com.acme.requestId")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("X-Request-Id", service.TraceHeader)
+	s.Equal("com.acme.requestId", service.TraceLabel)
+}
+
+func (s *TraceTestSuite) Test_NewServiceFromEnv_SetsTraceLabelToDefault_WhenEnvIsNotPresent() {
+	labelOrig := os.Getenv("DF_TRACE_LABEL")
+	defer func() { os.Setenv("DF_TRACE_LABEL", labelOrig) }()
+	os.Unsetenv("DF_TRACE_LABEL")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("com.df.traceId", service.TraceLabel)
+}
+
+func (s *TraceTestSuite) Test_NewServiceFromEnv_DerivesTraceLabelFromLabelPrefix() {
+	labelOrig := os.Getenv("DF_TRACE_LABEL")
+	prefixOrig := os.Getenv("DF_LABEL_PREFIX")
+	defer func() {
+		os.Setenv("DF_TRACE_LABEL", labelOrig)
+		os.Setenv("DF_LABEL_PREFIX", prefixOrig)
+	}()
+	os.Unsetenv("DF_TRACE_LABEL")
+	os.Setenv("DF_LABEL_PREFIX", "acme.")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("acme.traceId", service.TraceLabel)
+}
+
+// Util
+
+func (s *TraceTestSuite) getService(name, traceId string) swarm.Service {
+	srv := swarm.Service{}
+	srv.Spec.Name = name
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	if len(traceId) > 0 {
+		srv.Spec.Labels["com.df.traceId"] = traceId
+	}
+	return srv
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type TransformRequest struct {
+	Event
+	Url  string `json:"url"`
+	Body string `json:"body,omitempty"`
+}
+
+type TransformResponse struct {
+	Url     string            `json:"url"`
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+func (m *Service) transform(n notification) (TransformResponse, error) {
+	original := n.getPayload()
+	if len(m.TransformUrl) == 0 {
+		return original, nil
+	}
+	payload, err := m.callTransform(n)
+	if err == nil {
+		return payload, nil
+	}
+	if m.TransformFailOpen {
+		logPrintf("WARNING: Could not transform the %s notification of the service %s. The original notification will be sent\n%s", n.event, n.serviceName, err.Error())
+		return original, nil
+	}
+	logPrintf("ERROR: Could not transform the %s notification of the service %s\n%s", n.event, n.serviceName, err.Error())
+	return TransformResponse{}, err
+}
+
+func (m *Service) callTransform(n notification) (TransformResponse, error) {
+	req := TransformRequest{
+		Event: Event{Type: n.event, ServiceName: n.serviceName},
+		Url:   n.fullUrl,
+		Body:  n.body,
+	}
+	m.stateMu.Lock()
+	if s, ok := m.ServicesCache[n.serviceName]; ok {
+		req.Labels = getServiceLabels(s)
+	}
+	m.stateMu.Unlock()
+	body, err := json.Marshal(req)
+	if err != nil {
+		return TransformResponse{}, err
+	}
+	client := &http.Client{Timeout: m.TransformTimeout}
+	resp, err := client.Post(m.TransformUrl, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return TransformResponse{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return TransformResponse{}, fmt.Errorf("Request %s returned status code %d", m.TransformUrl, resp.StatusCode)
+	}
+	data := TransformResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return TransformResponse{}, err
+	}
+	if len(data.Url) == 0 {
+		return TransformResponse{}, fmt.Errorf("Request %s returned an empty URL", m.TransformUrl)
+	}
+	return data, nil
+}
+
+// Bodies rendered by the remove body template are JSON like those of the endpoint templates
+func (n notification) getPayload() TransformResponse {
+	payload := TransformResponse{Url: n.fullUrl}
+	if len(n.body) > 0 {
+		payload.Body = n.body
+		payload.Headers = map[string]string{"Content-Type": "application/json"}
+	}
+	return payload
+}
+
+// The transformed URL is sent like any other notification unless the webhook also returned a method or a body
+func (m *Service) getTransformedRequest(event string, payload TransformResponse) (*http.Request, error) {
+	var req *http.Request
+	var err error
+	if len(payload.Method) == 0 && len(payload.Body) == 0 {
+		req, err = m.getNotificationRequest(event, payload.Url)
+	} else {
+		method := payload.Method
+		if len(method) == 0 {
+			method = "POST"
+		}
+		req, err = http.NewRequest(method, payload.Url, strings.NewReader(payload.Body))
+	}
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range payload.Headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+type TransformTestSuite struct {
+	suite.Suite
+}
+
+func TestTransformUnitTestSuite(t *testing.T) {
+	s := new(TransformTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+// transform
+
+func (s *TransformTestSuite) Test_Transform_ReturnsUrl_WhenTransformUrlIsNotSet() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	actual, err := service.transform(notification{"go-demo", "create", "http://proxy?serviceName=go-demo", ""})
+
+	s.NoError(err)
+	s.Equal("http://proxy?serviceName=go-demo", actual.Url)
+}
+
+func (s *TransformTestSuite) Test_Transform_SendsEventToTransformEndpoint() {
+	actual := TransformRequest{}
+	transformSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&actual)
+		w.Write([]byte(`{"url":"http://proxy?service=go-demo"}`))
+	}))
+	defer transformSrv.Close()
+	service := s.getService(transformSrv.URL)
+
+	payload, err := service.transform(notification{"go-demo", "create", "http://proxy?serviceName=go-demo", ""})
+
+	s.NoError(err)
+	s.Equal("http://proxy?service=go-demo", payload.Url)
+	s.Equal("create", actual.Type)
+	s.Equal("go-demo", actual.ServiceName)
+	s.Equal("http://proxy?serviceName=go-demo", actual.Url)
+	s.Equal("true", actual.Labels["com.df.notify"])
+}
+
+func (s *TransformTestSuite) Test_Transform_ReturnsOriginalUrl_WhenTransformFailsAndFailOpenIsTrue() {
+	transformSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer transformSrv.Close()
+	service := s.getService(transformSrv.URL)
+
+	actual, err := service.transform(notification{"go-demo", "create", "http://proxy?serviceName=go-demo", ""})
+
+	s.NoError(err)
+	s.Equal("http://proxy?serviceName=go-demo", actual.Url)
+}
+
+func (s *TransformTestSuite) Test_Transform_ReturnsError_WhenTransformFailsAndFailOpenIsFalse() {
+	transformSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer transformSrv.Close()
+	service := s.getService(transformSrv.URL)
+	service.TransformFailOpen = false
+
+	_, err := service.transform(notification{"go-demo", "create", "http://proxy?serviceName=go-demo", ""})
+
+	s.Error(err)
+}
+
+func (s *TransformTestSuite) Test_Transform_ReturnsOriginalUrl_WhenTimeoutIsReached() {
+	done := make(chan struct{})
+	transformSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer transformSrv.Close()
+	defer close(done)
+	service := s.getService(transformSrv.URL)
+	service.TransformTimeout = 10 * time.Millisecond
+
+	actual, err := service.transform(notification{"go-demo", "create", "http://proxy?serviceName=go-demo", ""})
+
+	s.NoError(err)
+	s.Equal("http://proxy?serviceName=go-demo", actual.Url)
+}
+
+// NotifyServicesCreate
+
+func (s *TransformTestSuite) Test_NotifyServicesCreate_SendsTransformedNotification() {
+	actualQuery := ""
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer notifSrv.Close()
+	transformSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"url":"` + notifSrv.URL + `?backend=go-demo"}`))
+	}))
+	defer transformSrv.Close()
+	service := s.getService(transformSrv.URL)
+	service.NotifCreateServiceUrl = notifSrv.URL
+
+	err := service.NotifyServicesCreate([]swarm.Service{service.ServicesCache["go-demo"]}, 1, 0)
+
+	s.NoError(err)
+	s.Equal("backend=go-demo", actualQuery)
+}
+
+func (s *TransformTestSuite) Test_NotifyServicesCreate_SendsTransformedBodyMethodAndHeaders() {
+	actualMethod := ""
+	actualHeader := ""
+	actualBody := ""
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualMethod = r.Method
+		actualHeader = r.Header.Get("Content-Type")
+		body, _ := ioutil.ReadAll(r.Body)
+		actualBody = string(body)
+	}))
+	defer notifSrv.Close()
+	transformSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(TransformResponse{
+			Url:     notifSrv.URL + "/services",
+			Method:  "PUT",
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    `{"backend":"go-demo"}`,
+		})
+	}))
+	defer transformSrv.Close()
+	service := s.getService(transformSrv.URL)
+	service.NotifCreateServiceUrl = notifSrv.URL
+
+	err := service.NotifyServicesCreate([]swarm.Service{service.ServicesCache["go-demo"]}, 1, 0)
+
+	s.NoError(err)
+	s.Equal("PUT", actualMethod)
+	s.Equal("application/json", actualHeader)
+	s.Equal(`{"backend":"go-demo"}`, actualBody)
+}
+
+func (s *TransformTestSuite) Test_NotifyServicesCreate_PostsTransformedBody_WhenMethodIsNotReturned() {
+	actualMethod := ""
+	actualBody := ""
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualMethod = r.Method
+		body, _ := ioutil.ReadAll(r.Body)
+		actualBody = string(body)
+	}))
+	defer notifSrv.Close()
+	transformSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"url":"` + notifSrv.URL + `","body":"backend=go-demo"}`))
+	}))
+	defer transformSrv.Close()
+	service := s.getService(transformSrv.URL)
+	service.NotifCreateServiceUrl = notifSrv.URL
+
+	err := service.NotifyServicesCreate([]swarm.Service{service.ServicesCache["go-demo"]}, 1, 0)
+
+	s.NoError(err)
+	s.Equal("POST", actualMethod)
+	s.Equal("backend=go-demo", actualBody)
+}
+
+func (s *TransformTestSuite) Test_NotifyServicesCreate_AddsTransformedHeaders_WhenOnlyUrlAndHeadersAreReturned() {
+	actualMethod := ""
+	actualHeader := ""
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualMethod = r.Method
+		actualHeader = r.Header.Get("X-Tenant")
+	}))
+	defer notifSrv.Close()
+	transformSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"url":"` + notifSrv.URL + `?backend=go-demo","headers":{"X-Tenant":"payments"}}`))
+	}))
+	defer transformSrv.Close()
+	service := s.getService(transformSrv.URL)
+	service.NotifCreateServiceUrl = notifSrv.URL
+
+	err := service.NotifyServicesCreate([]swarm.Service{service.ServicesCache["go-demo"]}, 1, 0)
+
+	s.NoError(err)
+	s.Equal("GET", actualMethod)
+	s.Equal("payments", actualHeader)
+}
+
+func (s *TransformTestSuite) Test_NotifyServicesCreate_ReturnsError_WhenTransformFailsAndFailOpenIsFalse() {
+	actualSent := false
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualSent = true
+	}))
+	defer notifSrv.Close()
+	service := s.getService("http://127.0.0.1:1/transform")
+	service.NotifCreateServiceUrl = notifSrv.URL
+	service.TransformFailOpen = false
+
+	err := service.NotifyServicesCreate([]swarm.Service{service.ServicesCache["go-demo"]}, 1, 0)
+
+	s.Error(err)
+	s.False(actualSent)
+}
+
+// NewServiceFromEnv
+
+func (s *TransformTestSuite) Test_NewServiceFromEnv_SetsTransform() {
+	transformUrl := os.Getenv("DF_TRANSFORM_URL")
+	transformTimeout := os.Getenv("DF_TRANSFORM_TIMEOUT")
+	failOpen := os.Getenv("DF_TRANSFORM_FAIL_OPEN")
+	defer func() {
+		os.Setenv("DF_TRANSFORM_URL", transformUrl)
+		os.Setenv("DF_TRANSFORM_TIMEOUT", transformTimeout)
+		os.Setenv("DF_TRANSFORM_FAIL_OPEN", failOpen)
+	}()
+	os.Setenv("DF_TRANSFORM_URL", "http://transformer/transform")
+	os.Setenv("DF_TRANSFORM_TIMEOUT", "2")
+	os.Setenv("DF_TRANSFORM_FAIL_OPEN", "false")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("http://transformer/transform", service.TransformUrl)
+	s.Equal(2*time.Second, service.TransformTimeout)
+	s.False(service.TransformFailOpen)
+}
+
+// Util
+
+func (s *TransformTestSuite) getService(transformUrl string) *Service {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.TransformUrl = transformUrl
+	srv := swarm.Service{}
+	srv.Spec.Name = "go-demo"
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	service.Services["go-demo"] = true
+	service.ServicesCache["go-demo"] = srv
+	return service
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"sort"
+	"strconv"
+)
+
+func (m *Service) getWeight(s swarm.Service) int {
+	value, ok := getServiceLabels(s)[m.LabelPrefix+"weight"]
+	if !ok {
+		return 0
+	}
+	weight, err := strconv.Atoi(value)
+	if err != nil {
+		logPrintf("WARNING: The weight label of the service %s is not a number", s.Spec.Name)
+		return 0
+	}
+	return weight
+}
+
+func (m *Service) sortByWeight(services []swarm.Service) []swarm.Service {
+	weights := map[string]int{}
+	for _, s := range services {
+		weights[s.Spec.Name] = m.getWeight(s)
+	}
+	sorted := append([]swarm.Service{}, services...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return weights[sorted[i].Spec.Name] > weights[sorted[j].Spec.Name]
+	})
+	return sorted
+}
+
+func (m *Service) sortNamesByWeight(names []string) []string {
+	weights := map[string]int{}
+	for _, name := range names {
+		weights[name] = m.getWeight(m.ServicesCache[name])
+	}
+	sorted := append([]string{}, names...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return weights[sorted[i]] > weights[sorted[j]]
+	})
+	return sorted
+}
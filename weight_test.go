@@ -0,0 +1,99 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type WeightTestSuite struct {
+	suite.Suite
+	mu       sync.Mutex
+	services []string
+}
+
+func TestWeightUnitTestSuite(t *testing.T) {
+	s := new(WeightTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+func (s *WeightTestSuite) SetupTest() {
+	s.services = []string{}
+}
+
+// NotifyServicesCreate
+
+func (s *WeightTestSuite) Test_NotifyServicesCreate_NotifiesHigherWeightServicesFirst() {
+	httpSrv := s.newReceiver()
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	services := []swarm.Service{
+		s.getService("low", "1"),
+		s.getService("none", ""),
+		s.getService("critical", "100"),
+		s.getService("medium", "10"),
+	}
+
+	service.NotifyServicesCreate(services, 1, 0)
+
+	s.Equal([]string{"critical", "medium", "low", "none"}, s.services)
+}
+
+func (s *WeightTestSuite) Test_NotifyServicesCreate_KeepsOrder_WhenWeightsAreEqual() {
+	httpSrv := s.newReceiver()
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", httpSrv.URL, "")
+	services := []swarm.Service{
+		s.getService("b", "not-a-number"),
+		s.getService("a", ""),
+		s.getService("c", "0"),
+	}
+
+	service.NotifyServicesCreate(services, 1, 0)
+
+	s.Equal([]string{"b", "a", "c"}, s.services)
+}
+
+// NotifyServicesRemove
+
+func (s *WeightTestSuite) Test_NotifyServicesRemove_NotifiesHigherWeightServicesFirst() {
+	httpSrv := s.newReceiver()
+	defer httpSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", "", httpSrv.URL)
+	for _, srv := range []swarm.Service{s.getService("low", "1"), s.getService("critical", "100")} {
+		service.Services[srv.Spec.Name] = true
+		service.ServicesCache[srv.Spec.Name] = srv
+	}
+
+	service.NotifyServicesRemove([]string{"low", "critical"}, 1, 0)
+
+	s.Equal([]string{"critical", "low"}, s.services)
+}
+
+// Util
+
+func (s *WeightTestSuite) newReceiver() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.services = append(s.services, r.URL.Query().Get("serviceName"))
+	}))
+}
+
+func (s *WeightTestSuite) getService(name, weight string) swarm.Service {
+	srv := swarm.Service{}
+	srv.Spec.Name = name
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	if len(weight) > 0 {
+		srv.Spec.Labels["com.df.weight"] = weight
+	}
+	return srv
+}
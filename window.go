@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var removeWindowNow = time.Now
+
+func parseRemoveWindow(window string) (int, int, error) {
+	bounds := strings.Split(window, "-")
+	if len(bounds) != 2 {
+		return 0, 0, fmt.Errorf("DF_REMOVE_WINDOW %s must be in the format HH:MM-HH:MM", window)
+	}
+	minutes := []int{}
+	for _, b := range bounds {
+		t, err := time.Parse("15:04", strings.TrimSpace(b))
+		if err != nil {
+			return 0, 0, fmt.Errorf("DF_REMOVE_WINDOW %s must be in the format HH:MM-HH:MM\n%s", window, err.Error())
+		}
+		minutes = append(minutes, t.Hour()*60+t.Minute())
+	}
+	return minutes[0], minutes[1], nil
+}
+
+func (m *Service) validateRemoveWindow() error {
+	if len(m.RemoveWindow) == 0 {
+		return nil
+	}
+	_, _, err := parseRemoveWindow(m.RemoveWindow)
+	return err
+}
+
+func (m *Service) isRemoveWindowOpen(t time.Time) bool {
+	if len(m.RemoveWindow) == 0 {
+		return true
+	}
+	start, end, err := parseRemoveWindow(m.RemoveWindow)
+	if err != nil {
+		return true
+	}
+	now := t.Hour()*60 + t.Minute()
+	// A window that ends before it starts spans midnight
+	if start <= end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+func (m *Service) deferRemovals(removed []string) []string {
+	if m.isRemoveWindowOpen(removeWindowNow()) {
+		if len(m.deferredRemovals) > 0 {
+			logPrintf("The remove window %s is open. Deferred removals will be sent", m.RemoveWindow)
+			m.deferredRemovals = map[string]bool{}
+		}
+		return removed
+	}
+	// Deferred services stay tracked so that they are found as removed again until the window opens
+	deferred := map[string]bool{}
+	added := []string{}
+	for _, name := range removed {
+		deferred[name] = true
+		if !m.deferredRemovals[name] {
+			added = append(added, name)
+		}
+	}
+	m.deferredRemovals = deferred
+	if len(added) > 0 {
+		sort.Strings(added)
+		logPrintf("Removals of the services %v are deferred until the remove window %s opens", added, m.RemoveWindow)
+	}
+	return []string{}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type WindowTestSuite struct {
+	suite.Suite
+	logs []string
+}
+
+func TestWindowUnitTestSuite(t *testing.T) {
+	s := new(WindowTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {
+		s.logs = append(s.logs, fmt.Sprintf(format, v...))
+	}
+
+	suite.Run(t, s)
+}
+
+func (s *WindowTestSuite) SetupTest() {
+	s.logs = []string{}
+}
+
+// GetRemovedServices
+
+func (s *WindowTestSuite) Test_GetRemovedServices_ReturnsRemovals_WhenInsideWindow() {
+	defer s.setNow("12:00")()
+	service := s.getServiceWithRemoval("09:00-18:00")
+
+	actual := service.GetRemovedServices([]swarm.Service{})
+
+	s.Equal([]string{"go-demo"}, actual)
+}
+
+func (s *WindowTestSuite) Test_GetRemovedServices_QueuesRemovals_WhenOutsideWindow() {
+	restore := s.setNow("20:00")
+	service := s.getServiceWithRemoval("09:00-18:00")
+
+	actual := service.GetRemovedServices([]swarm.Service{})
+
+	s.Equal([]string{}, actual)
+	s.Contains(service.Services, "go-demo")
+	s.Contains(strings.Join(s.logs, "\n"), "Removals of the services [go-demo] are deferred until the remove window 09:00-18:00 opens")
+
+	restore()
+	defer s.setNow("09:00")()
+
+	actual = service.GetRemovedServices([]swarm.Service{})
+
+	s.Equal([]string{"go-demo"}, actual)
+}
+
+func (s *WindowTestSuite) Test_GetRemovedServices_LogsDeferredRemovalsOnce() {
+	defer s.setNow("20:00")()
+	service := s.getServiceWithRemoval("09:00-18:00")
+
+	service.GetRemovedServices([]swarm.Service{})
+	service.GetRemovedServices([]swarm.Service{})
+
+	s.Len(s.logs, 1)
+}
+
+func (s *WindowTestSuite) Test_GetRemovedServices_ReturnsRemovals_WhenWindowIsNotSet() {
+	service := s.getServiceWithRemoval("")
+
+	actual := service.GetRemovedServices([]swarm.Service{})
+
+	s.Equal([]string{"go-demo"}, actual)
+}
+
+// isRemoveWindowOpen
+
+func (s *WindowTestSuite) Test_IsRemoveWindowOpen_HandlesWindowsThatSpanMidnight() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.RemoveWindow = "22:00-06:00"
+
+	s.True(service.isRemoveWindowOpen(s.getTime("23:30")))
+	s.True(service.isRemoveWindowOpen(s.getTime("05:59")))
+	s.False(service.isRemoveWindowOpen(s.getTime("06:00")))
+	s.False(service.isRemoveWindowOpen(s.getTime("12:00")))
+}
+
+// ValidateTemplates
+
+func (s *WindowTestSuite) Test_ValidateTemplates_ReturnsError_WhenRemoveWindowIsInvalid() {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+
+	for _, window := range []string{"09:00", "9-18", "09:00-25:00"} {
+		service.RemoveWindow = window
+
+		s.Error(service.ValidateTemplates(), window)
+	}
+}
+
+// NewServiceFromEnv
+
+func (s *WindowTestSuite) Test_NewServiceFromEnv_SetsRemoveWindow() {
+	windowOrig := os.Getenv("DF_REMOVE_WINDOW")
+	defer func() { os.Setenv("DF_REMOVE_WINDOW", windowOrig) }()
+	os.Setenv("DF_REMOVE_WINDOW", "09:00-18:00")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("09:00-18:00", service.RemoveWindow)
+	s.NoError(service.ValidateTemplates())
+}
+
+// Util
+
+func (s *WindowTestSuite) getServiceWithRemoval(window string) *Service {
+	service := NewService("unix:///var/run/docker.sock", "", "")
+	service.RemoveWindow = window
+	service.Services["go-demo"] = true
+	return service
+}
+
+func (s *WindowTestSuite) getTime(clock string) time.Time {
+	t, _ := time.Parse("2006-01-02 15:04", "2026-10-14 "+clock)
+	return t
+}
+
+func (s *WindowTestSuite) setNow(clock string) func() {
+	nowOrig := removeWindowNow
+	removeWindowNow = func() time.Time { return s.getTime(clock) }
+	return func() { removeWindowNow = nowOrig }
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/docker/docker/api/types"
+	"golang.org/x/net/context"
+)
+
+const notifyStatusKey = "notifyStatus"
+
+func (m *Service) writeBackStatus(names []string, errs map[string]error) {
+	if m.WriteBackStatus != "true" && m.WriteBackStatus != "log" {
+		return
+	}
+	for _, name := range names {
+		status := "ok"
+		if _, failed := errs[name]; failed {
+			status = "failed"
+		}
+		if m.WriteBackStatus == "log" {
+			logPrintf("Notification status of the service %s is %s", name, status)
+			continue
+		}
+		if err := m.writeStatusLabel(name, status); err != nil {
+			logPrintf("WARNING: Could not write the notification status of the service %s\n%s", name, err.Error())
+		}
+	}
+}
+
+func (m *Service) writeStatusLabel(name, status string) error {
+	dc, err := newDockerClient(m.Host)
+	if err != nil {
+		return err
+	}
+	s, _, err := dc.ServiceInspectWithRaw(context.Background(), name, types.ServiceInspectOptions{})
+	if err != nil {
+		return err
+	}
+	statusLabel := m.LabelPrefix + notifyStatusKey
+	if s.Spec.Labels[statusLabel] == status {
+		return nil
+	}
+	spec := s.Spec
+	labels := map[string]string{}
+	for k, v := range spec.Labels {
+		labels[k] = v
+	}
+	labels[statusLabel] = status
+	spec.Labels = labels
+	_, err = dc.ServiceUpdate(context.Background(), s.ID, s.Version, spec, types.ServiceUpdateOptions{})
+	return err
+}
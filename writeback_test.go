@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type WriteBackTestSuite struct {
+	suite.Suite
+	mu      sync.Mutex
+	updates []swarm.ServiceSpec
+}
+
+func TestWriteBackUnitTestSuite(t *testing.T) {
+	s := new(WriteBackTestSuite)
+
+	logPrintfOrig := logPrintf
+	defer func() { logPrintf = logPrintfOrig }()
+	logPrintf = func(format string, v ...interface{}) {}
+
+	suite.Run(t, s)
+}
+
+func (s *WriteBackTestSuite) SetupTest() {
+	s.updates = []swarm.ServiceSpec{}
+}
+
+// NotifyServicesCreate
+
+func (s *WriteBackTestSuite) Test_NotifyServicesCreate_WritesOkStatusLabel_WhenNotificationSucceeds() {
+	dockerSrv := s.newFakeDockerServer(map[string]string{"com.df.notify": "true"})
+	defer dockerSrv.Close()
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, notifSrv.URL)
+
+	err := service.NotifyServicesCreate(s.getServices(), 1, 0)
+
+	s.NoError(err)
+	s.Equal(1, len(s.updates))
+	s.Equal("ok", s.updates[0].Labels["com.df.notifyStatus"])
+	s.Equal("true", s.updates[0].Labels["com.df.notify"])
+}
+
+func (s *WriteBackTestSuite) Test_NotifyServicesCreate_WritesFailedStatusLabel_WhenNotificationFails() {
+	dockerSrv := s.newFakeDockerServer(map[string]string{"com.df.notify": "true"})
+	defer dockerSrv.Close()
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, notifSrv.URL)
+
+	service.NotifyServicesCreate(s.getServices(), 1, 0)
+
+	s.Equal(1, len(s.updates))
+	s.Equal("failed", s.updates[0].Labels["com.df.notifyStatus"])
+}
+
+func (s *WriteBackTestSuite) Test_NotifyServicesCreate_DoesNotUpdateService_WhenStatusDidNotChange() {
+	dockerSrv := s.newFakeDockerServer(map[string]string{"com.df.notify": "true", "com.df.notifyStatus": "ok"})
+	defer dockerSrv.Close()
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, notifSrv.URL)
+
+	service.NotifyServicesCreate(s.getServices(), 1, 0)
+
+	s.Empty(s.updates)
+}
+
+func (s *WriteBackTestSuite) Test_NotifyServicesCreate_DoesNotUpdateService_WhenWriteBackStatusIsLog() {
+	dockerSrv := s.newFakeDockerServer(map[string]string{"com.df.notify": "true"})
+	defer dockerSrv.Close()
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, notifSrv.URL)
+	service.WriteBackStatus = "log"
+
+	service.NotifyServicesCreate(s.getServices(), 1, 0)
+
+	s.Empty(s.updates)
+}
+
+func (s *WriteBackTestSuite) Test_NotifyServicesCreate_DoesNotSendStatusLabel() {
+	actualQuery := ""
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualQuery = r.URL.RawQuery
+	}))
+	defer notifSrv.Close()
+	service := NewService("unix:///var/run/docker.sock", notifSrv.URL, "")
+	services := s.getServices()
+	services[0].Spec.Labels["com.df.notifyStatus"] = "ok"
+
+	service.NotifyServicesCreate(services, 1, 0)
+
+	s.Equal("serviceName=go-demo", actualQuery)
+}
+
+func (s *WriteBackTestSuite) Test_NotifyServicesCreate_WritesStatusLabelWithLabelPrefix() {
+	dockerSrv := s.newFakeDockerServer(map[string]string{"acme.notify": "true"})
+	defer dockerSrv.Close()
+	notifSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer notifSrv.Close()
+	service := s.getService(dockerSrv, notifSrv.URL)
+	service.LabelPrefix = "acme."
+	service.NotifyLabel = "acme.notify"
+	services := s.getServices()
+	services[0].Spec.Labels = map[string]string{"acme.notify": "true"}
+
+	service.NotifyServicesCreate(services, 1, 0)
+
+	s.Equal(1, len(s.updates))
+	s.Equal("ok", s.updates[0].Labels["acme.notifyStatus"])
+	s.NotContains(s.updates[0].Labels, "com.df.notifyStatus")
+}
+
+// getLabels
+
+func (s *WriteBackTestSuite) Test_GetLabels_IgnoresStatusLabel() {
+	services := s.getServices()
+	services[0].Spec.Labels["com.df.notifyStatus"] = "ok"
+
+	s.Equal(map[string]string{"com.df.notify": "true"}, getLabels(services[0], "com.df."))
+}
+
+func (s *WriteBackTestSuite) Test_GetLabels_IgnoresLeaderLabels() {
+	srv := swarm.Service{}
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true", "com.df.swarmListenerLeader": "instance-1|1500000000", "com.df.swarmListenerInstances": "instance-1|1500000000"}
+
+	s.Equal(map[string]string{"com.df.notify": "true"}, getLabels(srv, "com.df."))
+}
+
+func (s *WriteBackTestSuite) Test_GetLabels_IgnoresStatusLabelWithLabelPrefix() {
+	srv := swarm.Service{}
+	srv.Spec.Labels = map[string]string{"acme.notify": "true", "acme.notifyStatus": "ok"}
+
+	s.Equal(map[string]string{"acme.notify": "true"}, getLabels(srv, "acme."))
+}
+
+// NewServiceFromEnv
+
+func (s *WriteBackTestSuite) Test_NewServiceFromEnv_SetsWriteBackStatus() {
+	writeBackOrig := os.Getenv("DF_WRITE_BACK_STATUS")
+	defer func() { os.Setenv("DF_WRITE_BACK_STATUS", writeBackOrig) }()
+	os.Setenv("DF_WRITE_BACK_STATUS", "true")
+
+	service := NewServiceFromEnv()
+
+	s.Equal("true", service.WriteBackStatus)
+}
+
+// Util
+
+func (s *WriteBackTestSuite) getServices() []swarm.Service {
+	srv := swarm.Service{ID: "go-demo-id"}
+	srv.Spec.Name = "go-demo"
+	srv.Spec.Labels = map[string]string{"com.df.notify": "true"}
+	return []swarm.Service{srv}
+}
+
+func (s *WriteBackTestSuite) getService(dockerSrv *httptest.Server, notifUrl string) *Service {
+	service := NewService(strings.Replace(dockerSrv.URL, "http://", "tcp://", 1), notifUrl, "")
+	service.WriteBackStatus = "true"
+	return service
+}
+
+func (s *WriteBackTestSuite) newFakeDockerServer(labels map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/services/go-demo-id/update"):
+			spec := swarm.ServiceSpec{}
+			json.NewDecoder(r.Body).Decode(&spec)
+			s.mu.Lock()
+			s.updates = append(s.updates, spec)
+			s.mu.Unlock()
+			w.Write([]byte("{}"))
+		case strings.HasSuffix(r.URL.Path, "/services/go-demo"):
+			srv := swarm.Service{ID: "go-demo-id"}
+			srv.Spec.Name = "go-demo"
+			srv.Spec.Labels = labels
+			json.NewEncoder(w).Encode(srv)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}